@@ -2,6 +2,6 @@
 set -euo pipefail
 
 # Placeholder script demonstrating embedded asset execution.
-# The real implementation should prune unused Docker images and volumes safely.
+# Use `homekit docker prune` to reclaim Docker space safely.
 
 echo "[homekit-cli] docker prune safe stub"
@@ -84,6 +84,10 @@ It supports executing embedded scripts, rendering templates, and delegating to e
 	cmd.AddCommand(commands.NewSystemCommand())
 	cmd.AddCommand(commands.NewPluginCommand())
 	cmd.AddCommand(commands.NewWorkspaceCommand())
+	cmd.AddCommand(commands.NewDockerCommand())
+	cmd.AddCommand(commands.NewStackCommand())
+	cmd.AddCommand(commands.NewComposeCommand())
+	cmd.AddCommand(commands.NewProxyCommand())
 
 	return cmd
 }
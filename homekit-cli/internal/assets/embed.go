@@ -1,6 +1,7 @@
 package assets
 
 import (
+	"fmt"
 	"io/fs"
 
 	embeddedassets "github.com/homekit/homekit-cli/assets"
@@ -19,6 +20,33 @@ const (
 	AssetNamespaceWorkspaces AssetNamespace = "workspaces"
 )
 
+// Namespaces returns every namespace known to the asset manager.
+func Namespaces() []AssetNamespace {
+	return []AssetNamespace{
+		AssetNamespaceScripts,
+		AssetNamespaceTemplates,
+		AssetNamespaceWorkspaces,
+	}
+}
+
+// ParseNamespace converts user input into a known namespace.
+func ParseNamespace(value string) (AssetNamespace, error) {
+	ns := AssetNamespace(value)
+	if !ns.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrUnknownNamespace, value)
+	}
+	return ns, nil
+}
+
+// Valid reports whether the namespace is one of the predefined constants.
+func (a AssetNamespace) Valid() bool {
+	switch a {
+	case AssetNamespaceScripts, AssetNamespaceTemplates, AssetNamespaceWorkspaces:
+		return true
+	}
+	return false
+}
+
 func (a AssetNamespace) String() string {
 	return string(a)
 }
@@ -0,0 +1,40 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrAssetNotFound is returned when an asset exists in neither overrides nor embedded assets.
+	ErrAssetNotFound = errors.New("asset not found")
+	// ErrInvalidAssetName is returned for names that could escape the namespace root.
+	ErrInvalidAssetName = errors.New("invalid asset name")
+	// ErrUnknownNamespace is returned for namespaces outside the predefined set.
+	ErrUnknownNamespace = errors.New("unknown asset namespace")
+)
+
+// ValidateName ensures an asset name is a clean, relative, slash-separated path
+// that stays inside its namespace.
+func ValidateName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("%w: empty name", ErrInvalidAssetName)
+	case strings.ContainsAny(name, "\\\x00"):
+		return fmt.Errorf("%w: %q contains a forbidden character", ErrInvalidAssetName, name)
+	case strings.HasPrefix(name, "/"):
+		return fmt.Errorf("%w: %q must be relative", ErrInvalidAssetName, name)
+	case len(name) >= 2 && name[1] == ':':
+		return fmt.Errorf("%w: %q must not contain a volume name", ErrInvalidAssetName, name)
+	}
+	for _, elem := range strings.Split(name, "/") {
+		switch elem {
+		case "":
+			return fmt.Errorf("%w: %q contains an empty path element", ErrInvalidAssetName, name)
+		case ".", "..":
+			return fmt.Errorf("%w: %q contains a relative path element", ErrInvalidAssetName, name)
+		}
+	}
+	return nil
+}
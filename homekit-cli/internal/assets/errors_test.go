@@ -0,0 +1,50 @@
+package assets
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func FuzzValidateName(f *testing.F) {
+	for _, seed := range []string{
+		"docker-compose.yaml.tmpl",
+		"proxy/Caddyfile.tmpl",
+		"../etc/passwd",
+		"a/../../b",
+		"/abs",
+		"C:/windows",
+		"a\\b",
+		"a\x00b",
+		"./a",
+		"a//b",
+		"",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, name string) {
+		if ValidateName(name) != nil {
+			return
+		}
+		if strings.ContainsRune(name, 0) {
+			t.Fatalf("accepted %q with a NUL byte", name)
+		}
+		if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+			t.Fatalf("accepted absolute name %q", name)
+		}
+		if path.Clean(name) != name {
+			t.Fatalf("accepted unclean name %q", name)
+		}
+		for _, elem := range strings.Split(name, "/") {
+			if elem == ".." {
+				t.Fatalf("accepted %q with a .. element", name)
+			}
+		}
+		root := "/namespace"
+		joined := path.Join(root, name)
+		if !strings.HasPrefix(joined, root+"/") {
+			t.Fatalf("%q escapes the namespace root: %s", name, joined)
+		}
+	})
+}
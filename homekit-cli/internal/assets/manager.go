@@ -2,10 +2,12 @@ package assets
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -25,9 +27,12 @@ func NewManager(embedded fs.FS, overrideDir string) *Manager {
 	}
 }
 
-// List returns sorted asset names under the provided namespace (scripts/templates).
-func (m *Manager) List(namespace string) ([]string, error) {
-	base := strings.Trim(namespace, "/")
+// List returns sorted asset names under the provided namespace.
+func (m *Manager) List(namespace AssetNamespace) ([]string, error) {
+	if !namespace.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownNamespace, namespace)
+	}
+	base := namespace.String()
 
 	set := map[string]struct{}{}
 
@@ -41,21 +46,26 @@ func (m *Manager) List(namespace string) ([]string, error) {
 		rel := strings.TrimPrefix(path, base+"/")
 		set[rel] = struct{}{}
 		return nil
-	}); err != nil && !os.IsNotExist(err) {
+	}); err != nil && !errors.Is(err, fs.ErrNotExist) {
 		return nil, err
 	}
 
-	if m.override != "" {
-		root := filepath.Join(m.override, base)
-		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+	if root, ok := m.overrideRoot(); ok {
+		nsRoot := filepath.Join(root, base)
+		_ = filepath.WalkDir(nsRoot, func(path string, d fs.DirEntry, err error) error {
 			if err != nil || d.IsDir() {
 				return err
 			}
-			rel, err := filepath.Rel(root, path)
+			rel, err := filepath.Rel(nsRoot, path)
 			if err != nil {
 				return err
 			}
-			set[filepath.ToSlash(rel)] = struct{}{}
+			name := filepath.ToSlash(rel)
+			// Hide entries (typically symlinks) that resolve outside the override root.
+			if _, _, err := m.overridePath(namespace, name); err != nil {
+				return nil
+			}
+			set[name] = struct{}{}
 			return nil
 		})
 	}
@@ -69,19 +79,29 @@ func (m *Manager) List(namespace string) ([]string, error) {
 }
 
 // Open returns a read handle for an asset, preferring overrides.
-func (m *Manager) Open(namespace, name string) (fs.File, error) {
-	if m.override != "" {
-		path := filepath.Join(m.override, namespace, name)
-		if f, err := os.Open(path); err == nil {
+func (m *Manager) Open(namespace AssetNamespace, name string) (fs.File, error) {
+	if err := validate(namespace, name); err != nil {
+		return nil, err
+	}
+
+	if p, ok, err := m.overridePath(namespace, name); err != nil {
+		return nil, err
+	} else if ok {
+		if f, err := os.Open(p); err == nil {
 			return f, nil
 		}
 	}
-	return m.embedded.Open(filepath.ToSlash(filepath.Join(namespace, name)))
+
+	f, err := m.embedded.Open(path.Join(namespace.String(), name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrAssetNotFound, namespace, name)
+	}
+	return f, err
 }
 
 // OpenBytes returns the content of an asset as a byte slice.
 func (m *Manager) OpenBytes(namespace AssetNamespace, name string) ([]byte, error) {
-	src, err := m.Open(namespace.String(), name)
+	src, err := m.Open(namespace, name)
 	if err != nil {
 		return nil, err
 	}
@@ -90,14 +110,17 @@ func (m *Manager) OpenBytes(namespace AssetNamespace, name string) ([]byte, erro
 }
 
 // Export copies the asset to the destination directory.
-func (m *Manager) Export(namespace, name, destDir string) (string, error) {
+func (m *Manager) Export(namespace AssetNamespace, name, destDir string) (string, error) {
 	src, err := m.Open(namespace, name)
 	if err != nil {
 		return "", err
 	}
 	defer src.Close()
 
-	target := filepath.Join(destDir, name)
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	if !within(filepath.Clean(destDir), target) {
+		return "", fmt.Errorf("%w: %q escapes destination %s", ErrInvalidAssetName, name, destDir)
+	}
 	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
 		return "", err
 	}
@@ -118,7 +141,7 @@ func (m *Manager) Export(namespace, name, destDir string) (string, error) {
 }
 
 // Verify calculates a checksum for an asset.
-func (m *Manager) Verify(namespace, name string) (string, error) {
+func (m *Manager) Verify(namespace AssetNamespace, name string) (string, error) {
 	file, err := m.Open(namespace, name)
 	if err != nil {
 		return "", err
@@ -136,3 +159,53 @@ func (m *Manager) Verify(namespace, name string) (string, error) {
 func (m *Manager) Filesystem() fs.FS {
 	return m.embedded
 }
+
+// overrideRoot resolves the override directory, following symlinks so that
+// containment checks compare real paths.
+func (m *Manager) overrideRoot() (string, bool) {
+	if m.override == "" {
+		return "", false
+	}
+	root, err := filepath.EvalSymlinks(m.override)
+	if err != nil {
+		return "", false
+	}
+	return root, true
+}
+
+// overridePath returns the resolved override location for an asset. It reports
+// false when no override exists and fails when the asset resolves outside the
+// override root.
+func (m *Manager) overridePath(namespace AssetNamespace, name string) (string, bool, error) {
+	root, ok := m.overrideRoot()
+	if !ok {
+		return "", false, nil
+	}
+	candidate := filepath.Join(root, namespace.String(), filepath.FromSlash(name))
+	resolved, err := filepath.EvalSymlinks(candidate)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	if !within(root, resolved) {
+		return "", false, fmt.Errorf("%w: %s/%s resolves outside override root", ErrInvalidAssetName, namespace, name)
+	}
+	return resolved, true, nil
+}
+
+func validate(namespace AssetNamespace, name string) error {
+	if !namespace.Valid() {
+		return fmt.Errorf("%w: %q", ErrUnknownNamespace, namespace)
+	}
+	return ValidateName(name)
+}
+
+func within(root, target string) bool {
+	rel, err := filepath.Rel(root, target)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,135 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// FS returns a read-only view of a namespace in which override files shadow
+// embedded ones. Paths are relative to the namespace root.
+func (m *Manager) FS(namespace AssetNamespace) (fs.FS, error) {
+	if !namespace.Valid() {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownNamespace, namespace)
+	}
+	return namespaceFS{m: m, ns: namespace}, nil
+}
+
+// Sub returns the FS view of a directory inside a namespace.
+func (m *Manager) Sub(namespace AssetNamespace, dir string) (fs.FS, error) {
+	fsys, err := m.FS(namespace)
+	if err != nil {
+		return nil, err
+	}
+	if dir == "." {
+		return fsys, nil
+	}
+	if err := ValidateName(dir); err != nil {
+		return nil, err
+	}
+	info, err := fs.Stat(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s/%s is not a directory", namespace, dir)
+	}
+	return fs.Sub(fsys, dir)
+}
+
+// EmbeddedOnly returns a manager that ignores overrides.
+func (m *Manager) EmbeddedOnly() *Manager {
+	return &Manager{embedded: m.embedded}
+}
+
+// OverridesOnly returns a manager that ignores embedded assets.
+func (m *Manager) OverridesOnly() *Manager {
+	return &Manager{embedded: emptyFS{}, override: m.override}
+}
+
+type emptyFS struct{}
+
+func (emptyFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+type namespaceFS struct {
+	m  *Manager
+	ns AssetNamespace
+}
+
+func (f namespaceFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		if root, ok := f.m.overrideRoot(); ok {
+			if dir, err := os.Open(filepath.Join(root, f.ns.String())); err == nil {
+				return dir, nil
+			}
+		}
+		return f.m.embedded.Open(f.ns.String())
+	}
+	file, err := f.m.Open(f.ns, name)
+	if errors.Is(err, ErrAssetNotFound) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return file, err
+}
+
+// ReadDir merges directory listings from the embedded and override trees,
+// preferring override entries with the same name.
+func (f namespaceFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	merged := map[string]fs.DirEntry{}
+	embedded, embErr := fs.ReadDir(f.m.embedded, path.Join(f.ns.String(), name))
+	for _, entry := range embedded {
+		merged[entry.Name()] = entry
+	}
+
+	ovrErr := error(fs.ErrNotExist)
+	if name == "." {
+		if root, ok := f.m.overrideRoot(); ok {
+			var entries []fs.DirEntry
+			entries, ovrErr = os.ReadDir(filepath.Join(root, f.ns.String()))
+			for _, entry := range entries {
+				merged[entry.Name()] = entry
+			}
+		}
+	} else if p, ok, err := f.m.overridePath(f.ns, name); err != nil {
+		return nil, err
+	} else if ok {
+		var entries []fs.DirEntry
+		entries, ovrErr = os.ReadDir(p)
+		for _, entry := range entries {
+			merged[entry.Name()] = entry
+		}
+	}
+
+	if embErr != nil && ovrErr != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	out := make([]fs.DirEntry, 0, len(merged))
+	for _, entry := range merged {
+		out = append(out, entry)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, nil
+}
+
+func (f namespaceFS) Stat(name string) (fs.FileInfo, error) {
+	file, err := f.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return file.Stat()
+}
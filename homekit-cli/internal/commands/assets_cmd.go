@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -47,11 +48,15 @@ func NewAssetsCommand() *cobra.Command {
 	return root
 }
 
-func listAssets(cmd *cobra.Command, namespace string) error {
+func listAssets(cmd *cobra.Command, nsValue string) error {
 	rt, err := runtimeFrom(cmd)
 	if err != nil {
 		return err
 	}
+	namespace, err := assets.ParseNamespace(nsValue)
+	if err != nil {
+		return err
+	}
 	manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
 	names, err := manager.List(namespace)
 	if err != nil {
@@ -63,33 +68,52 @@ func listAssets(cmd *cobra.Command, namespace string) error {
 	return nil
 }
 
-func extractAsset(cmd *cobra.Command, namespace, name, dest string) error {
+func extractAsset(cmd *cobra.Command, nsValue, name, dest string) error {
 	rt, err := runtimeFrom(cmd)
 	if err != nil {
 		return err
 	}
+	namespace, err := assets.ParseNamespace(nsValue)
+	if err != nil {
+		return err
+	}
 
 	manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
 	path, err := manager.Export(namespace, name, dest)
 	if err != nil {
-		return err
+		return describeAssetError(err, namespace, name)
 	}
 
 	fmt.Fprintf(cmd.OutOrStdout(), "exported %s to %s\n", name, filepath.ToSlash(path))
 	return nil
 }
 
-func verifyAsset(cmd *cobra.Command, namespace, name string) error {
+func verifyAsset(cmd *cobra.Command, nsValue, name string) error {
 	rt, err := runtimeFrom(cmd)
 	if err != nil {
 		return err
 	}
+	namespace, err := assets.ParseNamespace(nsValue)
+	if err != nil {
+		return err
+	}
 
 	manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
 	sum, err := manager.Verify(namespace, name)
 	if err != nil {
-		return err
+		return describeAssetError(err, namespace, name)
 	}
 	fmt.Fprintf(cmd.OutOrStdout(), "%s\n", sum)
 	return nil
 }
+
+// describeAssetError turns manager sentinel errors into user-facing messages.
+func describeAssetError(err error, namespace assets.AssetNamespace, name string) error {
+	switch {
+	case errors.Is(err, assets.ErrAssetNotFound):
+		return fmt.Errorf("asset %q does not exist in %s (see `homekit assets list %s`): %w", name, namespace, namespace, err)
+	case errors.Is(err, assets.ErrInvalidAssetName):
+		return fmt.Errorf("refusing to access asset %q: %w", name, err)
+	}
+	return err
+}
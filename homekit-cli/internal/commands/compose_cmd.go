@@ -0,0 +1,209 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/compose"
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/stack"
+)
+
+// errComposeLint is returned when lint finds errors, or warnings with --strict.
+var errComposeLint = errors.New("compose lint failed")
+
+// NewComposeCommand groups compose file tooling.
+func NewComposeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "compose",
+		Short: "Check docker compose files",
+	}
+
+	cmd.AddCommand(newComposeLintCommand())
+	return cmd
+}
+
+func newComposeLintCommand() *cobra.Command {
+	var (
+		output  string
+		envFile string
+		disable []string
+		strict  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "lint [file|dir...]",
+		Short: "Validate compose files and check them against house rules",
+		Long: `Parse compose files (anchors, merge keys, x- extensions and ${VAR}
+interpolation from the environment and .env), validate them and lint them.
+Directories are searched for a compose file; the default is the current
+directory.
+
+Rules:
+` + composeRuleList() + `
+A service can skip rules with a ` + compose.IgnoreLabel + ` label listing them,
+comma-separated. Errors fail the command, warnings only with --strict.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+
+			var findings []compose.Finding
+			for _, arg := range args {
+				file, err := composeLintTarget(arg)
+				if err != nil {
+					return err
+				}
+				lookup, err := composeLookup(filepath.Dir(file), envFile)
+				if err != nil {
+					return err
+				}
+				content, err := os.ReadFile(file)
+				if err != nil {
+					return err
+				}
+				found, err := compose.Check(file, content, lookup, disable...)
+				if err != nil {
+					return err
+				}
+				findings = append(findings, found...)
+			}
+
+			switch output {
+			case "json":
+				if findings == nil {
+					findings = []compose.Finding{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(findings); err != nil {
+					return err
+				}
+			case "table":
+				if err := printComposeFindings(cmd, findings); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown output format %q (table|json)", output)
+			}
+
+			errs, warnings := countFindings(findings)
+			if errs > 0 || (strict && warnings > 0) {
+				return fmt.Errorf("%d error(s), %d warning(s): %w", errs, warnings, errComposeLint)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	cmd.Flags().StringVar(&envFile, "env-file", "", "Read variables from this file instead of .env next to the compose file")
+	cmd.Flags().StringSliceVar(&disable, "disable", nil, "Rules to skip (comma-separated)")
+	cmd.Flags().BoolVar(&strict, "strict", false, "Fail on warnings too")
+	return cmd
+}
+
+// composeRuleList formats compose.Rules for help text.
+func composeRuleList() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, r := range compose.Rules {
+		fmt.Fprintf(tw, "  %s\t%s\n", r.Name, r.Description)
+	}
+	tw.Flush()
+	return b.String()
+}
+
+// composeLintTarget returns the compose file for a file or directory argument.
+func composeLintTarget(arg string) (string, error) {
+	info, err := os.Stat(arg)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return arg, nil
+	}
+	return stack.FindComposeFile(arg)
+}
+
+// composeLookup resolves ${VAR} like docker compose run in dir, or from
+// envFile when set.
+func composeLookup(dir, envFile string) (compose.Lookup, error) {
+	if envFile == "" {
+		return compose.EnvLookup(dir)
+	}
+	env, err := compose.LoadEnvFile(envFile)
+	if err != nil {
+		return nil, err
+	}
+	return func(name string) (string, bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := env[name]
+		return v, ok
+	}, nil
+}
+
+func printComposeFindings(cmd *cobra.Command, findings []compose.Finding) error {
+	if len(findings) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "no problems found")
+		return nil
+	}
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "LOCATION\tSEVERITY\tRULE\tSERVICE\tMESSAGE")
+	for _, f := range findings {
+		location := f.File
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		service := f.Service
+		if service == "" {
+			service = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", location, f.Severity, f.Rule, service, f.Message)
+	}
+	return tw.Flush()
+}
+
+func countFindings(findings []compose.Finding) (errs, warnings int) {
+	for _, f := range findings {
+		if f.Severity == compose.SeverityError {
+			errs++
+		} else {
+			warnings++
+		}
+	}
+	return errs, warnings
+}
+
+// lintRenderedCompose checks rendered compose content before it is written:
+// warnings are logged and errors abort. Variables resolve as docker compose
+// would in dir.
+func lintRenderedCompose(rt *core.Runtime, name string, content []byte, dir string) error {
+	lookup, err := compose.EnvLookup(dir)
+	if err != nil {
+		return err
+	}
+	findings, err := compose.Check(name, content, lookup)
+	if err != nil {
+		return fmt.Errorf("rendered compose file: %w", err)
+	}
+	for _, f := range findings {
+		if f.Severity == compose.SeverityError {
+			rt.Logger.Error().Msg(f.String())
+		} else {
+			rt.Logger.Warn().Msg(f.String())
+		}
+	}
+	if errs, warnings := countFindings(findings); errs > 0 {
+		return fmt.Errorf("%s: %d error(s), %d warning(s): %w", name, errs, warnings, errComposeLint)
+	}
+	return nil
+}
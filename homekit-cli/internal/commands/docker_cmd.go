@@ -0,0 +1,184 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/compose"
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/docker"
+	"github.com/homekit/homekit-cli/internal/ui"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+// newDockerClient connects to the Docker Engine API at docker.host from the
+// config, else DOCKER_HOST; replace it to run the docker commands against a
+// fake daemon.
+var newDockerClient = func(rt *core.Runtime) (*docker.Client, error) {
+	return docker.NewClient(rt.Config.Docker.Host)
+}
+
+// NewDockerCommand groups Docker maintenance helpers.
+func NewDockerCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "docker",
+		Short: "Docker maintenance helpers",
+	}
+
+	cmd.AddCommand(newDockerPruneCommand())
+	cmd.AddCommand(newDockerImagesCommand())
+
+	return cmd
+}
+
+func newDockerPruneCommand() *cobra.Command {
+	var (
+		opts   docker.PruneOptions
+		yes    bool
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove stopped containers, dangling images, unused volumes and build cache",
+		Long: `List what can be reclaimed, with sizes and the reason for each resource,
+then remove it after confirmation.
+
+Resources labelled homekit.keep=true and containers or volumes of registered
+workspaces are never removed. Only anonymous volumes are pruned unless
+--all-volumes is given. Pick resource kinds with --containers, --images,
+--volumes and --build-cache; without any, all kinds are considered.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			if !opts.Containers && !opts.Images && !opts.Volumes && !opts.BuildCache {
+				opts.Containers, opts.Images, opts.Volumes, opts.BuildCache = true, true, true, true
+			}
+			registry, err := loadWorkspaceRegistry(rt)
+			if err != nil {
+				return err
+			}
+			opts.Protect = workspaceProtector(registry.List())
+
+			client, err := newDockerClient(rt)
+			if err != nil {
+				return err
+			}
+			plan, err := client.PlanPrune(cmd.Context(), opts)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			switch output {
+			case "json":
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(plan)
+			case "table":
+				if err := writePrunePlan(out, plan); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unknown output format %q (table|json)", output)
+			}
+			if len(plan.Remove) == 0 {
+				return nil
+			}
+
+			if rt.DryRun {
+				rt.Logger.Info().Msgf("Would remove %d resources, reclaiming %s", len(plan.Remove), ui.HumanBytes(plan.Reclaimable()))
+				return nil
+			}
+			if !yes {
+				if !stdinIsTerminal(cmd) {
+					return errors.New("refusing to prune without confirmation: pass --yes when not running interactively")
+				}
+				prompter := ui.Prompter{In: cmd.InOrStdin(), Out: out}
+				ok, err := prompter.Confirm(fmt.Sprintf("Remove %d resources?", len(plan.Remove)), false)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					rt.Logger.Info().Msg("Aborted")
+					return nil
+				}
+			}
+
+			reclaimed, err := client.ApplyPrune(cmd.Context(), plan, func(c docker.Candidate) {
+				rt.Logger.Debug().Msgf("Removed %s %s", c.Kind, c.Name)
+			})
+			rt.Logger.Info().Msgf("Reclaimed %s", ui.HumanBytes(reclaimed))
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Containers, "containers", false, "Prune stopped containers")
+	cmd.Flags().BoolVar(&opts.Images, "images", false, "Prune dangling images")
+	cmd.Flags().BoolVar(&opts.Volumes, "volumes", false, "Prune unused volumes")
+	cmd.Flags().BoolVar(&opts.BuildCache, "build-cache", false, "Prune unused build cache")
+	cmd.Flags().BoolVar(&opts.AllVolumes, "all-volumes", false, "Also prune unused named volumes")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format for the plan (table|json); json never removes anything")
+	return cmd
+}
+
+func writePrunePlan(out io.Writer, plan *docker.PrunePlan) error {
+	if len(plan.Remove) == 0 {
+		fmt.Fprintln(out, "nothing to prune")
+	} else {
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KIND\tID\tNAME\tSIZE\tREASON")
+		for _, c := range plan.Remove {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Kind, docker.ShortID(c.ID), c.Name, ui.HumanBytes(c.Size), c.Reason)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "\n%d resources, %s reclaimable\n", len(plan.Remove), ui.HumanBytes(plan.Reclaimable()))
+	}
+
+	if len(plan.Keep) > 0 {
+		fmt.Fprintln(out)
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEPT\tID\tNAME\tSIZE\tPROTECTED BY")
+		for _, c := range plan.Keep {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Kind, docker.ShortID(c.ID), c.Name, ui.HumanBytes(c.Size), c.Reason)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// workspaceProtector keeps containers and volumes that belong to a
+// registered workspace: its dev container, or anything compose created for
+// the workspace directory or project.
+func workspaceProtector(entries []workspace.Entry) func(name string, labels map[string]string) string {
+	return func(name string, labels map[string]string) string {
+		for _, e := range entries {
+			reason := "workspace " + e.Name
+			switch {
+			case name == workspaceContainerName(e.Name):
+				return reason
+			case labels[docker.ComposeWorkingDirLabel] != "" &&
+				filepath.Clean(labels[docker.ComposeWorkingDirLabel]) == filepath.Clean(e.Path):
+				return reason
+			case labels[docker.ComposeProjectLabel] != "" &&
+				labels[docker.ComposeProjectLabel] == compose.NormalizeProjectName(filepath.Base(e.Path)):
+				return reason
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/docker"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+// pruneDaemon is an Engine API on a unix socket with resources of the
+// workspace at dir, protected ones and strays. It records removals.
+type pruneDaemon struct {
+	mu      sync.Mutex
+	removed []string
+}
+
+func (d *pruneDaemon) serve(t *testing.T, dir string) string {
+	t.Helper()
+	project := map[string]string{docker.ComposeProjectLabel: "demows"}
+	containers := []docker.Container{
+		{ID: "c-dev", Names: []string{"/demo-dev"}, State: "exited"},
+		{ID: "c-app", Names: []string{"/other-app-1"}, State: "exited", Labels: map[string]string{docker.ComposeWorkingDirLabel: dir}},
+		{ID: "c-db", Names: []string{"/demows-db-1"}, State: "exited", Labels: project},
+		{ID: "c-keep", Names: []string{"/keep"}, State: "exited", Labels: map[string]string{docker.KeepLabel: "true"}},
+		{ID: "c-stray", Names: []string{"/stray"}, State: "exited"},
+	}
+	df := docker.DiskUsage{Volumes: []docker.Volume{
+		{Name: "demows_data", Labels: project, UsageData: &docker.VolumeUsage{}},
+		{Name: "pinned", Labels: map[string]string{docker.KeepLabel: "true"}, UsageData: &docker.VolumeUsage{}},
+		{Name: "scratch", UsageData: &docker.VolumeUsage{}},
+	}}
+
+	mux := http.NewServeMux()
+	prefix := "/v" + docker.APIVersion
+	reply := func(v any) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) { json.NewEncoder(w).Encode(v) }
+	}
+	mux.HandleFunc("GET "+prefix+"/containers/json", reply(containers))
+	mux.HandleFunc("GET "+prefix+"/images/json", reply([]docker.Image{}))
+	mux.HandleFunc("GET "+prefix+"/system/df", reply(df))
+	mux.HandleFunc("POST "+prefix+"/build/prune", reply(map[string]int{"SpaceReclaimed": 0}))
+	for _, kind := range []string{"containers", "images", "volumes"} {
+		mux.HandleFunc("DELETE "+prefix+"/"+kind+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			d.removed = append(d.removed, r.PathValue("id"))
+		})
+	}
+
+	return serveDockerAPI(t, mux)
+}
+
+// serveDockerAPI serves h on a unix socket and returns its docker host.
+func serveDockerAPI(t *testing.T, h http.Handler) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(h)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return "unix://" + socket
+}
+
+// runPrune runs `docker prune` against a fake daemon with the workspace
+// "demo" registered in a directory named Demo.WS, whose compose project is
+// demows. Stdin is never a terminal.
+func runPrune(t *testing.T, dryRun bool, args ...string) (*pruneDaemon, string, error) {
+	t.Helper()
+	stateDir := t.TempDir()
+	dir := filepath.Join(t.TempDir(), "Demo.WS")
+	registry, err := workspace.LoadRegistry(stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Add(workspace.Entry{Name: "demo", Path: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	daemon := &pruneDaemon{}
+	host := daemon.serve(t, dir)
+	saved := newDockerClient
+	newDockerClient = func(*core.Runtime) (*docker.Client, error) { return docker.NewClient(host) }
+	t.Cleanup(func() { newDockerClient = saved })
+
+	rt := &core.Runtime{Logger: zerolog.Nop(), DryRun: dryRun}
+	rt.Config.StateDir = stateDir
+	cmd := NewDockerCommand()
+	var out bytes.Buffer
+	cmd.SetIn(strings.NewReader("y\n"))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	cmd.SetArgs(append([]string{"prune"}, args...))
+	err = cmd.ExecuteContext(core.WithRuntime(context.Background(), rt))
+	return daemon, out.String(), err
+}
+
+func TestDockerPrunePlanKeepsProtected(t *testing.T) {
+	daemon, out, err := runPrune(t, false, "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plan docker.PrunePlan
+	if err := json.Unmarshal([]byte(out), &plan); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	var remove, keep []string
+	for _, c := range plan.Remove {
+		remove = append(remove, c.ID)
+	}
+	for _, c := range plan.Keep {
+		keep = append(keep, c.ID+": "+c.Reason)
+	}
+	if want := []string{"c-stray"}; !slices.Equal(remove, want) {
+		t.Errorf("Remove = %q, want %q", remove, want)
+	}
+	wantKeep := []string{
+		"c-dev: workspace demo",
+		"c-app: workspace demo",
+		"c-db: workspace demo",
+		"c-keep: homekit.keep=true",
+		"demows_data: workspace demo",
+		"pinned: homekit.keep=true",
+		"scratch: named volume (use --all-volumes)",
+	}
+	if !slices.Equal(keep, wantKeep) {
+		t.Errorf("Keep = %q\nwant %q", keep, wantKeep)
+	}
+	if len(daemon.removed) != 0 {
+		t.Errorf("json output removed %q", daemon.removed)
+	}
+}
+
+func TestDockerPruneNeedsYesWithoutTerminal(t *testing.T) {
+	daemon, _, err := runPrune(t, false)
+	if err == nil || !strings.Contains(err.Error(), "--yes") {
+		t.Fatalf("prune without --yes = %v, want a refusal", err)
+	}
+	if len(daemon.removed) != 0 {
+		t.Errorf("removed %q without confirmation", daemon.removed)
+	}
+}
+
+func TestDockerPruneYes(t *testing.T) {
+	daemon, out, err := runPrune(t, false, "--yes", "--all-volumes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c-stray", "scratch"}; !slices.Equal(daemon.removed, want) {
+		t.Errorf("removed = %q, want %q", daemon.removed, want)
+	}
+	if !strings.Contains(out, "PROTECTED BY") {
+		t.Errorf("plan does not list kept resources:\n%s", out)
+	}
+}
+
+func TestDockerPruneDryRun(t *testing.T) {
+	daemon, _, err := runPrune(t, true, "--yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(daemon.removed) != 0 {
+		t.Errorf("dry run removed %q", daemon.removed)
+	}
+}
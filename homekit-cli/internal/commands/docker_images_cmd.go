@@ -0,0 +1,285 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/docker"
+	executor "github.com/homekit/homekit-cli/internal/exec"
+	"github.com/homekit/homekit-cli/internal/imageupdate"
+	"github.com/homekit/homekit-cli/internal/registry"
+	"github.com/homekit/homekit-cli/internal/stack"
+)
+
+// composeFileNames are tried, in order, when a directory is given instead of
+// a compose file.
+var composeFileNames = append(slices.Clone(stack.ComposeFileNames), workspaceComposeFile)
+
+func newDockerImagesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "images",
+		Short: "Manage images used by compose stacks",
+	}
+	cmd.AddCommand(newDockerImagesUpdateCommand())
+	return cmd
+}
+
+func newDockerImagesUpdateCommand() *cobra.Command {
+	var (
+		policy        string
+		apply         bool
+		output        string
+		healthTimeout time.Duration
+	)
+
+	cmd := &cobra.Command{
+		Use:   "update [compose-file|dir ...]",
+		Short: "Check compose images for newer tags or digests and optionally apply them",
+		Long: `Scan compose files for service images and ask their registries for updates.
+
+Version tags (1.2.3, v2.1, 1.25-alpine) move to the newest tag allowed by the
+policy: patch, minor or major. Other tags, such as latest, are compared by
+digest with the local image. A service label homekit.update=<policy> overrides
+--policy; "pinned" and images referenced by digest are never changed.
+
+Without arguments, the compose files of all registered workspaces and of the
+stacks in stacks_dir are scanned. With --apply, changed tags are written to
+the compose file, the images are pulled and the services recreated; if they
+do not become healthy within --health-timeout, the previous file and images
+are restored. Compose files rendered from a stack template are only checked:
+change the image in the template or its values instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			defaultPolicy, err := imageupdate.ParsePolicy(policy)
+			if err != nil {
+				return err
+			}
+			files, err := managedComposeFiles(rt, args)
+			if err != nil {
+				return err
+			}
+
+			var usages []imageupdate.Usage
+			for _, file := range files {
+				found, skipped, err := imageupdate.ScanCompose(file, defaultPolicy)
+				if err != nil {
+					return err
+				}
+				for _, s := range skipped {
+					rt.Logger.Warn().Msgf("Skipping %s", s)
+				}
+				usages = append(usages, found...)
+			}
+			if len(usages) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no images found")
+				return nil
+			}
+
+			client, err := newDockerClient(rt)
+			if err != nil {
+				return err
+			}
+			planner := imageupdate.Planner{
+				Registry: registry.NewClient(),
+				LocalDigests: func(ctx context.Context, image string) ([]string, error) {
+					img, err := client.ImageInspect(ctx, image)
+					if errors.Is(err, docker.ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					return img.RepoDigests, nil
+				},
+			}
+			updates := planner.Plan(cmd.Context(), usages)
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				enc.SetEscapeHTML(false)
+				err = enc.Encode(updates)
+			case "table":
+				err = writeImageUpdatePlan(cmd.OutOrStdout(), updates)
+			default:
+				return fmt.Errorf("unknown output format %q (table|json)", output)
+			}
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, u := range updates {
+				if u.Error != "" {
+					failed++
+				}
+			}
+			if apply {
+				applier := imageupdate.Applier{
+					Docker:        client,
+					Compose:       composeRunner(rt),
+					HealthTimeout: healthTimeout,
+					Logf:          func(format string, args ...any) { rt.Logger.Info().Msgf(format, args...) },
+				}
+				var errs []error
+				for _, file := range files {
+					pending := 0
+					for _, u := range updates {
+						if u.File == file && u.Pending() {
+							pending++
+						}
+					}
+					if pending == 0 {
+						continue
+					}
+					if renderedFromStackTemplate(file) {
+						rt.Logger.Warn().Msgf("Skipping %s: it is rendered from %s/, update the image there", file, filepath.Join(filepath.Dir(file), stack.TemplateDir))
+						continue
+					}
+					if rt.DryRun {
+						rt.Logger.Info().Msgf("Would update %d images in %s", pending, file)
+						continue
+					}
+					if err := applier.Apply(cmd.Context(), file, updates); err != nil {
+						errs = append(errs, err)
+						continue
+					}
+					rt.Logger.Info().Msgf("Updated %d images in %s", pending, file)
+				}
+				if err := errors.Join(errs...); err != nil {
+					return err
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d images could not be checked", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&policy, "policy", string(imageupdate.PolicyMinor), "Default update policy (patch|minor|major|pinned)")
+	cmd.Flags().BoolVar(&apply, "apply", false, "Pull updates, recreate services and roll back on failed health checks")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format for the plan (table|json)")
+	cmd.Flags().DurationVar(&healthTimeout, "health-timeout", time.Minute, "How long recreated services may take to become healthy")
+	return cmd
+}
+
+// managedComposeFiles resolves the arguments to compose files, defaulting
+// to every registered workspace and stack.
+func managedComposeFiles(rt *core.Runtime, args []string) ([]string, error) {
+	var files []string
+	if len(args) == 0 {
+		workspaces, err := loadWorkspaceRegistry(rt)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range workspaces.List() {
+			file := filepath.Join(e.Path, workspaceComposeFile)
+			if _, err := os.Stat(file); err == nil {
+				files = append(files, file)
+			}
+		}
+		dir, err := rt.Config.StacksDirectory()
+		if err != nil {
+			return nil, err
+		}
+		stacks, err := stack.List(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range stacks {
+			if s.ComposeFile != "" {
+				files = append(files, s.ComposeFile)
+			}
+		}
+		return files, nil
+	}
+	for _, arg := range args {
+		file, err := findComposeFile(arg)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// findComposeFile returns path itself, or the first compose file inside it
+// when it is a directory.
+func findComposeFile(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return abs, nil
+	}
+	for _, name := range composeFileNames {
+		if _, err := os.Stat(filepath.Join(abs, name)); err == nil {
+			return filepath.Join(abs, name), nil
+		}
+	}
+	return "", fmt.Errorf("no compose file in %s", path)
+}
+
+// renderedFromStackTemplate reports whether a compose file belongs to a
+// templated stack, where edits would be lost on the next render.
+func renderedFromStackTemplate(file string) bool {
+	info, err := os.Stat(filepath.Join(filepath.Dir(file), stack.TemplateDir))
+	return err == nil && info.IsDir()
+}
+
+// composeRunner runs docker compose for a compose file in its directory.
+func composeRunner(rt *core.Runtime) func(ctx context.Context, file string, args ...string) error {
+	return func(ctx context.Context, file string, args ...string) error {
+		rt.Logger.Debug().Msgf("docker compose %v in %s", args, filepath.Dir(file))
+		_, err := executor.Run(ctx, executor.Spec{
+			Command: "docker",
+			Args:    append([]string{"compose", "-f", filepath.Base(file)}, args...),
+			Dir:     filepath.Dir(file),
+			DryRun:  rt.DryRun,
+		})
+		return err
+	}
+}
+
+func writeImageUpdatePlan(out io.Writer, updates []imageupdate.Update) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSERVICE\tIMAGE\tPOLICY\tUPDATE\tNOTE")
+	pending := 0
+	for _, u := range updates {
+		change, note := "-", u.Note
+		if u.Pending() {
+			pending++
+			change = string(u.Kind) + ": " + u.Target
+		}
+		if u.Error != "" {
+			change, note = "error", u.Error
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", u.File, u.Service, u.Image, u.Policy, change, note)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d of %d images have updates\n", pending, len(updates))
+	return nil
+}
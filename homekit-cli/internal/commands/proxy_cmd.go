@@ -0,0 +1,259 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/assets"
+	"github.com/homekit/homekit-cli/internal/compose"
+	"github.com/homekit/homekit-cli/internal/core"
+	executor "github.com/homekit/homekit-cli/internal/exec"
+	"github.com/homekit/homekit-cli/internal/proxy"
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+	"github.com/homekit/homekit-cli/internal/util/pathformat"
+)
+
+// proxyTemplates maps proxy types to their template in the templates namespace.
+var proxyTemplates = map[string]string{
+	proxy.TypeCaddy:   "proxy/Caddyfile.tmpl",
+	proxy.TypeTraefik: "proxy/traefik.yaml.tmpl",
+}
+
+// NewProxyCommand generates reverse-proxy configuration from compose labels.
+func NewProxyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Generate reverse-proxy configuration from compose labels",
+	}
+
+	cmd.AddCommand(newProxyGenerateCommand(), newProxyRoutesCommand())
+	return cmd
+}
+
+// proxyOptions holds the flags shared by proxy commands, defaulting to the
+// proxy config section.
+type proxyOptions struct {
+	upstream string
+}
+
+func (o *proxyOptions) bind(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.upstream, "upstream", "", "How the proxy reaches services: container (<project>-<service>-1) or host (published port); default proxy.upstream, else container")
+}
+
+func (o *proxyOptions) routes(rt *core.Runtime, args []string) ([]proxy.Route, error) {
+	upstream := o.upstream
+	if upstream == "" {
+		upstream = rt.Config.Proxy.Upstream
+	}
+	files, err := managedComposeFiles(rt, args)
+	if err != nil {
+		return nil, err
+	}
+	var routes []proxy.Route
+	var errs []error
+	for _, file := range files {
+		lookup, err := compose.EnvLookup(filepath.Dir(file))
+		if err != nil {
+			return nil, err
+		}
+		project, _, err := compose.Load(file, lookup)
+		if err != nil {
+			return nil, err
+		}
+		found, err := proxy.Routes(project, upstream)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		routes = append(routes, found...)
+	}
+	if err := errors.Join(append(errs, proxy.CheckHosts(routes))...); err != nil {
+		return nil, err
+	}
+	rt.Logger.Debug().Msgf("%d route(s) from %d compose file(s)", len(routes), len(files))
+	return routes, nil
+}
+
+func newProxyGenerateCommand() *cobra.Command {
+	var (
+		opts          proxyOptions
+		outOpts       templateOutputOptions
+		proxyType     string
+		output        string
+		reload        bool
+		reloadCommand string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "generate [compose-file|dir ...]",
+		Short: "Render a Caddyfile or Traefik dynamic config for exposed services",
+		Long: `Render reverse-proxy configuration for every compose service with a
+homekit.expose.host label. Without arguments the compose files of all
+registered workspaces and stacks are read; nothing talks to a running proxy.
+
+Labels:
+  homekit.expose.host  host names, comma-separated (required)
+  homekit.expose.port  container port (optional when the service maps one port)
+  homekit.expose.auth  Caddy snippet to import or Traefik middleware to apply
+  homekit.expose.tls   auto (default), internal or off
+
+The configuration comes from templates/proxy/Caddyfile.tmpl or
+templates/proxy/traefik.yaml.tmpl, which asset overrides can replace. --output
+(default proxy.output) is replaced atomically only when it changes, with
+--diff, --check and --backup as in "template render". --reload runs
+proxy.reload_command after the file changed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			if proxyType == "" {
+				proxyType = rt.Config.Proxy.Type
+			}
+			if proxyType == "" {
+				proxyType = proxy.TypeCaddy
+			}
+			name, ok := proxyTemplates[proxyType]
+			if !ok {
+				return fmt.Errorf("unknown proxy type %q (caddy|traefik)", proxyType)
+			}
+			if output == "" {
+				output = pathformat.ExpandHome(rt.Config.Proxy.Output)
+			}
+			if reloadCommand == "" {
+				reloadCommand = rt.Config.Proxy.ReloadCommand
+			}
+			if output == "" && (outOpts.diff || outOpts.check || outOpts.backup || reload) {
+				return errors.New("--diff, --check, --backup and --reload require --output or proxy.output")
+			}
+			if reload && strings.TrimSpace(reloadCommand) == "" {
+				return errors.New("--reload needs --reload-command or proxy.reload_command")
+			}
+
+			routes, err := opts.routes(rt, args)
+			if err != nil {
+				return err
+			}
+
+			manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
+			vfs, err := manager.FS(assets.AssetNamespaceTemplates)
+			if err != nil {
+				return err
+			}
+			var rendered bytes.Buffer
+			renderer := newTemplateRenderer(cmd.Context(), rt)
+			if err := renderer.RenderFile(vfs, name, map[string]any{"Routes": routes}, &rendered); err != nil {
+				return err
+			}
+
+			if output == "" {
+				_, err := cmd.OutOrStdout().Write(rendered.Bytes())
+				return err
+			}
+			status, _, err := fileutil.Compare(output, rendered.Bytes())
+			if err != nil {
+				return err
+			}
+			if err := writeTemplateOutput(cmd, rt, output, rendered.Bytes(), outOpts); err != nil {
+				return err
+			}
+			rt.Logger.Info().Msgf("%d route(s) in %s", len(routes), output)
+			if !reload || outOpts.check {
+				return nil
+			}
+			if status == fileutil.StatusUnchanged {
+				rt.Logger.Info().Msg("Configuration unchanged, not reloading")
+				return nil
+			}
+			return reloadProxy(cmd, rt, reloadCommand)
+		},
+	}
+
+	opts.bind(cmd)
+	cmd.Flags().StringVar(&proxyType, "type", "", "Proxy type (caddy|traefik; default proxy.type, else caddy)")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "File to write (default proxy.output, else stdout)")
+	cmd.Flags().BoolVar(&outOpts.diff, "diff", false, "Show a unified diff between --output and the generated config")
+	cmd.Flags().BoolVar(&outOpts.backup, "backup", false, "Keep the previous --output as <file>.bak before replacing it")
+	cmd.Flags().BoolVar(&outOpts.check, "check", false, "Do not write; exit non-zero when --output is out of date")
+	cmd.Flags().BoolVar(&reload, "reload", false, "Run the reload command when the file changed")
+	cmd.Flags().StringVar(&reloadCommand, "reload-command", "", "Command run by --reload (default proxy.reload_command)")
+	return cmd
+}
+
+// reloadProxy runs the configured reload command.
+func reloadProxy(cmd *cobra.Command, rt *core.Runtime, command string) error {
+	fields := strings.Fields(command)
+	if rt.DryRun {
+		rt.Logger.Info().Msgf("Would run %s", command)
+		return nil
+	}
+	res, err := executor.Run(cmd.Context(), executor.Spec{
+		Command:       fields[0],
+		Args:          fields[1:],
+		CaptureOutput: true,
+	})
+	if err != nil {
+		return fmt.Errorf("reload proxy: %w: %s", err, strings.TrimSpace(res.Stderr))
+	}
+	rt.Logger.Info().Msgf("Reloaded proxy with %s", command)
+	return nil
+}
+
+func newProxyRoutesCommand() *cobra.Command {
+	var (
+		opts   proxyOptions
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "routes [compose-file|dir ...]",
+		Short: "List the services exposed through homekit.expose.* labels",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			routes, err := opts.routes(rt, args)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				if routes == nil {
+					routes = []proxy.Route{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(routes)
+			case "table":
+			default:
+				return fmt.Errorf("unknown output format %q (table|json)", output)
+			}
+			if len(routes) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "no services carry a %s label\n", proxy.LabelHost)
+				return nil
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tHOSTS\tUPSTREAM\tTLS\tAUTH")
+			for _, r := range routes {
+				auth := r.Auth
+				if auth == "" {
+					auth = "-"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", r.Name, strings.Join(r.Hosts, ","), r.Upstream, r.TLS, auth)
+			}
+			return tw.Flush()
+		},
+	}
+
+	opts.bind(cmd)
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	return cmd
+}
@@ -63,7 +63,7 @@ func NewScriptCommand() *cobra.Command {
 				return err
 			}
 			manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
-			names, err := manager.List("scripts")
+			names, err := manager.List(assets.AssetNamespaceScripts)
 			if err != nil {
 				return err
 			}
@@ -94,9 +94,9 @@ func runScript(cmd *cobra.Command, rt *core.Runtime, embeddedName string, spec e
 	}
 
 	manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
-	handle, err := manager.Open("scripts", embeddedName)
+	handle, err := manager.Open(assets.AssetNamespaceScripts, embeddedName)
 	if err != nil {
-		return fmt.Errorf("open embedded script: %w", err)
+		return fmt.Errorf("open embedded script: %w", describeAssetError(err, assets.AssetNamespaceScripts, embeddedName))
 	}
 	defer handle.Close()
 
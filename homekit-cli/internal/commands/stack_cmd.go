@@ -0,0 +1,611 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/homekit/homekit-cli/internal/assets"
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/docker"
+	"github.com/homekit/homekit-cli/internal/stack"
+	"github.com/homekit/homekit-cli/internal/templating"
+	"github.com/homekit/homekit-cli/internal/util/diffutil"
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+	"github.com/homekit/homekit-cli/internal/util/pathformat"
+)
+
+// NewStackCommand manages self-hosted compose stacks below stacks_dir.
+func NewStackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stack",
+		Short: "Manage self-hosted compose stacks",
+		Long: `Manage compose applications kept in stacks_dir (default ~/stacks), one
+directory per stack. Stacks created with "stack new" keep their template in
+template/ and its values in values.yaml; values.<profile>.yaml overlays them
+for a profile. Templated stacks are rendered again by "stack up".
+
+Commands taking an optional name default to the stack containing the current
+directory.`,
+	}
+
+	cmd.AddCommand(
+		newStackNewCommand(),
+		newStackUpCommand(),
+		newStackComposeCommand("down", "Stop and remove a stack's containers", "down"),
+		newStackServicesCommand("restart", "Restart a stack's services", "restart"),
+		newStackLogsCommand(),
+		newStackPsCommand(),
+		newStackConfigCommand(),
+		newStackListCommand(),
+	)
+	return cmd
+}
+
+func newStackNewCommand() *cobra.Command {
+	var (
+		dataOpts templateDataOptions
+		from     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "new <name> --from <template>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Create a stack from a template",
+		Long: `Create <stacks_dir>/<name> from a template in the templates namespace (a
+single compose template or a directory rendered like render-dir) or a local
+path. The template is copied to template/, the data given with --data and
+--set is saved as values.yaml, and the compose file is rendered. Templates
+see the values plus .Stack (the name) and .Profile.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			name := args[0]
+			if err := stack.ValidateName(name); err != nil {
+				return err
+			}
+			dir, err := rt.Config.StacksDirectory()
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(dir, name)
+			if _, err := os.Stat(target); err == nil {
+				return fmt.Errorf("stack %s already exists at %s", name, target)
+			}
+
+			data, err := dataOpts.load(cmd)
+			if err != nil {
+				return err
+			}
+			values, err := yaml.Marshal(data)
+			if err != nil {
+				return fmt.Errorf("encode values: %w", err)
+			}
+			manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
+			files, err := stackTemplateFiles(manager, from)
+			if err != nil {
+				return err
+			}
+
+			stage, err := fileutil.NewStage(rt.Config.TempDirectory())
+			if err != nil {
+				return fmt.Errorf("create staging directory: %w", err)
+			}
+			defer stage.Close()
+			for rel, file := range files {
+				if err := stage.Add(path.Join(stack.TemplateDir, rel), file.content, file.mode); err != nil {
+					return err
+				}
+			}
+			if err := stage.Add(stack.ValuesFile, values, 0o644); err != nil {
+				return err
+			}
+
+			// render from the staged copy so the stack is complete before it is moved into place
+			staged := stack.Stack{Name: name, Path: stage.Dir(), Templated: true}
+			renderer := newTemplateRenderer(cmd.Context(), rt)
+			renderer.Strict = dataOpts.strict
+			rendered, err := staged.Render(renderer, "")
+			if err != nil {
+				return err
+			}
+			if renderedComposeFile(rendered) == "" {
+				return fmt.Errorf("template %s renders no compose file (%s)", from, strings.Join(stack.ComposeFileNames, ", "))
+			}
+			for _, file := range rendered {
+				if err := stage.Add(file.Path, file.Content, file.Mode); err != nil {
+					return err
+				}
+			}
+
+			if rt.DryRun {
+				for _, rel := range stage.Files() {
+					rt.Logger.Info().Msgf("Would create %s", filepath.Join(target, filepath.FromSlash(rel)))
+				}
+				return nil
+			}
+			if err := stage.Commit(target); err != nil {
+				return err
+			}
+			rt.Logger.Info().Msgf("Created stack %s in %s", name, target)
+			return nil
+		},
+	}
+
+	dataOpts.bind(cmd)
+	cmd.Flags().StringVar(&from, "from", "", "Template file or directory (templates namespace or local path)")
+	_ = cmd.MarkFlagRequired("from")
+	return cmd
+}
+
+func newStackUpCommand() *cobra.Command {
+	var profile string
+
+	cmd := &cobra.Command{
+		Use:   "up [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Render a stack (when templated) and start it",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			s, err := resolveStack(rt, args)
+			if err != nil {
+				return err
+			}
+			file, err := renderStack(cmd, rt, s, profile)
+			if err != nil {
+				return err
+			}
+			return composeRunner(rt)(cmd.Context(), file, "up", "-d")
+		},
+	}
+
+	cmd.Flags().StringVar(&profile, "profile", "", "Profile whose values.<profile>.yaml overlays values.yaml")
+	return cmd
+}
+
+// newStackComposeCommand runs one docker compose command for a stack.
+func newStackComposeCommand(use, short string, composeArgs ...string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStackCompose(cmd, args, composeArgs...)
+		},
+	}
+}
+
+// newStackServicesCommand runs a docker compose command for a stack,
+// limited to the services named with --service.
+func newStackServicesCommand(use, short string, composeArgs ...string) *cobra.Command {
+	var services []string
+
+	cmd := &cobra.Command{
+		Use:   use + " [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: short,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStackCompose(cmd, args, append(composeArgs, services...)...)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&services, "service", "s", nil, "Limit to these services")
+	return cmd
+}
+
+func newStackLogsCommand() *cobra.Command {
+	var (
+		follow   bool
+		tail     int
+		services []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "logs [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Show stack logs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			composeArgs := []string{"logs"}
+			if follow {
+				composeArgs = append(composeArgs, "--follow")
+			}
+			if tail >= 0 {
+				composeArgs = append(composeArgs, "--tail", strconv.Itoa(tail))
+			}
+			return runStackCompose(cmd, args, append(composeArgs, services...)...)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	cmd.Flags().IntVar(&tail, "tail", -1, "Number of lines to show from the end (-1 for all)")
+	cmd.Flags().StringSliceVarP(&services, "service", "s", nil, "Limit to these services")
+	return cmd
+}
+
+func newStackPsCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "ps [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "List a stack's containers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			s, err := resolveStack(rt, args)
+			if err != nil {
+				return err
+			}
+			return showComposeContainers(cmd, rt, s.Path, output,
+				fmt.Sprintf("stack %s has no containers (see `stack up`)", s.Name))
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	return cmd
+}
+
+func newStackConfigCommand() *cobra.Command {
+	var (
+		profile string
+		write   bool
+		diff    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "config [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Print the effective compose file of a stack",
+		Long: `Render the stack's template with values.yaml and the profile overlay and
+print the compose file. --diff compares every rendered file with the one on
+disk and --write updates them. Stacks without a template print their compose
+file as is.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			s, err := resolveStack(rt, args)
+			if err != nil {
+				return err
+			}
+			if !s.Templated {
+				if write || diff || profile != "" {
+					return fmt.Errorf("stack %s has no %s/: --profile, --diff and --write need a templated stack", s.Name, stack.TemplateDir)
+				}
+				if s.ComposeFile == "" {
+					return fmt.Errorf("stack %s: %w", s.Name, stack.ErrNoComposeFile)
+				}
+				content, err := os.ReadFile(s.ComposeFile)
+				if err != nil {
+					return err
+				}
+				_, err = cmd.OutOrStdout().Write(content)
+				return err
+			}
+
+			renderer := newTemplateRenderer(cmd.Context(), rt)
+			rendered, err := s.Render(renderer, profile)
+			if err != nil {
+				return err
+			}
+			switch {
+			case write:
+				return writeRenderedFiles(cmd, rt, rendered, s.Path)
+			case diff:
+				for _, file := range rendered {
+					target := filepath.Join(s.Path, filepath.FromSlash(file.Path))
+					status, current, err := fileutil.Compare(target, file.Content)
+					if err != nil {
+						return err
+					}
+					oldName := target
+					switch status {
+					case fileutil.StatusUnchanged:
+						continue
+					case fileutil.StatusCreated:
+						oldName = os.DevNull
+					}
+					fmt.Fprint(cmd.OutOrStdout(), diffutil.Unified(oldName, target, string(current), string(file.Content), diffutil.DefaultContext))
+				}
+				return nil
+			}
+			compose := renderedComposeFile(rendered)
+			for _, file := range rendered {
+				if file.Path == compose {
+					_, err := cmd.OutOrStdout().Write(file.Content)
+					return err
+				}
+			}
+			return fmt.Errorf("stack %s: template renders no compose file", s.Name)
+		},
+	}
+
+	cmd.Flags().StringVar(&profile, "profile", "", "Profile whose values.<profile>.yaml overlays values.yaml")
+	cmd.Flags().BoolVar(&write, "write", false, "Write the rendered files into the stack directory")
+	cmd.Flags().BoolVar(&diff, "diff", false, "Show a unified diff between the files on disk and the rendered result")
+	cmd.MarkFlagsMutuallyExclusive("write", "diff")
+	return cmd
+}
+
+// stackListing is one row of `stack list`. Running and Containers are -1
+// when docker could not be asked.
+type stackListing struct {
+	stack.Stack
+	Running    int `json:"running"`
+	Containers int `json:"containers"`
+}
+
+func newStackListCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Args:  cobra.NoArgs,
+		Short: "List stacks in stacks_dir with their running containers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			dir, err := rt.Config.StacksDirectory()
+			if err != nil {
+				return err
+			}
+			stacks, err := stack.List(dir)
+			if err != nil {
+				return err
+			}
+
+			listings := make([]stackListing, len(stacks))
+			for i, s := range stacks {
+				listings[i] = stackListing{Stack: s, Running: -1, Containers: -1}
+			}
+			if len(stacks) > 0 {
+				if err := countStackContainers(cmd, rt, listings); err != nil {
+					rt.Logger.Warn().Msgf("Container status unavailable: %v", err)
+				}
+			}
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(listings)
+			case "table":
+			default:
+				return fmt.Errorf("unknown output format %q (table|json)", output)
+			}
+			if len(listings) == 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "no stacks in %s\n", dir)
+				return nil
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tTEMPLATED\tRUNNING\tPATH")
+			for _, l := range listings {
+				running := "-"
+				if l.Containers >= 0 {
+					running = fmt.Sprintf("%d/%d", l.Running, l.Containers)
+				}
+				fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", l.Name, l.Templated, running, l.Path)
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	return cmd
+}
+
+// countStackContainers fills in container counts with one query for every
+// compose-managed container.
+func countStackContainers(cmd *cobra.Command, rt *core.Runtime, listings []stackListing) error {
+	client, err := newDockerClient(rt)
+	if err != nil {
+		return err
+	}
+	containers, err := client.ContainerList(cmd.Context(), docker.ContainerListOptions{
+		All:     true,
+		Filters: docker.Filters{"label": {docker.ComposeWorkingDirLabel}},
+	})
+	if err != nil {
+		return err
+	}
+	for i := range listings {
+		listings[i].Running, listings[i].Containers = 0, 0
+		for _, c := range containers {
+			if filepath.Clean(c.Labels[docker.ComposeWorkingDirLabel]) != filepath.Clean(listings[i].Path) {
+				continue
+			}
+			listings[i].Containers++
+			if c.State == "running" {
+				listings[i].Running++
+			}
+		}
+	}
+	return nil
+}
+
+// resolveStack opens the stack named in args, or the one containing the
+// current directory.
+func resolveStack(rt *core.Runtime, args []string) (stack.Stack, error) {
+	dir, err := rt.Config.StacksDirectory()
+	if err != nil {
+		return stack.Stack{}, err
+	}
+	if len(args) > 0 {
+		return stack.Open(dir, args[0])
+	}
+	rel, err := filepath.Rel(dir, pathformat.Pwd())
+	if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return stack.Open(dir, strings.Split(rel, string(filepath.Separator))[0])
+	}
+	return stack.Stack{}, fmt.Errorf("not inside a stack: pass a stack name or run from a directory below %s", dir)
+}
+
+// runStackCompose runs `docker compose <args>` for the resolved stack.
+func runStackCompose(cmd *cobra.Command, args []string, composeArgs ...string) error {
+	rt, err := runtimeFrom(cmd)
+	if err != nil {
+		return err
+	}
+	s, err := resolveStack(rt, args)
+	if err != nil {
+		return err
+	}
+	if s.ComposeFile == "" {
+		return fmt.Errorf("stack %s: %w (see `stack up`)", s.Name, stack.ErrNoComposeFile)
+	}
+	return composeRunner(rt)(cmd.Context(), s.ComposeFile, composeArgs...)
+}
+
+// renderStack writes the rendered files of a templated stack and returns its
+// compose file. Untemplated stacks are used as they are.
+func renderStack(cmd *cobra.Command, rt *core.Runtime, s stack.Stack, profile string) (string, error) {
+	if !s.Templated {
+		if profile != "" {
+			return "", fmt.Errorf("stack %s has no %s/ to apply --profile to", s.Name, stack.TemplateDir)
+		}
+		if s.ComposeFile == "" {
+			return "", fmt.Errorf("stack %s: %w", s.Name, stack.ErrNoComposeFile)
+		}
+		return s.ComposeFile, nil
+	}
+
+	rendered, err := s.Render(newTemplateRenderer(cmd.Context(), rt), profile)
+	if err != nil {
+		return "", err
+	}
+	compose := renderedComposeFile(rendered)
+	if compose == "" {
+		return "", fmt.Errorf("stack %s: template renders no compose file", s.Name)
+	}
+	for _, file := range rendered {
+		target := filepath.Join(s.Path, filepath.FromSlash(file.Path))
+		status, err := fileutil.WriteIfChanged(target, file.Content, file.Mode, rt.DryRun)
+		if err != nil {
+			return "", fmt.Errorf("write %s: %w", target, err)
+		}
+		if status != fileutil.StatusUnchanged {
+			rt.Logger.Info().Msgf("%s %s", target, dryRunStatus(status, rt.DryRun))
+		}
+	}
+	return filepath.Join(s.Path, filepath.FromSlash(compose)), nil
+}
+
+// renderedComposeFile returns the path of the rendered compose file, the
+// first of stack.ComposeFileNames at the top level.
+func renderedComposeFile(files []templating.RenderedFile) string {
+	for _, name := range stack.ComposeFileNames {
+		if slices.ContainsFunc(files, func(f templating.RenderedFile) bool { return f.Path == name }) {
+			return name
+		}
+	}
+	return ""
+}
+
+// stackTemplateFile is a file copied into a new stack's template directory.
+type stackTemplateFile struct {
+	content []byte
+	mode    fs.FileMode
+}
+
+// stackTemplateFiles reads the template a stack is created from. Directories
+// are copied as they are; a single template becomes compose.yaml.tmpl, along
+// with its sidecar schema.
+func stackTemplateFiles(manager *assets.Manager, ref string) (map[string]stackTemplateFile, error) {
+	var (
+		src  fs.FS
+		name string
+	)
+	if !isLocalTemplateRef(ref) {
+		vfs, err := manager.FS(assets.AssetNamespaceTemplates)
+		if err != nil {
+			return nil, err
+		}
+		asset := path.Clean(strings.Trim(ref, "/"))
+		_, statErr := fs.Stat(vfs, asset)
+		switch {
+		case statErr == nil:
+			src, name = vfs, asset
+		case !errors.Is(statErr, fs.ErrNotExist):
+			return nil, describeAssetError(statErr, assets.AssetNamespaceTemplates, ref)
+		default:
+			if _, err := os.Stat(ref); err != nil {
+				return nil, describeAssetError(fmt.Errorf("%w: %s/%s", assets.ErrAssetNotFound, assets.AssetNamespaceTemplates, ref), assets.AssetNamespaceTemplates, ref)
+			}
+		}
+	}
+	if src == nil {
+		full := pathformat.RenderFullPath(ref)
+		src, name = os.DirFS(filepath.Dir(full)), filepath.Base(full)
+	}
+
+	info, err := fs.Stat(src, name)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", ref, err)
+	}
+	files := map[string]stackTemplateFile{}
+	read := func(vfs fs.FS, from, to string) error {
+		content, err := fs.ReadFile(vfs, from)
+		if err != nil {
+			return err
+		}
+		mode := fs.FileMode(0o644)
+		if info, err := fs.Stat(vfs, from); err == nil && info.Mode()&0o111 != 0 {
+			mode = 0o755
+		}
+		files[to] = stackTemplateFile{content: content, mode: mode}
+		return nil
+	}
+
+	if info.IsDir() {
+		sub, err := fs.Sub(src, name)
+		if err != nil {
+			return nil, err
+		}
+		err = fs.WalkDir(sub, ".", func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return read(sub, p, p)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", ref, err)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("template %s is empty", ref)
+		}
+		return files, nil
+	}
+
+	const target = "compose.yaml.tmpl"
+	if err := read(src, name, target); err != nil {
+		return nil, fmt.Errorf("template %s: %w", ref, err)
+	}
+	sidecar := path.Join(path.Dir(name), templating.SidecarSchemaName(path.Base(name)))
+	if _, err := fs.Stat(src, sidecar); err == nil {
+		if err := read(src, sidecar, templating.SidecarSchemaName(target)); err != nil {
+			return nil, fmt.Errorf("template %s: %w", ref, err)
+		}
+	}
+	return files, nil
+}
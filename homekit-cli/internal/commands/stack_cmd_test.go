@@ -0,0 +1,260 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/docker"
+	"github.com/homekit/homekit-cli/internal/templating"
+)
+
+// fakeDockerCLI puts a docker script on PATH that records its working
+// directory and arguments. The returned func reads the calls so far, one
+// "<dir>|<args>" line each.
+func fakeDockerCLI(t *testing.T) func() []string {
+	t.Helper()
+	bin := t.TempDir()
+	log := filepath.Join(t.TempDir(), "docker.log")
+	script := "#!/bin/sh\nprintf '%s|%s\\n' \"$(pwd)\" \"$*\" >> " + log + "\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return func() []string {
+		content, err := os.ReadFile(log)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	}
+}
+
+// chdir changes the working directory for the rest of the test.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	old, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(old) })
+}
+
+func stackRuntime(t *testing.T) *core.Runtime {
+	t.Helper()
+	rt := &core.Runtime{Logger: zerolog.Nop()}
+	rt.Config.StateDir = t.TempDir()
+	rt.Config.StacksDir = filepath.Join(t.TempDir(), "stacks")
+	return rt
+}
+
+func runStack(t *testing.T, rt *core.Runtime, args ...string) (string, error) {
+	t.Helper()
+	cmd := NewStackCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(core.WithRuntime(context.Background(), rt))
+	return out.String(), err
+}
+
+func TestStackNewUpConfig(t *testing.T) {
+	calls := fakeDockerCLI(t)
+	rt := stackRuntime(t)
+	tmpl := filepath.Join(t.TempDir(), "app.yaml.tmpl")
+	content := "services:\n  web:\n    image: web:{{ .tag }}\n    ports: [\"{{ .port }}:80\"]\n"
+	if err := os.WriteFile(tmpl, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := runStack(t, rt, "new", "app", "--from", tmpl, "--set", "tag=1,port=8080"); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(rt.Config.StacksDir, "app")
+	compose := filepath.Join(dir, "compose.yaml")
+	if got := readTestFile(t, compose); !strings.Contains(got, `"8080:80"`) {
+		t.Fatalf("rendered compose.yaml:\n%s", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "template", "compose.yaml.tmpl")); got != content {
+		t.Fatalf("template copy:\n%s", got)
+	}
+	if _, err := runStack(t, rt, "new", "app", "--from", tmpl); err == nil {
+		t.Fatal("expected an error for an existing stack")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "values.prod.yaml"), []byte("port: 9090\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runStack(t, rt, "config", "app", "--profile", "prod", "--diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `-    ports: ["8080:80"]`) || !strings.Contains(out, `+    ports: ["9090:80"]`) {
+		t.Fatalf("config --diff:\n%s", out)
+	}
+	if _, err := runStack(t, rt, "config", "app", "--profile", "staging"); err == nil {
+		t.Fatal("expected an error for an unknown profile")
+	}
+
+	chdir(t, filepath.Join(dir, "template"))
+	if _, err := runStack(t, rt, "up", "--profile", "prod"); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, compose); !strings.Contains(got, `"9090:80"`) {
+		t.Fatalf("compose.yaml after up:\n%s", got)
+	}
+	if _, err := runStack(t, rt, "down", "app"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{dir + "|compose -f compose.yaml up -d", dir + "|compose -f compose.yaml down"}
+	if got := calls(); !slices.Equal(got, want) {
+		t.Fatalf("docker calls %q, want %q", got, want)
+	}
+
+	out, err = runStack(t, rt, "config", "--profile", "prod", "--diff")
+	if err != nil || out != "" {
+		t.Fatalf("config --diff after up = %q, %v", out, err)
+	}
+}
+
+func TestStackUpDryRun(t *testing.T) {
+	calls := fakeDockerCLI(t)
+	rt := stackRuntime(t)
+	dir := filepath.Join(rt.Config.StacksDir, "plain")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docker-compose.yml"), []byte("services: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runStack(t, rt, "up", "plain", "--profile", "prod"); err == nil {
+		t.Fatal("expected --profile to need a template")
+	}
+	rt.DryRun = true
+	if _, err := runStack(t, rt, "up", "plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls(); got != nil {
+		t.Fatalf("dry run ran docker: %q", got)
+	}
+	rt.DryRun = false
+	if _, err := runStack(t, rt, "restart", "plain", "-s", "web"); err != nil {
+		t.Fatal(err)
+	}
+	if got := calls(); !slices.Equal(got, []string{dir + "|compose -f docker-compose.yml restart web"}) {
+		t.Fatalf("docker calls %q", got)
+	}
+}
+
+func TestStackList(t *testing.T) {
+	saved := newDockerClient
+	newDockerClient = func(*core.Runtime) (*docker.Client, error) { return nil, errors.New("no daemon") }
+	t.Cleanup(func() { newDockerClient = saved })
+
+	rt := stackRuntime(t)
+	out, err := runStack(t, rt, "list")
+	if err != nil || !strings.HasPrefix(out, "no stacks in ") {
+		t.Fatalf("list = %q, %v", out, err)
+	}
+	if err := os.MkdirAll(filepath.Join(rt.Config.StacksDir, "web"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(rt.Config.StacksDir, "web", "compose.yaml"), []byte("services: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runStack(t, rt, "list", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listings []stackListing
+	if err := json.Unmarshal([]byte(out), &listings); err != nil {
+		t.Fatal(err)
+	}
+	if len(listings) != 1 || listings[0].Name != "web" || listings[0].Running != -1 || listings[0].Containers != -1 {
+		t.Fatalf("listings %+v", listings)
+	}
+}
+
+func TestResolveStack(t *testing.T) {
+	rt := stackRuntime(t)
+	nested := filepath.Join(rt.Config.StacksDir, "app", "config", "nginx")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cwd  string
+		args []string
+		want string
+	}{
+		{cwd: t.TempDir(), args: []string{"app"}, want: "app"},
+		{cwd: nested, want: "app"},
+		{cwd: filepath.Join(rt.Config.StacksDir, "app"), want: "app"},
+		{cwd: rt.Config.StacksDir},
+		{cwd: t.TempDir()},
+	}
+	for _, tt := range tests {
+		chdir(t, tt.cwd)
+		s, err := resolveStack(rt, tt.args)
+		if tt.want == "" {
+			if err == nil || !strings.Contains(err.Error(), "not inside a stack") {
+				t.Errorf("resolveStack in %s = %+v, %v; want an error", tt.cwd, s, err)
+			}
+			continue
+		}
+		if err != nil || s.Name != tt.want || s.Path != filepath.Join(rt.Config.StacksDir, tt.want) {
+			t.Errorf("resolveStack in %s = %+v, %v; want %s", tt.cwd, s, err, tt.want)
+		}
+	}
+}
+
+func TestRenderedComposeFile(t *testing.T) {
+	files := func(paths ...string) []templating.RenderedFile {
+		var out []templating.RenderedFile
+		for _, p := range paths {
+			out = append(out, templating.RenderedFile{Path: p})
+		}
+		return out
+	}
+	tests := []struct {
+		files []templating.RenderedFile
+		want  string
+	}{
+		{files("compose.yaml"), "compose.yaml"},
+		{files("docker-compose.yml", "compose.yml"), "compose.yml"},
+		{files("config/compose.yaml", ".env"), ""},
+		{files("compose.dev.yml"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := renderedComposeFile(tt.files); got != tt.want {
+			t.Errorf("renderedComposeFile(%v) = %q, want %q", tt.files, got, tt.want)
+		}
+	}
+}
+
+func readTestFile(t *testing.T, p string) string {
+	t.Helper()
+	content, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
@@ -1,16 +1,24 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
-	"runtime"
+	"io"
+	"strings"
+	"text/tabwriter"
 	"time"
 
-	"github.com/shirou/gopsutil/v3/disk"
-	"github.com/shirou/gopsutil/v3/load"
-	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/sysinfo"
+	"github.com/homekit/homekit-cli/internal/ui"
 )
 
+// healthProvider is the metrics source for the sys commands; replace it to
+// run them against fake data.
+var healthProvider sysinfo.Provider = sysinfo.GopsutilProvider{}
+
 // NewSystemCommand delivers lightweight health checks.
 func NewSystemCommand() *cobra.Command {
 	cmd := &cobra.Command{
@@ -18,40 +26,181 @@ func NewSystemCommand() *cobra.Command {
 		Short: "Inspect local system health metrics",
 	}
 
-	cmd.AddCommand(&cobra.Command{
+	cmd.AddCommand(newSystemHealthCommand())
+	cmd.AddCommand(newSystemWatchCommand())
+	cmd.AddCommand(newSystemServeCommand())
+	cmd.AddCommand(newSystemRecordCommand())
+	cmd.AddCommand(newSystemReportCommand())
+
+	return cmd
+}
+
+func newSystemHealthCommand() *cobra.Command {
+	var (
+		interval   time.Duration
+		top        int
+		output     string
+		thresholds []string
+	)
+
+	cmd := &cobra.Command{
 		Use:   "health",
-		Short: "Display basic system health information",
+		Short: "Report CPU, memory, disks, network, temperatures and top processes",
+		Long: `Collect host metrics and compare them with warning/critical thresholds.
+
+CPU and per-process usage are sampled over --interval. Thresholds come from
+the health.thresholds config section and --threshold metric=warn:crit flags
+(metrics: cpu, memory, swap, disk, inodes, load per core, temperature).
+The exit code follows the Nagios convention: 0 OK, 1 WARNING, 2 CRITICAL.`,
+		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt, err := runtimeFrom(cmd)
 			if err != nil {
 				return err
 			}
-
-			memInfo, err := mem.VirtualMemory()
+			levels, err := healthThresholds(rt, thresholds)
 			if err != nil {
 				return err
 			}
 
-			loadAvg, err := load.Avg()
+			collector := sysinfo.Collector{Provider: healthProvider, Interval: interval, TopN: top}
+			report, err := collector.Collect(cmd.Context())
 			if err != nil {
-				return err
+				return &core.ExitError{Code: sysinfo.StatusUnknown.ExitCode(), Err: fmt.Errorf("collect metrics: %w", err)}
+			}
+			eval := levels.Evaluate(report)
+			for _, w := range report.Warnings {
+				rt.Logger.Debug().Msg(w)
 			}
 
-			diskInfo, err := disk.Usage("/")
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				err = enc.Encode(struct {
+					*sysinfo.Report
+					Health sysinfo.Evaluation `json:"health"`
+				}{report, eval})
+			case "table":
+				err = writeHealthReport(cmd.OutOrStdout(), report, eval)
+			default:
+				return fmt.Errorf("unknown output format %q (table|json)", output)
+			}
 			if err != nil {
 				return err
 			}
-
-			rt.Logger.Info().
-				Time("time", time.Now()).
-				Str("os", fmt.Sprintf("%s / %s", runtime.GOOS, runtime.GOARCH)).
-				Str("memory", fmt.Sprintf("%.2f%% used (%.2f GiB / %.2f GiB)", memInfo.UsedPercent, float64(memInfo.Used)/1_073_741_824, float64(memInfo.Total)/1_073_741_824)).
-				Str("load_average", fmt.Sprintf("%.2f %.2f %.2f", loadAvg.Load1, loadAvg.Load5, loadAvg.Load15)).
-				Str("disk", fmt.Sprintf("%.2f%% used (%.2f GiB / %.2f GiB)", diskInfo.UsedPercent, float64(diskInfo.Used)/1_073_741_824, float64(diskInfo.Total)/1_073_741_824)).
-				Msg("System health")
+			if eval.Status != sysinfo.StatusOK {
+				return &core.ExitError{Code: eval.Status.ExitCode()}
+			}
 			return nil
 		},
-	})
+	}
 
+	cmd.Flags().DurationVar(&interval, "interval", sysinfo.DefaultInterval, "CPU sampling interval")
+	cmd.Flags().IntVar(&top, "top", 5, "Number of top processes to show (0 disables)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	cmd.Flags().StringArrayVar(&thresholds, "threshold", nil, "Override a threshold as metric=warn:crit, repeatable")
 	return cmd
 }
+
+// healthThresholds layers config and flag overrides on the defaults.
+func healthThresholds(rt *core.Runtime, flags []string) (sysinfo.Thresholds, error) {
+	levels, err := sysinfo.DefaultThresholds().Merge(rt.Config.Health.Thresholds)
+	if err != nil {
+		return nil, fmt.Errorf("health.thresholds: %w", err)
+	}
+	for _, expr := range flags {
+		if err := levels.ParseThreshold(expr); err != nil {
+			return nil, err
+		}
+	}
+	return levels, nil
+}
+
+func writeHealthReport(out io.Writer, r *sysinfo.Report, eval sysinfo.Evaluation) error {
+	summary := make([]string, 0, len(eval.Findings))
+	for _, f := range eval.Findings {
+		summary = append(summary, f.String())
+	}
+	if len(summary) > 0 {
+		fmt.Fprintf(out, "HEALTH %s - %s\n\n", eval.Status, strings.Join(summary, "; "))
+	} else {
+		fmt.Fprintf(out, "HEALTH %s\n\n", eval.Status)
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	if r.Host.Hostname != "" {
+		uptime := (time.Duration(r.Host.Uptime) * time.Second).String()
+		fmt.Fprintf(tw, "host:\t%s (%s, %s), up %s\n", r.Host.Hostname, r.Host.Platform, r.Host.OS, uptime)
+	}
+	fmt.Fprintf(tw, "cpu:\t%.1f%% over %s, load %.2f %.2f %.2f, %d cores\n", r.CPU.Total, r.CPU.Sampled, r.CPU.Load1, r.CPU.Load5, r.CPU.Load15, r.CPU.Cores)
+	if len(r.CPU.PerCPU) > 0 {
+		per := make([]string, len(r.CPU.PerCPU))
+		for i, pct := range r.CPU.PerCPU {
+			per[i] = fmt.Sprintf("%.0f%%", pct)
+		}
+		fmt.Fprintf(tw, "per cpu:\t%s\n", strings.Join(per, " "))
+	}
+	fmt.Fprintf(tw, "memory:\t%.1f%% (%s / %s)\n", r.Memory.UsedPercent, ui.HumanBytes(int64(r.Memory.Used)), ui.HumanBytes(int64(r.Memory.Total)))
+	if r.Swap.Total > 0 {
+		fmt.Fprintf(tw, "swap:\t%.1f%% (%s / %s)\n", r.Swap.UsedPercent, ui.HumanBytes(int64(r.Swap.Used)), ui.HumanBytes(int64(r.Swap.Total)))
+	} else {
+		fmt.Fprintf(tw, "swap:\tnone\n")
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MOUNT\tDEVICE\tTYPE\tUSED\tSIZE\tUSE%\tINODE%")
+	for _, d := range r.Disks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.1f\t%.1f\n", d.Mountpoint, d.Device, d.Fstype, ui.HumanBytes(int64(d.Used)), ui.HumanBytes(int64(d.Total)), d.UsedPercent, d.InodesUsedPercent)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Network) > 0 {
+		fmt.Fprintln(out)
+		tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "INTERFACE\tRX\tTX\tERRORS\tDROPS")
+		for _, n := range r.Network {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\n", n.Name, ui.HumanBytes(int64(n.BytesRecv)), ui.HumanBytes(int64(n.BytesSent)), n.Errors, n.Drops)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.Temperatures) > 0 {
+		fmt.Fprintln(out)
+		tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SENSOR\tCELSIUS\tHIGH\tCRITICAL")
+		for _, t := range r.Temperatures {
+			fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%.1f\n", t.Sensor, t.Celsius, t.High, t.Critical)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	for _, list := range []struct {
+		title string
+		procs []sysinfo.ProcessStat
+	}{{"TOP CPU", r.TopCPU}, {"TOP MEMORY", r.TopMemory}} {
+		if len(list.procs) == 0 {
+			continue
+		}
+		fmt.Fprintln(out)
+		tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "%s\tPID\tCPU%%\tMEM%%\tRSS\n", list.title)
+		for _, p := range list.procs {
+			fmt.Fprintf(tw, "%s\t%d\t%.1f\t%.1f\t%s\n", p.Name, p.PID, p.CPUPercent, p.MemPercent, ui.HumanBytes(int64(p.RSS)))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
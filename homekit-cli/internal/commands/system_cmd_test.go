@@ -0,0 +1,138 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/sysinfo"
+)
+
+// fakeHealth reports an idle host with the given memory and root disk usage.
+type fakeHealth struct {
+	memory, disk float64
+	err          error
+}
+
+func (f fakeHealth) CPUTimes(context.Context, bool) ([]cpu.TimesStat, error) {
+	return []cpu.TimesStat{{CPU: "cpu0", Idle: 100}}, f.err
+}
+
+func (f fakeHealth) VirtualMemory(context.Context) (*mem.VirtualMemoryStat, error) {
+	return &mem.VirtualMemoryStat{Total: 1 << 30, UsedPercent: f.memory}, nil
+}
+
+func (f fakeHealth) SwapMemory(context.Context) (*mem.SwapMemoryStat, error) {
+	return &mem.SwapMemoryStat{}, nil
+}
+
+func (f fakeHealth) LoadAvg(context.Context) (*load.AvgStat, error) {
+	return &load.AvgStat{}, nil
+}
+
+func (f fakeHealth) Partitions(context.Context) ([]disk.PartitionStat, error) {
+	return []disk.PartitionStat{{Mountpoint: "/", Device: "/dev/sda1", Fstype: "ext4"}}, nil
+}
+
+func (f fakeHealth) DiskUsage(_ context.Context, path string) (*disk.UsageStat, error) {
+	return &disk.UsageStat{Path: path, Total: 1 << 30, UsedPercent: f.disk}, nil
+}
+
+func (f fakeHealth) NetIOCounters(context.Context) ([]net.IOCountersStat, error) { return nil, nil }
+
+func (f fakeHealth) DiskIOCounters(context.Context) (map[string]disk.IOCountersStat, error) {
+	return nil, nil
+}
+
+func (f fakeHealth) HostInfo(context.Context) (*host.InfoStat, error) {
+	return &host.InfoStat{Hostname: "fake"}, nil
+}
+
+func (f fakeHealth) Temperatures(context.Context) ([]host.TemperatureStat, error) { return nil, nil }
+
+func (f fakeHealth) Processes(context.Context) ([]sysinfo.ProcessSample, error) { return nil, nil }
+
+// runHealth runs `sys health` against provider and returns its output and
+// exit code.
+func runHealth(t *testing.T, provider sysinfo.Provider, args ...string) (string, int) {
+	t.Helper()
+	saved := healthProvider
+	healthProvider = provider
+	t.Cleanup(func() { healthProvider = saved })
+
+	rt := &core.Runtime{Logger: zerolog.Nop()}
+	cmd := NewSystemCommand()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	cmd.SetArgs(append([]string{"health", "--interval", "1ms"}, args...))
+	err := cmd.ExecuteContext(core.WithRuntime(context.Background(), rt))
+	if err == nil {
+		return out.String(), 0
+	}
+	var exitErr *core.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("sys health: %v", err)
+	}
+	return out.String(), exitErr.Code
+}
+
+func TestSystemHealthExitCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider fakeHealth
+		args     []string
+		code     int
+		header   string
+	}{
+		{"ok", fakeHealth{memory: 10, disk: 10}, nil, 0, "HEALTH OK"},
+		{"warning", fakeHealth{memory: 10, disk: 85}, nil, 1, "HEALTH WARNING - disk / 85.0 >= 80.0"},
+		{"critical", fakeHealth{memory: 96, disk: 85}, nil, 2, "HEALTH CRITICAL - memory 96.0 >= 95.0; disk / 85.0 >= 80.0"},
+		{"flag threshold", fakeHealth{memory: 10, disk: 10}, []string{"--threshold", "disk=5:8"}, 2, "HEALTH CRITICAL - disk / 10.0 >= 8.0"},
+		{"collect error", fakeHealth{err: errors.New("no /proc")}, nil, 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := runHealth(t, tt.provider, tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+			if tt.header != "" && !strings.HasPrefix(out, tt.header+"\n") {
+				t.Errorf("output starts with %q, want %q", strings.SplitN(out, "\n", 2)[0], tt.header)
+			}
+		})
+	}
+}
+
+func TestSystemHealthJSON(t *testing.T) {
+	out, code := runHealth(t, fakeHealth{memory: 10, disk: 91}, "-o", "json")
+	if code != 2 {
+		t.Errorf("exit code = %d, want 2", code)
+	}
+	var got struct {
+		Host   sysinfo.HostInfo `json:"host"`
+		Health struct {
+			Status   string            `json:"status"`
+			Findings []json.RawMessage `json:"findings"`
+		} `json:"health"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	if got.Host.Hostname != "fake" || got.Health.Status != "CRITICAL" || len(got.Health.Findings) != 1 {
+		t.Fatalf("unexpected report: %s", out)
+	}
+}
@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/sysinfo"
+	"github.com/homekit/homekit-cli/internal/ui"
+)
+
+func newSystemRecordCommand() *cobra.Command {
+	var (
+		interval     time.Duration
+		rawRetention string
+		retention    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "record",
+		Short: "Append one metrics sample to the local history",
+		Long: `Collect CPU, memory, swap, load and disk usage and append them to the
+history in the state directory. Run it periodically, e.g. from cron:
+
+  */5 * * * * homekit sys record
+
+Samples older than --raw-retention are folded into hourly means and hourly
+samples older than --retention are dropped.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			history, err := metricsHistory(rt)
+			if err != nil {
+				return err
+			}
+			if history.RawRetention, err = sysinfo.ParseDuration(rawRetention); err != nil {
+				return fmt.Errorf("--raw-retention: %w", err)
+			}
+			if history.Retention, err = sysinfo.ParseDuration(retention); err != nil {
+				return fmt.Errorf("--retention: %w", err)
+			}
+			if rt.DryRun {
+				rt.Logger.Info().Msgf("Would record a sample to %s", history.Dir())
+				return nil
+			}
+
+			collector := sysinfo.Collector{Provider: healthProvider, Interval: interval}
+			report, err := collector.Collect(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("collect metrics: %w", err)
+			}
+			if err := history.Append(sysinfo.SampleFrom(report)); err != nil {
+				return fmt.Errorf("record sample: %w", err)
+			}
+			if err := history.Compact(report.Time); err != nil {
+				return fmt.Errorf("compact history: %w", err)
+			}
+			rt.Logger.Debug().Msgf("Recorded sample to %s", history.Dir())
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", sysinfo.DefaultInterval, "CPU sampling interval")
+	cmd.Flags().StringVar(&rawRetention, "raw-retention", "48h", "Keep full-resolution samples this long (e.g. 48h, 3d)")
+	cmd.Flags().StringVar(&retention, "retention", "90d", "Keep hourly samples this long")
+	return cmd
+}
+
+func newSystemReportCommand() *cobra.Command {
+	var (
+		since  string
+		output string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Summarise recorded metrics and project disk usage",
+		Long: `Summarise the samples written by "sys record": min, average, max and 95th
+percentile of each metric, plus per-filesystem growth and the date each disk
+fills up, projected with a linear regression over the period.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			period, err := sysinfo.ParseDuration(since)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+			history, err := metricsHistory(rt)
+			if err != nil {
+				return err
+			}
+			samples, err := history.Load(time.Now().Add(-period))
+			if err != nil {
+				return err
+			}
+			if len(samples) == 0 {
+				return fmt.Errorf("no samples recorded in the last %s; run `homekit sys record` periodically", since)
+			}
+			summary := sysinfo.Summarize(samples)
+
+			switch output {
+			case "json":
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(summary)
+			case "table":
+				return writeMetricsSummary(cmd.OutOrStdout(), summary)
+			default:
+				return fmt.Errorf("unknown output format %q (table|json)", output)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "7d", "Period to summarise (e.g. 24h, 7d, 2w)")
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	return cmd
+}
+
+func metricsHistory(rt *core.Runtime) (*sysinfo.History, error) {
+	stateDir, err := rt.Config.StateDirectory()
+	if err != nil {
+		return nil, err
+	}
+	return sysinfo.NewHistory(stateDir), nil
+}
+
+func writeMetricsSummary(out io.Writer, s sysinfo.Summary) error {
+	const stamp = "2006-01-02 15:04"
+	fmt.Fprintf(out, "%d samples from %s to %s\n\n", s.Samples, s.From.Format(stamp), s.To.Format(stamp))
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METRIC\tMIN\tAVG\tMAX\tP95")
+	for _, m := range s.Metrics {
+		format := "%s\t%.1f\t%.1f\t%.1f\t%.1f\n"
+		if m.Metric == sysinfo.MetricLoad {
+			format = "%s\t%.2f\t%.2f\t%.2f\t%.2f\n"
+		}
+		fmt.Fprintf(tw, format, m.Metric, m.Min, m.Avg, m.Max, m.P95)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(s.Disks) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "MOUNT\tUSED\tSIZE\tUSE%\tGROWTH/DAY\tFULL BY")
+	for _, d := range s.Disks {
+		full := "never"
+		switch {
+		case d.FullAt != nil:
+			full = fmt.Sprintf("%s (%d days)", d.FullAt.Format("2006-01-02"), int(time.Until(*d.FullAt).Hours()/24))
+		case d.GrowthPerDay == 0:
+			full = "-"
+		}
+		growth := ui.HumanBytes(int64(d.GrowthPerDay))
+		if d.GrowthPerDay > 0 {
+			growth = "+" + growth
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f\t%s\t%s\n", d.Mountpoint, ui.HumanBytes(int64(d.Used)), ui.HumanBytes(int64(d.Total)), d.UsedPercent, growth, full)
+	}
+	return tw.Flush()
+}
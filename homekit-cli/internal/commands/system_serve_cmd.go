@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/sysinfo"
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+)
+
+func newSystemServeCommand() *cobra.Command {
+	var (
+		listen     string
+		textfile   string
+		interval   time.Duration
+		cacheTTL   time.Duration
+		thresholds []string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Expose system metrics for Prometheus",
+		Long: `Serve the metrics collected by "sys health" over HTTP:
+
+  /metrics  OpenMetrics text format
+  /healthz  threshold status as JSON (503 when CRITICAL or collection fails)
+
+Collections are cached for --cache-ttl so every scrape does not re-read the
+host. With --textfile the metrics are written once, in Prometheus text format,
+to a file for node_exporter's textfile collector (run it from cron).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			levels, err := healthThresholds(rt, thresholds)
+			if err != nil {
+				return err
+			}
+			collector := sysinfo.Collector{Provider: healthProvider, Interval: interval}
+
+			if textfile != "" {
+				if rt.DryRun {
+					rt.Logger.Info().Msgf("Would write metrics to %s", textfile)
+					return nil
+				}
+				report, err := collector.Collect(cmd.Context())
+				if err != nil {
+					return fmt.Errorf("collect metrics: %w", err)
+				}
+				var buf bytes.Buffer
+				if err := sysinfo.WriteMetrics(&buf, report, levels.Evaluate(report), sysinfo.FormatPrometheus); err != nil {
+					return err
+				}
+				if err := fileutil.WriteAtomic(textfile, buf.Bytes(), 0o644); err != nil {
+					return fmt.Errorf("write %s: %w", textfile, err)
+				}
+				rt.Logger.Info().Msgf("Wrote metrics to %s", textfile)
+				return nil
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			ln, err := net.Listen("tcp", listen)
+			if err != nil {
+				return fmt.Errorf("listen on %s: %w", listen, err)
+			}
+			cache := &sysinfo.Cache{Collector: collector, TTL: cacheTTL}
+			srv := &http.Server{
+				Handler:           newMetricsHandler(cache, levels, rt.Logger),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			rt.Logger.Info().Msgf("Serving metrics on http://%s/metrics", ln.Addr())
+
+			errc := make(chan error, 1)
+			go func() { errc <- srv.Serve(ln) }()
+			select {
+			case err := <-errc:
+				return err
+			case <-ctx.Done():
+			}
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				return fmt.Errorf("shutdown metrics server: %w", err)
+			}
+			if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&listen, "listen", ":9101", "Address to serve /metrics and /healthz on")
+	cmd.Flags().StringVar(&textfile, "textfile", "", "Write metrics to this file once and exit instead of serving")
+	cmd.Flags().DurationVar(&interval, "interval", sysinfo.DefaultInterval, "CPU sampling interval")
+	cmd.Flags().DurationVar(&cacheTTL, "cache-ttl", 15*time.Second, "How long a collection is reused across requests")
+	cmd.Flags().StringArrayVar(&thresholds, "threshold", nil, "Override a threshold as metric=warn:crit, repeatable")
+	return cmd
+}
+
+// newMetricsHandler serves /metrics and /healthz from the cache.
+func newMetricsHandler(cache *sysinfo.Cache, levels sysinfo.Thresholds, logger zerolog.Logger) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		report, err := cache.Get(r.Context())
+		if err != nil {
+			logger.Warn().Err(err).Msg("collect metrics")
+			http.Error(w, "collect metrics: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		var buf bytes.Buffer
+		if err := sysinfo.WriteMetrics(&buf, report, levels.Evaluate(report), sysinfo.FormatOpenMetrics); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", sysinfo.FormatOpenMetrics.ContentType())
+		_, _ = w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			sysinfo.Evaluation
+			Time  time.Time `json:"time,omitempty"`
+			Error string    `json:"error,omitempty"`
+		}{}
+		code := http.StatusOK
+		if report, err := cache.Get(r.Context()); err != nil {
+			body.Status, body.Error = sysinfo.StatusUnknown, err.Error()
+			code = http.StatusServiceUnavailable
+		} else {
+			body.Evaluation, body.Time = levels.Evaluate(report), report.Time
+			if body.Status >= sysinfo.StatusCritical {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(body)
+	})
+	return mux
+}
@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/homekit/homekit-cli/internal/sysinfo"
+	"github.com/homekit/homekit-cli/internal/ui"
+)
+
+// ANSI sequences used to redraw the watch view in place.
+const (
+	ansiClearHome  = "\x1b[H\x1b[2J"
+	ansiHideCursor = "\x1b[?25l"
+	ansiShowCursor = "\x1b[?25h"
+)
+
+func newSystemWatchCommand() *cobra.Command {
+	var (
+		interval time.Duration
+		history  int
+		top      int
+		once     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Show a live dashboard of CPU, memory, disk I/O and network",
+		Long: `Redraw a compact view of host metrics every --interval, with sparklines
+of recent CPU, memory, disk I/O and network activity.
+
+When stdout is not a terminal, or with --once, a single frame is printed
+without escape sequences. Press Ctrl-C to exit.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			if history < 1 {
+				return fmt.Errorf("--history must be at least 1")
+			}
+			levels, err := healthThresholds(rt, nil)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			out := cmd.OutOrStdout()
+			live := !once && stdoutIsTerminal(cmd)
+			if live {
+				fmt.Fprint(out, ansiHideCursor)
+				defer fmt.Fprint(out, ansiShowCursor)
+			}
+
+			collector := sysinfo.Collector{Provider: healthProvider, Interval: interval, TopN: top}
+			hist := newWatchHistory(history)
+			for {
+				report, err := collector.Collect(ctx)
+				if err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return fmt.Errorf("collect metrics: %w", err)
+				}
+				hist.add(report)
+
+				var frame bytes.Buffer
+				if err := writeWatchFrame(&frame, report, levels.Evaluate(report), hist); err != nil {
+					return err
+				}
+				if live {
+					fmt.Fprint(out, ansiClearHome)
+				}
+				if _, err := out.Write(frame.Bytes()); err != nil {
+					return err
+				}
+				if !live {
+					return nil
+				}
+			}
+		},
+	}
+
+	cmd.Flags().DurationVar(&interval, "interval", 2*time.Second, "Refresh and sampling interval")
+	cmd.Flags().IntVar(&history, "history", 40, "Number of samples kept for the sparklines")
+	cmd.Flags().IntVar(&top, "top", 3, "Number of top CPU processes to show (0 disables)")
+	cmd.Flags().BoolVar(&once, "once", false, "Print a single frame and exit")
+	return cmd
+}
+
+// watchHistory keeps the most recent samples behind each sparkline.
+type watchHistory struct {
+	size                int
+	cpu, mem, disk, net []float64
+}
+
+func newWatchHistory(size int) *watchHistory {
+	return &watchHistory{size: size}
+}
+
+func (h *watchHistory) add(r *sysinfo.Report) {
+	read, write := r.DiskIORate()
+	recv, sent := r.NetworkRate()
+	h.cpu = h.push(h.cpu, r.CPU.Total)
+	h.mem = h.push(h.mem, r.Memory.UsedPercent)
+	h.disk = h.push(h.disk, read+write)
+	h.net = h.push(h.net, recv+sent)
+}
+
+func (h *watchHistory) push(series []float64, v float64) []float64 {
+	series = append(series, v)
+	if len(series) > h.size {
+		series = series[len(series)-h.size:]
+	}
+	return series
+}
+
+func writeWatchFrame(out io.Writer, r *sysinfo.Report, eval sysinfo.Evaluation, h *watchHistory) error {
+	host := r.Host.Hostname
+	if host == "" {
+		host = "localhost"
+	}
+	fmt.Fprintf(out, "%s  %s  every %s  HEALTH %s\n\n", host, r.Time.Format("15:04:05"), r.CPU.Sampled, eval.Status)
+
+	read, write := r.DiskIORate()
+	recv, sent := r.NetworkRate()
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "cpu\t%5.1f%%\tload %.2f %.2f %.2f\t%s\n", r.CPU.Total, r.CPU.Load1, r.CPU.Load5, r.CPU.Load15, ui.Sparkline(h.cpu, 100))
+	fmt.Fprintf(tw, "mem\t%5.1f%%\t%s / %s\t%s\n", r.Memory.UsedPercent, ui.HumanBytes(int64(r.Memory.Used)), ui.HumanBytes(int64(r.Memory.Total)), ui.Sparkline(h.mem, 100))
+	fmt.Fprintf(tw, "disk\t\tread %s/s write %s/s\t%s\n", ui.HumanBytes(int64(read)), ui.HumanBytes(int64(write)), ui.Sparkline(h.disk, 0))
+	fmt.Fprintf(tw, "net\t\trx %s/s tx %s/s\t%s\n", ui.HumanBytes(int64(recv)), ui.HumanBytes(int64(sent)), ui.Sparkline(h.net, 0))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(r.Disks) > 0 {
+		fmt.Fprintln(out)
+		tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MOUNT\tUSE%\tUSED\tSIZE")
+		for _, d := range r.Disks {
+			fmt.Fprintf(tw, "%s\t%.1f\t%s\t%s\n", d.Mountpoint, d.UsedPercent, ui.HumanBytes(int64(d.Used)), ui.HumanBytes(int64(d.Total)))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(r.TopCPU) > 0 {
+		fmt.Fprintln(out)
+		tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "PROCESS\tPID\tCPU%\tRSS")
+		for _, p := range r.TopCPU {
+			fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\n", p.Name, p.PID, p.CPUPercent, ui.HumanBytes(int64(p.RSS)))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	for _, f := range eval.Findings {
+		fmt.Fprintf(out, "%s: %s\n", f.Status, f)
+	}
+	return nil
+}
+
+// stdoutIsTerminal reports whether the command writes to an interactive terminal.
+func stdoutIsTerminal(cmd *cobra.Command) bool {
+	f, ok := cmd.OutOrStdout().(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
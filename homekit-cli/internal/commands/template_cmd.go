@@ -1,90 +1,483 @@
 package commands
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"text/template"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 
 	"github.com/homekit/homekit-cli/internal/assets"
+	"github.com/homekit/homekit-cli/internal/compose"
+	"github.com/homekit/homekit-cli/internal/core"
+	executor "github.com/homekit/homekit-cli/internal/exec"
 	"github.com/homekit/homekit-cli/internal/templating"
+	"github.com/homekit/homekit-cli/internal/util/diffutil"
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+	"github.com/homekit/homekit-cli/internal/util/pathformat"
 )
 
 // NewTemplateCommand exposes templating workflows.
 func NewTemplateCommand() *cobra.Command {
-	var dataFiles []string
-	var output string
-
 	cmd := &cobra.Command{
 		Use:   "template",
 		Short: "Render templates from embedded assets or local files",
 	}
 
-	renderCmd := &cobra.Command{
-		Use:   "render <template>",
+	cmd.AddCommand(newTemplateRenderCommand(), newTemplateRenderDirCommand(), newTemplateFuncsCommand())
+	return cmd
+}
+
+// templateDataOptions holds the data and rendering flags shared by render commands.
+type templateDataOptions struct {
+	dataFiles  []string
+	setValues  []string
+	setStrings []string
+	dataEnv    string
+	strict     bool
+}
+
+func (o *templateDataOptions) bind(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVarP(&o.dataFiles, "data", "d", nil, "YAML, JSON or TOML data files to deep-merge in order (- reads stdin)")
+	cmd.Flags().StringArrayVar(&o.setValues, "set", nil, "Set values on the command line (a.b[0].c=v, comma-separated, types inferred)")
+	cmd.Flags().StringArrayVar(&o.setStrings, "set-string", nil, "Set STRING values on the command line (no type inference)")
+	cmd.Flags().StringVar(&o.dataEnv, "data-env", "", "Lift environment variables with this prefix into data (PREFIX_A__B=v sets .A.B)")
+	cmd.Flags().BoolVar(&o.strict, "strict", false, "Fail on missing keys instead of rendering <no value>")
+}
+
+func (o *templateDataOptions) load(cmd *cobra.Command) (map[string]any, error) {
+	return templating.LoadData(templating.DataSources{
+		Files:     o.dataFiles,
+		EnvPrefix: o.dataEnv,
+		Set:       o.setValues,
+		SetString: o.setStrings,
+		Stdin:     cmd.InOrStdin(),
+	})
+}
+
+func newTemplateRenderCommand() *cobra.Command {
+	var dataOpts templateDataOptions
+	var output, schemaFile, from string
+	var outOpts templateOutputOptions
+	var noLint bool
+
+	c := &cobra.Command{
+		Use:   "render <template|path|->",
 		Args:  cobra.ExactArgs(1),
-		Short: "Render an embedded template to stdout or a file",
+		Short: "Render a template to stdout or a file",
+		Long: `Render a template from the templates namespace, a local file or stdin (-).
+
+Without --from, paths starting with ./, ../ or / are read locally, other names
+resolve through asset overrides and then embedded assets, falling back to a
+local file of the same name.
+
+When the output or the template is named like a compose file (compose.yaml,
+docker-compose.yml.tmpl, ...) the result is checked as by "compose lint"
+first: warnings are logged and errors stop it from being written.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			rt, err := runtimeFrom(cmd)
 			if err != nil {
 				return err
 			}
 
+			ref := args[0]
+			if ref == templating.StdinData && slices.Contains(dataOpts.dataFiles, templating.StdinData) {
+				return errors.New("stdin cannot provide both the template and --data")
+			}
+
 			manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
 
-			data, err := aggregateTemplateData(dataFiles)
+			data, err := dataOpts.load(cmd)
 			if err != nil {
 				return err
 			}
 
-			renderer := templating.Renderer{}
-			var out *os.File
-			if output != "" {
-				dest, err := os.Create(output)
+			renderer := newTemplateRenderer(cmd.Context(), rt)
+			renderer.Strict = dataOpts.strict
+			var explicit *templating.Schema
+			if schemaFile != "" {
+				content, err := os.ReadFile(schemaFile)
+				if err != nil {
+					return fmt.Errorf("read schema %s: %w", schemaFile, err)
+				}
+				if explicit, err = templating.ParseSchema(content); err != nil {
+					return fmt.Errorf("%s: %w", schemaFile, err)
+				}
+			}
+
+			var rendered bytes.Buffer
+			if ref == templating.StdinData {
+				content, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return fmt.Errorf("read template from stdin: %w", err)
+				}
+				err = renderer.RenderDocument("stdin", content, data, explicit, &rendered)
 				if err != nil {
 					return err
 				}
-				defer dest.Close()
-				out = dest
 			} else {
-				out = os.Stdout
+				vfs, name, err := resolveTemplateFile(manager, ref, from)
+				if err != nil {
+					return err
+				}
+				if explicit != nil {
+					var content []byte
+					if content, err = fs.ReadFile(vfs, name); err != nil {
+						return err
+					}
+					err = renderer.RenderDocument(name, content, data, explicit, &rendered)
+				} else {
+					err = renderer.RenderFile(vfs, name, data, &rendered)
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			if !noLint && (compose.IsComposeFile(output) || compose.IsComposeFile(ref)) {
+				name, dir := ref, "."
+				if output != "" {
+					name, dir = output, filepath.Dir(output)
+				}
+				if err := lintRenderedCompose(rt, name, rendered.Bytes(), dir); err != nil {
+					return err
+				}
+			}
+
+			if output == "" {
+				if outOpts.diff || outOpts.check || outOpts.backup {
+					return errors.New("--diff, --check and --backup require --output")
+				}
+				_, err := cmd.OutOrStdout().Write(rendered.Bytes())
+				return err
 			}
+			return writeTemplateOutput(cmd, rt, output, rendered.Bytes(), outOpts)
+		},
+	}
+
+	dataOpts.bind(c)
+	c.Flags().StringVarP(&output, "output", "o", "", "Destination file (default stdout)")
+	c.Flags().StringVar(&schemaFile, "schema", "", "JSON/YAML schema to validate data against (default <template>.schema.json next to the template)")
+	c.Flags().StringVar(&from, "from", "", "Template source (embedded|override|local; default auto-detect)")
+	c.Flags().BoolVar(&outOpts.diff, "diff", false, "Show a unified diff between --output and the rendered result")
+	c.Flags().BoolVar(&outOpts.backup, "backup", false, "Keep the previous --output as <file>.bak before replacing it")
+	c.Flags().BoolVar(&outOpts.check, "check", false, "Do not write; exit non-zero when --output differs from the rendered result")
+	c.Flags().BoolVar(&noLint, "no-lint", false, "Do not lint rendered compose files")
+	return c
+}
 
-			handle, err := manager.Open("templates", args[0])
+func newTemplateRenderDirCommand() *cobra.Command {
+	var dataOpts templateDataOptions
+	var from string
+	var noLint bool
+
+	c := &cobra.Command{
+		Use:   "render-dir <src> <dest>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Render a template directory tree into an output directory",
+		Long: `Render every file below <src> into <dest>. <src> is a local directory or a
+directory in the templates namespace (overrides shadow embedded files).
+
+Path names are rendered too ({{ .Name }}/compose.yml), a trailing .tmpl is
+dropped, files starting with _ are partials whose define blocks are shared,
+.tmplignore excludes files, and schema.json validates the data. Files are
+written atomically and unchanged files are left untouched. Rendered compose
+files are linted before anything is written, as by "template render".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
 			if err != nil {
 				return err
 			}
-			defer handle.Close()
 
-			if err := renderer.Render(handle, data, out); err != nil {
+			data, err := dataOpts.load(cmd)
+			if err != nil {
 				return err
 			}
-			return nil
+
+			manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
+			src, err := templateDirSource(manager, args[0], from)
+			if err != nil {
+				return err
+			}
+
+			renderer := newTemplateRenderer(cmd.Context(), rt)
+			renderer.Strict = dataOpts.strict
+			files, err := renderer.RenderDir(src, data)
+			if err != nil {
+				return err
+			}
+			if !noLint {
+				for _, file := range files {
+					if !compose.IsComposeFile(file.Path) {
+						continue
+					}
+					target := filepath.Join(args[1], filepath.FromSlash(file.Path))
+					if err := lintRenderedCompose(rt, target, file.Content, filepath.Dir(target)); err != nil {
+						return err
+					}
+				}
+			}
+			return writeRenderedFiles(cmd, rt, files, args[1])
 		},
 	}
 
-	renderCmd.Flags().StringSliceVarP(&dataFiles, "data", "d", nil, "YAML data files to merge")
-	renderCmd.Flags().StringVarP(&output, "output", "o", "", "Destination file (default stdout)")
+	dataOpts.bind(c)
+	c.Flags().StringVar(&from, "from", "", "Template source (embedded|override|local; default local directory, then assets)")
+	c.Flags().BoolVar(&noLint, "no-lint", false, "Do not lint rendered compose files")
+	return c
+}
 
-	cmd.AddCommand(renderCmd)
-	return cmd
+func newTemplateFuncsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "funcs",
+		Args:  cobra.NoArgs,
+		Short: "List functions available to templates",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			return listTemplateFuncs(cmd, rt.Config)
+		},
+	}
+}
+
+// templateOutputOptions controls how `template render` updates --output.
+type templateOutputOptions struct {
+	diff   bool
+	backup bool
+	check  bool
+}
+
+// errTemplateDrift is returned by --check when the output file is out of date.
+var errTemplateDrift = errors.New("rendered output differs from file on disk")
+
+// writeTemplateOutput atomically replaces path with rendered content when it
+// changed, optionally showing a diff, keeping a backup, or only checking.
+func writeTemplateOutput(cmd *cobra.Command, rt *core.Runtime, path string, rendered []byte, opts templateOutputOptions) error {
+	status, current, err := fileutil.Compare(path, rendered)
+	if err != nil {
+		return err
+	}
+
+	if opts.diff && status != fileutil.StatusUnchanged {
+		oldName := path
+		if status == fileutil.StatusCreated {
+			oldName = os.DevNull
+		}
+		fmt.Fprint(cmd.OutOrStdout(), diffutil.Unified(oldName, path, string(current), string(rendered), diffutil.DefaultContext))
+	}
+
+	if opts.check {
+		if status != fileutil.StatusUnchanged {
+			return fmt.Errorf("%s: %w", path, errTemplateDrift)
+		}
+		rt.Logger.Info().Msgf("%s is up to date", path)
+		return nil
+	}
+
+	if status == fileutil.StatusUnchanged || rt.DryRun {
+		rt.Logger.Info().Msgf("%s %s", path, dryRunStatus(status, rt.DryRun))
+		return nil
+	}
+
+	if opts.backup && status == fileutil.StatusChanged {
+		backup, err := fileutil.Backup(path)
+		if err != nil {
+			return fmt.Errorf("backup %s: %w", path, err)
+		}
+		rt.Logger.Info().Msgf("Previous content saved to %s", backup)
+	}
+	if err := fileutil.WriteAtomic(path, rendered, fileutil.ModeOr(path, 0o644)); err != nil {
+		return err
+	}
+	rt.Logger.Info().Msgf("%s %s", path, status)
+	return nil
+}
+
+func dryRunStatus(status fileutil.Status, dryRun bool) string {
+	if dryRun && status != fileutil.StatusUnchanged {
+		return string(status) + " (dry run)"
+	}
+	return string(status)
+}
+
+// Template sources accepted by --from.
+const (
+	templateFromEmbedded = "embedded"
+	templateFromOverride = "override"
+	templateFromLocal    = "local"
+)
+
+// templateAssets narrows the manager to the layers selected by --from.
+func templateAssets(manager *assets.Manager, from string) (*assets.Manager, error) {
+	switch from {
+	case "":
+		return manager, nil
+	case templateFromEmbedded:
+		return manager.EmbeddedOnly(), nil
+	case templateFromOverride:
+		return manager.OverridesOnly(), nil
+	}
+	return nil, fmt.Errorf("invalid --from %q: choose embedded, override or local", from)
+}
+
+// resolveTemplateFile returns the filesystem holding the template and its name
+// within it.
+func resolveTemplateFile(manager *assets.Manager, ref, from string) (fs.FS, string, error) {
+	if from == templateFromLocal || (from == "" && isLocalTemplateRef(ref)) {
+		return localTemplateFile(ref)
+	}
+
+	selected, err := templateAssets(manager, from)
+	if err != nil {
+		return nil, "", err
+	}
+	vfs, err := selected.FS(assets.AssetNamespaceTemplates)
+	if err != nil {
+		return nil, "", err
+	}
+	_, statErr := fs.Stat(vfs, ref)
+	if statErr == nil {
+		return vfs, ref, nil
+	}
+	if from == "" && errors.Is(statErr, fs.ErrNotExist) {
+		if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+			return localTemplateFile(ref)
+		}
+	}
+	if errors.Is(statErr, fs.ErrNotExist) {
+		statErr = fmt.Errorf("%w: %s/%s", assets.ErrAssetNotFound, assets.AssetNamespaceTemplates, ref)
+	}
+	return nil, "", describeAssetError(statErr, assets.AssetNamespaceTemplates, ref)
+}
+
+func localTemplateFile(ref string) (fs.FS, string, error) {
+	full := pathformat.RenderFullPath(ref)
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, "", fmt.Errorf("local template: %w", err)
+	}
+	if info.IsDir() {
+		return nil, "", fmt.Errorf("local template %s is a directory (use render-dir)", ref)
+	}
+	return os.DirFS(filepath.Dir(full)), filepath.Base(full), nil
 }
 
-func aggregateTemplateData(paths []string) (map[string]any, error) {
-	out := map[string]any{}
-	for _, path := range paths {
-		file, err := os.ReadFile(path)
+func isLocalTemplateRef(ref string) bool {
+	return filepath.IsAbs(ref) ||
+		strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") ||
+		strings.HasPrefix(ref, "."+string(filepath.Separator)) || strings.HasPrefix(ref, ".."+string(filepath.Separator))
+}
+
+// templateDirSource resolves a template directory. Without --from a local
+// directory wins over a directory in the templates namespace.
+func templateDirSource(manager *assets.Manager, src, from string) (fs.FS, error) {
+	if from == templateFromLocal || from == "" {
+		if info, err := os.Stat(src); err == nil && info.IsDir() {
+			return os.DirFS(src), nil
+		} else if from == templateFromLocal {
+			return nil, fmt.Errorf("template directory %s: not a local directory", src)
+		}
+	}
+	selected, err := templateAssets(manager, from)
+	if err != nil {
+		return nil, err
+	}
+	dir := strings.Trim(src, "/")
+	if dir == "" {
+		dir = "."
+	}
+	vfs, err := selected.Sub(assets.AssetNamespaceTemplates, dir)
+	if err != nil {
+		return nil, fmt.Errorf("template directory %s: %w", src, err)
+	}
+	return vfs, nil
+}
+
+// writeRenderedFiles writes files below dest and prints a per-file status with
+// a summary line. Dry runs report what would change.
+func writeRenderedFiles(cmd *cobra.Command, rt *core.Runtime, files []templating.RenderedFile, dest string) error {
+	counts := map[fileutil.Status]int{}
+	out := cmd.OutOrStdout()
+	for _, file := range files {
+		target := filepath.Join(dest, filepath.FromSlash(file.Path))
+		status, err := fileutil.WriteIfChanged(target, file.Content, file.Mode, rt.DryRun)
 		if err != nil {
-			return nil, fmt.Errorf("read data file %s: %w", path, err)
+			return fmt.Errorf("write %s: %w", target, err)
+		}
+		counts[status]++
+		fmt.Fprintf(out, "%-9s %s\n", status, filepath.ToSlash(target))
+	}
+	summary := fmt.Sprintf("%d created, %d changed, %d unchanged",
+		counts[fileutil.StatusCreated], counts[fileutil.StatusChanged], counts[fileutil.StatusUnchanged])
+	if rt.DryRun {
+		summary += " (dry run)"
+	}
+	fmt.Fprintln(out, summary)
+	return nil
+}
+
+// newTemplateRenderer returns a renderer with the built-in function library
+// extended by the functions declared under template_funcs in the config.
+func newTemplateRenderer(ctx context.Context, rt *core.Runtime) templating.Renderer {
+	funcs := template.FuncMap{}
+	for name, command := range rt.Config.TemplateFuncs {
+		funcs[name] = commandTemplateFunc(ctx, name, command)
+	}
+	return templating.Renderer{Funcs: funcs}
+}
+
+// commandTemplateFunc wraps an external command as a template function. Template
+// arguments are appended to the configured command line.
+func commandTemplateFunc(ctx context.Context, name, command string) func(args ...any) (string, error) {
+	return func(args ...any) (string, error) {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			return "", fmt.Errorf("template func %s: empty command", name)
+		}
+		cmdArgs := append([]string{}, fields[1:]...)
+		for _, arg := range args {
+			cmdArgs = append(cmdArgs, fmt.Sprint(arg))
 		}
-		doc := map[string]any{}
-		if err := yaml.Unmarshal(file, &doc); err != nil {
-			return nil, fmt.Errorf("parse data file %s: %w", path, err)
+		res, err := executor.Run(ctx, executor.Spec{
+			Command:       fields[0],
+			Args:          cmdArgs,
+			CaptureOutput: true,
+		})
+		if err != nil {
+			return "", fmt.Errorf("template func %s: %w: %s", name, err, strings.TrimSpace(res.Stderr))
 		}
-		for k, v := range doc {
-			out[k] = v
+		return strings.TrimSpace(res.Stdout), nil
+	}
+}
+
+func listTemplateFuncs(cmd *cobra.Command, cfg core.Config) error {
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSOURCE\tUSAGE")
+	for _, info := range templating.Funcs() {
+		if _, overridden := cfg.TemplateFuncs[info.Name]; overridden {
+			continue
 		}
+		fmt.Fprintf(tw, "%s\tbuiltin\t%s\n", info.Name, info.Usage)
+	}
+	names := make([]string, 0, len(cfg.TemplateFuncs))
+	for name := range cfg.TemplateFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\tconfig\t%s ARGS...\n", name, cfg.TemplateFuncs[name])
 	}
-	return out, nil
+	return tw.Flush()
 }
@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/homekit/homekit-cli/internal/core"
+)
+
+// runTemplate runs `template` with stdin as input and returns stdout and the
+// log output.
+func runTemplate(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	return runTemplateWith(t, core.Config{}, stdin, args...)
+}
+
+// runTemplateWith is runTemplate with a base configuration.
+func runTemplateWith(t *testing.T, cfg core.Config, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	var logs bytes.Buffer
+	rt := &core.Runtime{Logger: zerolog.New(&logs), Config: cfg}
+	rt.Config.StateDir = t.TempDir()
+	cmd := NewTemplateCommand()
+	var out bytes.Buffer
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(core.WithRuntime(context.Background(), rt))
+	return out.String(), logs.String(), err
+}
+
+func TestTemplateRenderLintsCompose(t *testing.T) {
+	const (
+		clean      = "services:\n  web:\n    image: web:{{ .tag }}\n    restart: always\n    healthcheck: {disable: true}\n"
+		warnings   = "services:\n  web:\n    image: web:{{ .tag }}\n"
+		broken     = "services:\n  web:\n    image: web:1\n    privileged: true\n    restart: always\n    healthcheck: {disable: true}\n"
+		dotenvOnly = "services:\n  web:\n    image: web:${TAG:?set TAG}\n    restart: always\n    healthcheck: {disable: true}\n"
+	)
+	tests := []struct {
+		name     string
+		template string
+		output   string
+		args     []string
+		dotenv   string
+		err      string
+		written  bool
+		logged   string
+	}{
+		{name: "clean", template: clean, output: "compose.yaml", written: true},
+		{name: "warnings are logged", template: warnings, output: "compose.yaml", written: true, logged: "[restart]"},
+		{name: "errors stop the write", template: broken, output: "compose.yaml", err: "compose lint failed", logged: "[privileged]"},
+		{name: "no-lint", template: broken, output: "compose.yaml", args: []string{"--no-lint"}, written: true},
+		{name: "not a compose file", template: broken, output: "values.yaml", written: true},
+		{name: "dotenv of the output directory", template: dotenvOnly, output: "compose.dev.yml", dotenv: "TAG=2\n", written: true},
+		{name: "required variable", template: dotenvOnly, output: "compose.dev.yml", err: "TAG: set TAG"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.dotenv != "" {
+				if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(tt.dotenv), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			output := filepath.Join(dir, tt.output)
+			args := append([]string{"render", "-", "--set", "tag=1", "-o", output}, tt.args...)
+			_, logs, err := runTemplate(t, tt.template, args...)
+			if tt.err == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("error = %v, want %q", err, tt.err)
+			}
+			if _, statErr := os.Stat(output); (statErr == nil) != tt.written {
+				t.Fatalf("output written = %v, want %v", statErr == nil, tt.written)
+			}
+			if tt.logged != "" && !strings.Contains(logs, tt.logged) {
+				t.Fatalf("logs %q do not mention %s", logs, tt.logged)
+			}
+		})
+	}
+}
+
+func TestTemplateRenderLintsComposeTemplateName(t *testing.T) {
+	tmpl := filepath.Join(t.TempDir(), "docker-compose.yml.tmpl")
+	if err := os.WriteFile(tmpl, []byte("services:\n  web:\n    image: web:1\n    privileged: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := runTemplate(t, "", "render", tmpl); !errors.Is(err, errComposeLint) {
+		t.Fatalf("error = %v, want errComposeLint", err)
+	}
+	out, _, err := runTemplate(t, "", "render", tmpl, "--no-lint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "privileged: true") {
+		t.Fatalf("stdout %q", out)
+	}
+}
+
+func TestTemplateRenderSources(t *testing.T) {
+	dir, overrides := t.TempDir(), t.TempDir()
+	for name, content := range map[string]string{
+		filepath.Join(overrides, "templates", "greeting.tmpl"):           "override {{ .name }}\n",
+		filepath.Join(overrides, "templates", "docker-compose.yaml.tmpl"): "override compose\n",
+		filepath.Join(dir, "greeting.tmpl"):                               "local {{ .name }}\n",
+		filepath.Join(dir, "greeting.schema.json"):                        `{"required": ["name"]}`,
+		filepath.Join(dir, "plain.tmpl"):                                  "plain {{ .name }}\n",
+		filepath.Join(dir, "schema.yaml"):                                 "properties:\n  name: {type: string, minLength: 3}\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chdir(t, dir)
+	cfg := core.Config{AssetOverrides: overrides}
+
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  string
+		err   string
+	}{
+		{name: "override before embedded", args: []string{"greeting.tmpl", "--set", "name=a"}, want: "override a\n"},
+		{name: "local path", args: []string{"./greeting.tmpl", "--set", "name=a"}, want: "local a\n"},
+		{name: "from local", args: []string{"greeting.tmpl", "--from", "local", "--set", "name=a"}, want: "local a\n"},
+		{name: "local sidecar schema", args: []string{"./greeting.tmpl"}, err: ".name: is required"},
+		{name: "falls back to a local file", args: []string{"plain.tmpl", "--set", "name=a"}, want: "plain a\n"},
+		{name: "explicit schema", args: []string{"plain.tmpl", "--schema", "schema.yaml", "--set", "name=a"}, err: ".name: must be at least 3 characters"},
+		{name: "from embedded", args: []string{"docker-compose.yaml.tmpl", "--from", "embedded", "--set", "Profile=dev"}, want: `homekit.profile: "dev"`},
+		{name: "embedded sidecar schema", args: []string{"docker-compose.yaml.tmpl", "--from", "embedded"}, err: ".Profile: is required"},
+		{name: "from override", args: []string{"docker-compose.yaml.tmpl", "--from", "override", "--no-lint"}, want: "override compose\n"},
+		{name: "not in the override layer", args: []string{"plain.tmpl", "--from", "override"}, err: "templates/plain.tmpl"},
+		{name: "not in the embedded layer", args: []string{"greeting.tmpl", "--from", "embedded"}, err: "templates/greeting.tmpl"},
+		{name: "invalid from", args: []string{"greeting.tmpl", "--from", "remote"}, err: `invalid --from "remote"`},
+		{name: "stdin", stdin: "stdin {{ .name }}\n", args: []string{"-", "--set", "name=a"}, want: "stdin a\n"},
+		{name: "stdin with schema", stdin: "{{ .name }}", args: []string{"-", "--schema", "schema.yaml", "--set", "name=a"}, err: "stdin: data does not match schema"},
+		{name: "stdin twice", args: []string{"-", "-d", "-"}, err: "stdin cannot provide both the template and --data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _, err := runTemplateWith(t, cfg, tt.stdin, append([]string{"render"}, tt.args...)...)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Fatalf("stdout %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
@@ -1,26 +1,139 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
 	"slices"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/homekit/homekit-cli/internal/assets"
 	"github.com/homekit/homekit-cli/internal/core"
+	executor "github.com/homekit/homekit-cli/internal/exec"
+	"github.com/homekit/homekit-cli/internal/shell"
+	"github.com/homekit/homekit-cli/internal/ui"
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
 	"github.com/homekit/homekit-cli/internal/util/pathformat"
 	"github.com/homekit/homekit-cli/internal/util/templateutil"
+	"github.com/homekit/homekit-cli/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
-var (
-	imageTypes = []string{"go1.23.2", "uv", "nvm", "default"}
+type WorkspaceOptions struct {
+	DirPath     string            `mapstructure:"dir_path"`
+	Name        string            `mapstructure:"name"`
+	Type        string            `mapstructure:"type"`
+	Image       string            `mapstructure:"image"`
+	Description string            `mapstructure:"description"`
+	Env         map[string]string `mapstructure:"env"`
+	Ports       []string          `mapstructure:"ports"`
+	Volumes     []string          `mapstructure:"volumes"`
+	Services    map[string]any    `mapstructure:"services"`
+	EnvFiles    []string          `mapstructure:"env_files"`
+	GPU         bool              `mapstructure:"gpu"`
+	Repo        string            `mapstructure:"repo"`
+	PostCreate  []string          `mapstructure:"post_create"`
+	// Format is "compose" or "devcontainer"; devcontainer also writes
+	// .devcontainer/devcontainer.json.
+	Format string `mapstructure:"format"`
+	// NamedVolumes lists the named (non-path) volume sources in Volumes so
+	// the compose template can declare them.
+	NamedVolumes []string `mapstructure:"named_volumes"`
+}
+
+const (
+	workspaceFormatCompose      = "compose"
+	workspaceFormatDevcontainer = "devcontainer"
 )
 
-type WorkspaceOptions struct {
-	DirPath string `mapstructure:"dir_path"`
-	Name    string `mapstructure:"name"`
-	Type    string `mapstructure:"type"`
+// workspaceNewFlags holds the customisation flags of `workspace new`, layered
+// on top of the workspace type defaults.
+type workspaceNewFlags struct {
+	ports    []string
+	volumes  []string
+	env      []string
+	envFiles []string
+	gpu      bool
+	repo     string
+	format   string
+}
+
+func (f *workspaceNewFlags) bind(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&f.ports, "port", "p", nil, "Publish a port (host:container), repeatable")
+	cmd.Flags().StringArrayVar(&f.volumes, "volume", nil, "Mount an extra volume (source:target[:mode]), repeatable")
+	cmd.Flags().StringArrayVarP(&f.env, "env", "e", nil, "Set a container environment variable (KEY=VALUE), repeatable")
+	cmd.Flags().StringArrayVar(&f.envFiles, "env-file", nil, "Load container environment from a file, repeatable")
+	cmd.Flags().BoolVar(&f.gpu, "gpu", false, "Reserve all NVIDIA GPUs for the dev container")
+	cmd.Flags().StringVar(&f.repo, "repo", "", "Git URL or local path to clone into code/")
+	cmd.Flags().StringVar(&f.format, "format", workspaceFormatCompose, "Workspace format (compose|devcontainer); devcontainer also writes "+workspace.DevcontainerFile)
+}
+
+// apply merges the flags into opts: ports and volumes are appended to the type
+// defaults, --env overrides type variables, env files become absolute paths.
+func (f *workspaceNewFlags) apply(opts *WorkspaceOptions) error {
+	env := maps.Clone(opts.Env)
+	if env == nil {
+		env = map[string]string{}
+	}
+	for _, kv := range f.env {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("--env %q: expected KEY=VALUE", kv)
+		}
+		env[key] = value
+	}
+	opts.Env = env
+
+	for _, port := range f.ports {
+		if port == "" {
+			return errors.New("--port must not be empty")
+		}
+	}
+	opts.Ports = append(slices.Clone(opts.Ports), f.ports...)
+
+	opts.Volumes = append(slices.Clone(opts.Volumes), f.volumes...)
+	opts.NamedVolumes = nil
+	for _, volume := range opts.Volumes {
+		source, _, ok := strings.Cut(volume, ":")
+		if !ok || source == "" {
+			return fmt.Errorf("volume %q: expected source:target", volume)
+		}
+		if isNamedVolume(source) && !slices.Contains(opts.NamedVolumes, source) {
+			opts.NamedVolumes = append(opts.NamedVolumes, source)
+		}
+	}
+
+	for _, file := range f.envFiles {
+		abs, err := filepath.Abs(pathformat.ExpandHome(file))
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return fmt.Errorf("--env-file: %w", err)
+		}
+		opts.EnvFiles = append(opts.EnvFiles, abs)
+	}
+
+	switch f.format {
+	case workspaceFormatCompose, workspaceFormatDevcontainer:
+		opts.Format = f.format
+	default:
+		return fmt.Errorf("--format %q: expected %s or %s", f.format, workspaceFormatCompose, workspaceFormatDevcontainer)
+	}
+
+	opts.GPU = f.gpu
+	opts.Repo = f.repo
+	return nil
+}
+
+// isNamedVolume reports whether a volume source names a docker volume rather
+// than a host path.
+func isNamedVolume(source string) bool {
+	return !strings.HasPrefix(source, "/") && !strings.HasPrefix(source, ".") && !strings.HasPrefix(source, "~") && !strings.HasPrefix(source, "$")
 }
 
 func NewWorkspaceCommand() *cobra.Command {
@@ -29,12 +142,31 @@ func NewWorkspaceCommand() *cobra.Command {
 		Short: "Manage workspaces",
 	}
 
-	root.AddCommand(newWorkspaceNewCommand())
+	root.AddCommand(
+		newWorkspaceNewCommand(),
+		newWorkspaceListCommand(),
+		newWorkspaceInfoCommand(),
+		newWorkspaceRmCommand(),
+		newWorkspacePruneCommand(),
+		newWorkspaceUpCommand(),
+		newWorkspaceDownCommand(),
+		newWorkspaceStatusCommand(),
+		newWorkspaceLogsCommand(),
+		newWorkspaceShellCommand(),
+		newWorkspaceTypesCommand(),
+		newWorkspaceSnapshotCommand(),
+		newWorkspaceSnapshotsCommand(),
+		newWorkspaceRestoreCommand(),
+		newWorkspaceDoctorCommand(),
+		newWorkspaceExportCommand(),
+	)
 	return root
 }
 
 func newWorkspaceNewCommand() *cobra.Command {
 	var dirStr, name, imageType string
+	var force, merge bool
+	var flags workspaceNewFlags
 
 	cmd := &cobra.Command{
 		Use:   "new",
@@ -45,33 +177,76 @@ func newWorkspaceNewCommand() *cobra.Command {
 				return err
 			}
 
-			if !slices.Contains(imageTypes, imageType) {
-				return fmt.Errorf("invalid image type: %s. Please choose from %s", imageType, strings.Join(imageTypes, ", "))
+			manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
+			types, err := loadWorkspaceTypes(manager)
+			if err != nil {
+				return err
 			}
-
-			opts := WorkspaceOptions{
-				DirPath: pathformat.RenderFullPath(dirStr),
-				Name:    name,
-				Type:    imageType,
+			spec, err := types.Lookup(imageType)
+			if err != nil {
+				return err
 			}
 
-			workspaceDir, err := createWorkspaceSkeleton(rt, opts)
-			if err != nil {
+			opts := newWorkspaceOptions(spec, pathformat.RenderFullPath(dirStr), name)
+			if err := flags.apply(&opts); err != nil {
 				return err
 			}
 
-			rt.Logger.Info().Msgf("Workspace created successfully in %s", workspaceDir)
-			return nil
+			conflict := conflictAsk
+			switch {
+			case force:
+				conflict = conflictOverwrite
+			case merge:
+				conflict = conflictMerge
+			}
+			return createWorkspaceSkeleton(cmd, rt, manager, spec, opts, conflict)
 		},
 	}
 
 	cmd.Flags().StringVarP(&dirStr, "dir", "d", ".", "Directory to create the workspace in")
 	cmd.Flags().StringVarP(&name, "name", "n", "", "Name of the workspace")
-	cmd.Flags().StringVarP(&imageType, "type", "t", "default", "Type of the workspace")
+	cmd.Flags().StringVarP(&imageType, "type", "t", "default", "Type of the workspace (see `workspace types`)")
+	cmd.Flags().BoolVar(&force, "force", false, "Overwrite existing skeleton files")
+	cmd.Flags().BoolVar(&merge, "merge", false, "Keep existing skeleton files and only add missing ones")
+	cmd.MarkFlagsMutuallyExclusive("force", "merge")
+	flags.bind(cmd)
 
 	return cmd
 }
 
+// newWorkspaceOptions returns template options carrying the type defaults.
+func newWorkspaceOptions(spec workspace.Type, dir, name string) WorkspaceOptions {
+	opts := WorkspaceOptions{
+		DirPath:     dir,
+		Name:        name,
+		Type:        spec.Name,
+		Image:       spec.Image,
+		Description: spec.Description,
+		Env:         spec.Env,
+		Ports:       spec.Ports,
+		Volumes:     spec.Volumes,
+		Services:    spec.Services,
+		PostCreate:  spec.Hooks.PostCreate,
+		Format:      workspaceFormatCompose,
+	}
+	for _, volume := range opts.Volumes {
+		if source, _, ok := strings.Cut(volume, ":"); ok && isNamedVolume(source) && !slices.Contains(opts.NamedVolumes, source) {
+			opts.NamedVolumes = append(opts.NamedVolumes, source)
+		}
+	}
+	return opts
+}
+
+// conflictPolicy decides what happens to skeleton files that already exist.
+type conflictPolicy int
+
+const (
+	// conflictAsk prompts on a terminal and fails otherwise.
+	conflictAsk conflictPolicy = iota
+	conflictOverwrite
+	conflictMerge
+)
+
 /*
 *
 Create a new workspace skeleton.
@@ -82,18 +257,18 @@ It will contain the following files:
   - README.md
   - code (dir)
   - Makefile
+  - compose.dev.yml
+  - extra files declared by the workspace type
+
+Files are rendered into a staging directory under temp_dir first and only
+moved into place once everything rendered. Existing files are handled by the
+conflict policy. If cloning, a post_create hook or registration fails, every
+file and directory created (or overwritten) is rolled back.
 */
-func createWorkspaceSkeleton(rt *core.Runtime, opts WorkspaceOptions) (string, error) {
-	// init workspace dir
-	workspaceDir := ""
-	if opts.DirPath == "" {
-		workspaceDir = pathformat.Pwd()
-	} else {
+func createWorkspaceSkeleton(cmd *cobra.Command, rt *core.Runtime, assetManager *assets.Manager, spec workspace.Type, opts WorkspaceOptions, conflict conflictPolicy) (err error) {
+	workspaceDir := pathformat.Pwd()
+	if opts.DirPath != "" {
 		workspaceDir = pathformat.RenderFullPath(opts.DirPath)
-		err := pathformat.MakeDirIfNotExists(workspaceDir)
-		if err != nil {
-			return "", err
-		}
 	}
 	if opts.Name == "" {
 		opts.Name = pathformat.Base(workspaceDir)
@@ -102,62 +277,256 @@ func createWorkspaceSkeleton(rt *core.Runtime, opts WorkspaceOptions) (string, e
 	rt.Logger.Info().Msgf("Name: %s", opts.Name)
 	rt.Logger.Info().Msgf("Type: %s", opts.Type)
 
-	// create README.md with template replacement
-	assetManager := assets.NewManager(assets.Embedded(), "")
-	readmeContent, err := assetManager.OpenBytes(assets.AssetNamespaceWorkspaces, "README.md")
+	stage, err := fileutil.NewStage(rt.Config.TempDirectory())
 	if err != nil {
-		return "", err
+		return fmt.Errorf("create staging directory: %w", err)
 	}
+	defer stage.Close()
 
-	readmePath := pathformat.Join(workspaceDir, "README.md")
-	readmeFinalContent, err := templateutil.RenderTemplateInBytes(readmeContent, opts, "readme", rt.BufPool)
-	if err != nil {
-		return "", err
+	// render every file before touching the workspace directory
+	if err := stageWorkspaceFiles(rt, assetManager, spec, opts, stage); err != nil {
+		return err
 	}
-	if err := os.WriteFile(readmePath, readmeFinalContent, 0644); err != nil {
-		return "", err
+
+	if err := resolveSkeletonConflicts(cmd, rt, stage, workspaceDir, conflict); err != nil {
+		return err
 	}
 
-	rt.Logger.Info().Msgf("README.md created in %s", readmePath)
+	if rt.DryRun {
+		for _, rel := range stage.Files() {
+			rt.Logger.Info().Msgf("Would write %s", filepath.Join(workspaceDir, filepath.FromSlash(rel)))
+		}
+		if opts.Repo != "" {
+			rt.Logger.Info().Msgf("Would clone %s into %s", opts.Repo, pathformat.Join(workspaceDir, "code"))
+		}
+		for _, hook := range spec.Hooks.PostCreate {
+			rt.Logger.Info().Msgf("Would run post_create hook: %s", hook)
+		}
+		return nil
+	}
+
+	defer func() {
+		if err == nil {
+			return
+		}
+		rt.Logger.Warn().Msgf("Rolling back workspace %s", workspaceDir)
+		if rbErr := stage.Rollback(); rbErr != nil {
+			err = errors.Join(err, fmt.Errorf("rollback: %w", rbErr))
+		}
+	}()
 
 	// create code directory
-	codeDir := pathformat.Join(workspaceDir, "code")
-	if err := os.MkdirAll(codeDir, 0755); err != nil {
-		return "", err
+	codeDir := pathformat.Join(workspaceDir, workspaceCodeDir)
+	if err := stage.MkdirAll(codeDir, 0755); err != nil {
+		return err
+	}
+	if err := stage.Commit(workspaceDir); err != nil {
+		return err
+	}
+	generated := append(stage.Files(), workspaceCodeDir)
+	for _, rel := range stage.Files() {
+		rt.Logger.Info().Msgf("%s created", rel)
 	}
 
-	rt.Logger.Info().Msgf("Code directory created in %s", codeDir)
+	if opts.Repo != "" {
+		// the clone is recorded as the code directory only: it may hold
+		// local work, so workspace rm refuses until it is gone
+		if err := cloneWorkspaceRepo(cmd, rt, opts.Repo, codeDir); err != nil {
+			return err
+		}
+		stage.OnRollback(func() error { return emptyDir(codeDir) })
+	}
 
-	// create Makefile
-	makefileContent, err := assetManager.OpenBytes(assets.AssetNamespaceWorkspaces, "Makefile")
-	if err != nil {
-		return "", err
+	if err := runWorkspaceHooks(cmd, rt, workspaceDir, "post_create", spec.Hooks.PostCreate); err != nil {
+		return err
 	}
-	makefilePath := pathformat.Join(workspaceDir, "Makefile")
-	makefileFinalContent, err := templateutil.RenderTemplateInBytes(makefileContent, opts, "Makefile", rt.BufPool)
-	if err != nil {
-		return "", err
+
+	if err := registerWorkspace(rt, workspace.Entry{
+		Name:      opts.Name,
+		Path:      workspaceDir,
+		Type:      opts.Type,
+		CreatedAt: time.Now().UTC(),
+		Files:     generated,
+	}); err != nil {
+		return err
+	}
+
+	rt.Logger.Info().Msgf("Workspace created successfully in %s", workspaceDir)
+	return nil
+}
+
+// stageWorkspaceFiles renders the skeleton files and the type's extra files
+// into stage.
+func stageWorkspaceFiles(rt *core.Runtime, assetManager *assets.Manager, spec workspace.Type, opts WorkspaceOptions, stage *fileutil.Stage) error {
+	renderer := newTemplateRenderer(rt.Context, rt)
+	renderer.Strict = true
+	files := []workspace.TemplateFile{
+		{Src: "README.md", Dest: "README.md"},
+		{Src: "Makefile", Dest: "Makefile"},
+		{Src: workspaceComposeFile, Dest: workspaceComposeFile},
+	}
+	files = append(files, spec.Files...)
+	for _, file := range files {
+		if err := assets.ValidateName(file.Dest); err != nil {
+			return fmt.Errorf("workspace type %s: file destination %q: %w", spec.Name, file.Dest, err)
+		}
+		content, err := assetManager.OpenBytes(assets.AssetNamespaceWorkspaces, file.Src)
+		if err != nil {
+			return fmt.Errorf("workspace type %s: %w", spec.Name, describeAssetError(err, assets.AssetNamespaceWorkspaces, file.Src))
+		}
+		rendered, err := templateutil.RenderTemplateInBytes(renderer, content, opts, file.Src, rt.BufPool)
+		if err != nil {
+			return err
+		}
+		if err := stage.Add(file.Dest, rendered, 0644); err != nil {
+			return err
+		}
+	}
+
+	if opts.Format == workspaceFormatDevcontainer {
+		content, err := renderDevcontainer(opts)
+		if err != nil {
+			return err
+		}
+		if err := stage.Add(workspace.DevcontainerFile, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSkeletonConflicts applies the conflict policy to staged files that
+// already exist in dir. Files whose content is unchanged are never conflicts.
+func resolveSkeletonConflicts(cmd *cobra.Command, rt *core.Runtime, stage *fileutil.Stage, dir string, conflict conflictPolicy) error {
+	var existing []string
+	for _, rel := range stage.Files() {
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		data, err := os.ReadFile(filepath.Join(stage.Dir(), filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		status, _, err := fileutil.Compare(target, data)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case fileutil.StatusUnchanged:
+			stage.Drop(rel)
+		case fileutil.StatusChanged:
+			existing = append(existing, rel)
+		}
+	}
+	if len(existing) == 0 {
+		return nil
+	}
+
+	if conflict == conflictAsk {
+		if !stdinIsTerminal(cmd) {
+			return fmt.Errorf("%s already contains %s; use --force to overwrite or --merge to keep them", dir, strings.Join(existing, ", "))
+		}
+		prompter := ui.Prompter{In: cmd.InOrStdin(), Out: cmd.OutOrStdout()}
+		ok, err := prompter.Confirm(fmt.Sprintf("%s already contains %s. Overwrite?", dir, strings.Join(existing, ", ")), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("aborted: existing files left untouched")
+		}
+		conflict = conflictOverwrite
 	}
-	if err := os.WriteFile(makefilePath, makefileFinalContent, 0644); err != nil {
-		return "", err
+
+	for _, rel := range existing {
+		if conflict == conflictMerge {
+			rt.Logger.Info().Msgf("Keeping existing %s", rel)
+			stage.Drop(rel)
+		} else {
+			rt.Logger.Warn().Msgf("Overwriting %s", rel)
+		}
 	}
+	return nil
+}
 
-	rt.Logger.Info().Msgf("Makefile created in %s", makefilePath)
+// emptyDir removes the contents of dir but keeps the directory itself.
+func emptyDir(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	// create compose.dev.yml
-	composeDevContent, err := assetManager.OpenBytes(assets.AssetNamespaceWorkspaces, "compose.dev.yml")
+// cloneWorkspaceRepo clones repo into the (empty) code directory with the
+// local git binary. Local paths are made absolute so they resolve from the
+// caller's directory.
+func cloneWorkspaceRepo(cmd *cobra.Command, rt *core.Runtime, repo, codeDir string) error {
+	source := repo
+	if info, err := os.Stat(pathformat.ExpandHome(repo)); err == nil && info.IsDir() {
+		if source, err = filepath.Abs(pathformat.ExpandHome(repo)); err != nil {
+			return err
+		}
+	}
+	rt.Logger.Info().Msgf("Cloning %s into %s", source, codeDir)
+	_, err := executor.Run(cmd.Context(), executor.Spec{
+		Command: "git",
+		Args:    []string{"clone", "--", source, codeDir},
+	})
 	if err != nil {
-		return "", err
+		return fmt.Errorf("git clone %s: %w", source, err)
 	}
-	composeDevFinalContent, err := templateutil.RenderTemplateInBytes(composeDevContent, opts, "compose.dev.yml", rt.BufPool)
+	return nil
+}
+
+// loadWorkspaceTypes reads the workspace type registry, preferring an
+// override types.yaml over the embedded one.
+func loadWorkspaceTypes(manager *assets.Manager) (*workspace.Types, error) {
+	fsys, err := manager.FS(assets.AssetNamespaceWorkspaces)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	composeDevPath := pathformat.Join(workspaceDir, "compose.dev.yml")
-	if err := os.WriteFile(composeDevPath, composeDevFinalContent, 0644); err != nil {
-		return "", err
+	return workspace.LoadTypes(fsys)
+}
+
+// runWorkspaceHooks runs hook commands through the embedded shell inside dir.
+func runWorkspaceHooks(cmd *cobra.Command, rt *core.Runtime, dir, stage string, hooks []string) error {
+	for i, hook := range hooks {
+		rt.Logger.Info().Msgf("Running %s hook: %s", stage, hook)
+		_, err := shell.Run(cmd.Context(), fmt.Sprintf("%s[%d]", stage, i), strings.NewReader(hook), shell.Options{
+			Dir:    dir,
+			Stdout: cmd.OutOrStdout(),
+			Stderr: cmd.ErrOrStderr(),
+		})
+		if err != nil {
+			return fmt.Errorf("%s hook %q: %w", stage, hook, err)
+		}
 	}
-	rt.Logger.Info().Msgf("compose.dev.yml created")
+	return nil
+}
 
-	return workspaceDir, nil
+func newWorkspaceTypesCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "types",
+		Short: "List available workspace types",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			types, err := loadWorkspaceTypes(assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config)))
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tIMAGE\tDESCRIPTION")
+			for _, t := range types.List() {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.Image, t.Description)
+			}
+			return w.Flush()
+		},
+	}
 }
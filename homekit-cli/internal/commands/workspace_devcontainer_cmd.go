@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/homekit/homekit-cli/internal/assets"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+func newWorkspaceExportCommand() *cobra.Command {
+	root := &cobra.Command{
+		Use:   "export",
+		Short: "Export a workspace definition for other tools",
+	}
+	root.AddCommand(newWorkspaceExportDevcontainerCommand())
+	return root
+}
+
+func newWorkspaceExportDevcontainerCommand() *cobra.Command {
+	var (
+		outputOpts templateOutputOptions
+		stdout     bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "devcontainer [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Write .devcontainer/devcontainer.json derived from compose.dev.yml",
+		Long: `Derive a dev container definition from the workspace's compose.dev.yml
+(image, volumes, ports, environment, env files and GPU reservations) plus the
+post_create hooks of its type, and write it to .devcontainer/devcontainer.json.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			e, err := resolveWorkspace(rt, args)
+			if err != nil {
+				return err
+			}
+			opts, err := workspaceOptionsFromCompose(e)
+			if err != nil {
+				return err
+			}
+			if e.Type != "" {
+				manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
+				types, err := loadWorkspaceTypes(manager)
+				if err != nil {
+					return err
+				}
+				if spec, err := types.Lookup(e.Type); err == nil {
+					opts.PostCreate = spec.Hooks.PostCreate
+				}
+			}
+
+			content, err := renderDevcontainer(opts)
+			if err != nil {
+				return err
+			}
+			if stdout {
+				_, err := cmd.OutOrStdout().Write(content)
+				return err
+			}
+			path := filepath.Join(e.Path, filepath.FromSlash(workspace.DevcontainerFile))
+			_, statErr := os.Stat(path)
+			if err := writeTemplateOutput(cmd, rt, path, content, outputOpts); err != nil {
+				return err
+			}
+			if statErr == nil || outputOpts.check {
+				return nil
+			}
+			return recordGeneratedFile(rt, e, workspace.DevcontainerFile)
+		},
+	}
+
+	cmd.Flags().BoolVar(&stdout, "stdout", false, "Print the definition instead of writing it")
+	cmd.Flags().BoolVar(&outputOpts.diff, "diff", false, "Show a unified diff between the existing file and the new definition")
+	cmd.Flags().BoolVar(&outputOpts.backup, "backup", false, "Keep the previous file as <file>.bak before replacing it")
+	cmd.Flags().BoolVar(&outputOpts.check, "check", false, "Do not write; exit non-zero when the existing file is out of date")
+	return cmd
+}
+
+// renderDevcontainer builds devcontainer.json from the same options that
+// render compose.dev.yml.
+func renderDevcontainer(opts WorkspaceOptions) ([]byte, error) {
+	dc := workspace.NewDevcontainer(opts.Name, opts.Image)
+	for _, volume := range opts.Volumes {
+		if err := dc.AddVolume(volume); err != nil {
+			return nil, err
+		}
+	}
+	if len(opts.Env) > 0 {
+		dc.ContainerEnv = opts.Env
+	}
+	dc.AppPort = opts.Ports
+	for _, file := range opts.EnvFiles {
+		dc.RunArgs = append(dc.RunArgs, "--env-file", file)
+	}
+	if opts.GPU {
+		dc.RunArgs = append(dc.RunArgs, "--gpus=all")
+	}
+	dc.PostCreateCommand = strings.Join(opts.PostCreate, " && ")
+	return dc.Marshal()
+}
+
+// composeDevService is the part of a compose service that maps back onto
+// WorkspaceOptions.
+type composeDevService struct {
+	Image         string `yaml:"image"`
+	ContainerName string `yaml:"container_name"`
+	Volumes       []any  `yaml:"volumes"`
+	Ports         []any  `yaml:"ports"`
+	Environment   any    `yaml:"environment"`
+	EnvFile       any    `yaml:"env_file"`
+	Deploy        struct {
+		Resources struct {
+			Reservations struct {
+				Devices []struct {
+					Capabilities []string `yaml:"capabilities"`
+				} `yaml:"devices"`
+			} `yaml:"reservations"`
+		} `yaml:"resources"`
+	} `yaml:"deploy"`
+}
+
+// workspaceOptionsFromCompose reads the dev service of an existing workspace
+// back into WorkspaceOptions. The ./code mount is implied and skipped.
+func workspaceOptionsFromCompose(e workspace.Entry) (WorkspaceOptions, error) {
+	path := filepath.Join(e.Path, workspaceComposeFile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return WorkspaceOptions{}, err
+	}
+	var doc struct {
+		Services map[string]composeDevService `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return WorkspaceOptions{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+	svc, ok := doc.Services[e.Name]
+	if !ok {
+		for _, candidate := range doc.Services {
+			if candidate.ContainerName == workspaceContainerName(e.Name) {
+				svc, ok = candidate, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return WorkspaceOptions{}, fmt.Errorf("%s: no service %s or container %s", path, e.Name, workspaceContainerName(e.Name))
+	}
+
+	opts := WorkspaceOptions{
+		DirPath: e.Path,
+		Name:    e.Name,
+		Type:    e.Type,
+		Image:   svc.Image,
+		Env:     map[string]string{},
+		Format:  workspaceFormatDevcontainer,
+	}
+	for _, v := range svc.Volumes {
+		volume, ok := v.(string)
+		if !ok {
+			return WorkspaceOptions{}, fmt.Errorf("%s: only short volume syntax is supported, got %v", path, v)
+		}
+		if target, found := strings.CutPrefix(volume, "./code:"); found && target == workspace.CodeMountTarget {
+			continue
+		}
+		opts.Volumes = append(opts.Volumes, volume)
+	}
+	for _, p := range svc.Ports {
+		opts.Ports = append(opts.Ports, fmt.Sprint(p))
+	}
+	switch env := svc.Environment.(type) {
+	case map[string]any:
+		for k, v := range env {
+			if v == nil {
+				v = ""
+			}
+			opts.Env[k] = fmt.Sprint(v)
+		}
+	case []any:
+		for _, item := range env {
+			k, v, _ := strings.Cut(fmt.Sprint(item), "=")
+			opts.Env[k] = v
+		}
+	}
+	switch files := svc.EnvFile.(type) {
+	case string:
+		opts.EnvFiles = []string{files}
+	case []any:
+		for _, f := range files {
+			opts.EnvFiles = append(opts.EnvFiles, fmt.Sprint(f))
+		}
+	}
+	for _, device := range svc.Deploy.Resources.Reservations.Devices {
+		for _, capability := range device.Capabilities {
+			if capability == "gpu" {
+				opts.GPU = true
+			}
+		}
+	}
+	return opts, nil
+}
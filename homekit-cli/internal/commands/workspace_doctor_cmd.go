@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/assets"
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+// dockerProbe checks that the Docker daemon answers the Engine API. Tests
+// and alternative runtimes can replace it.
+var dockerProbe = func(ctx context.Context, rt *core.Runtime) error {
+	client, err := newDockerClient(rt)
+	if err != nil {
+		return fmt.Errorf("docker unavailable: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx); err != nil {
+		return fmt.Errorf("docker unavailable: %w", err)
+	}
+	return nil
+}
+
+func newWorkspaceDoctorCommand() *cobra.Command {
+	var (
+		output string
+		fix    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "doctor [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Check a workspace against its type and the local docker setup",
+		Long: `Check that the workspace directory, README.md, Makefile and code/ exist,
+that compose.dev.yml parses, names the container <name>-dev and uses an image
+provided by a workspace type, and that docker is available. With --fix,
+missing skeleton files are regenerated from the workspace templates.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			e, err := resolveWorkspace(rt, args)
+			if err != nil {
+				return err
+			}
+			manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
+			types, err := loadWorkspaceTypes(manager)
+			if err != nil {
+				return err
+			}
+
+			doctor := workspace.Doctor{
+				Types:       types,
+				ComposeFile: workspaceComposeFile,
+				Docker:      func(ctx context.Context) error { return dockerProbe(ctx, rt) },
+			}
+			checks := doctor.Run(cmd.Context(), e)
+
+			if fix && hasFixable(checks) {
+				spec, err := doctor.InferType(e)
+				if err != nil {
+					return err
+				}
+				if err := fixWorkspace(rt, manager, spec, e); err != nil {
+					return err
+				}
+				if !rt.DryRun {
+					checks = doctor.Run(cmd.Context(), e)
+				}
+			}
+
+			if output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(checks); err != nil {
+					return err
+				}
+			} else {
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+				fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+				for _, c := range checks {
+					detail := c.Detail
+					if c.Fixable && !fix {
+						detail += " (--fix)"
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, detail)
+				}
+				if err := tw.Flush(); err != nil {
+					return err
+				}
+			}
+
+			failed := 0
+			for _, c := range checks {
+				if c.Status == workspace.CheckFail {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("workspace %s: %d check(s) failed", e.Name, failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	cmd.Flags().BoolVar(&fix, "fix", false, "Regenerate missing skeleton files")
+	return cmd
+}
+
+func hasFixable(checks []workspace.Check) bool {
+	for _, c := range checks {
+		if c.Fixable && c.Status != workspace.CheckOK {
+			return true
+		}
+	}
+	return false
+}
+
+// fixWorkspace renders the skeleton for the workspace's type and writes only
+// the files that are missing; existing files are never touched.
+func fixWorkspace(rt *core.Runtime, manager *assets.Manager, spec workspace.Type, e workspace.Entry) error {
+	stage, err := fileutil.NewStage(rt.Config.TempDirectory())
+	if err != nil {
+		return fmt.Errorf("create staging directory: %w", err)
+	}
+	defer stage.Close()
+
+	if err := stageWorkspaceFiles(rt, manager, spec, newWorkspaceOptions(spec, e.Path, e.Name), stage); err != nil {
+		return err
+	}
+	for _, rel := range stage.Files() {
+		if _, err := os.Lstat(filepath.Join(e.Path, filepath.FromSlash(rel))); err == nil {
+			stage.Drop(rel)
+		}
+	}
+
+	codeDir := filepath.Join(e.Path, "code")
+	if rt.DryRun {
+		for _, rel := range stage.Files() {
+			rt.Logger.Info().Msgf("Would regenerate %s", rel)
+		}
+		if _, err := os.Stat(codeDir); err != nil {
+			rt.Logger.Info().Msgf("Would create %s", codeDir)
+		}
+		return nil
+	}
+	if err := stage.MkdirAll(codeDir, 0755); err != nil {
+		return err
+	}
+	if err := stage.Commit(e.Path); err != nil {
+		return err
+	}
+	for _, rel := range stage.Files() {
+		rt.Logger.Info().Msgf("Regenerated %s", rel)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+// stubDockerProbe replaces the daemon check for the rest of the test.
+func stubDockerProbe(t *testing.T, err error) {
+	t.Helper()
+	saved := dockerProbe
+	dockerProbe = func(context.Context, *core.Runtime) error { return err }
+	t.Cleanup(func() { dockerProbe = saved })
+}
+
+// bareWorkspace registers "demo" as a default workspace whose directory only
+// holds a hand-written README.md.
+func bareWorkspace(t *testing.T) (*core.Runtime, string) {
+	t.Helper()
+	rt, dir := workspaceRuntime(t)
+	for _, name := range []string{workspaceComposeFile, "code"} {
+		if err := os.RemoveAll(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("my notes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return rt, dir
+}
+
+func TestWorkspaceDoctor(t *testing.T) {
+	stubDockerProbe(t, nil)
+	rt, _ := bareWorkspace(t)
+
+	out, err := runWorkspace(t, rt, "doctor", "demo")
+	if err == nil || !strings.Contains(err.Error(), "workspace demo: 2 check(s) failed") {
+		t.Fatalf("doctor error = %v", err)
+	}
+	for _, want := range []string{
+		"Makefile         warn    missing (--fix)",
+		"code/            fail    missing (--fix)",
+		"compose.dev.yml  fail    missing (--fix)",
+		"docker           ok",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("doctor output lacks %q:\n%s", want, out)
+		}
+	}
+
+	stubDockerProbe(t, errors.New("docker unavailable: ping: connection refused"))
+	out, err = runWorkspace(t, rt, "doctor", "demo", "-o", "json")
+	if err == nil {
+		t.Fatal("expected failed checks")
+	}
+	var checks []workspace.Check
+	if err := json.Unmarshal([]byte(out), &checks); err != nil {
+		t.Fatal(err)
+	}
+	last := checks[len(checks)-1]
+	if last.Name != "docker" || last.Status != workspace.CheckFail || last.Detail != "docker unavailable: ping: connection refused" {
+		t.Fatalf("docker check %+v", last)
+	}
+}
+
+func TestWorkspaceDoctorFix(t *testing.T) {
+	stubDockerProbe(t, nil)
+	rt, dir := bareWorkspace(t)
+
+	rt.DryRun = true
+	if _, err := runWorkspace(t, rt, "doctor", "demo", "--fix"); err == nil {
+		t.Fatal("dry run reported a fixed workspace")
+	}
+	for _, name := range []string{"Makefile", "code", workspaceComposeFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("dry run created %s", name)
+		}
+	}
+
+	rt.DryRun = false
+	out, err := runWorkspace(t, rt, "doctor", "demo", "--fix")
+	if err != nil {
+		t.Fatalf("doctor --fix: %v\n%s", err, out)
+	}
+	if strings.Contains(out, "warn") || strings.Contains(out, "fail") {
+		t.Fatalf("checks after --fix:\n%s", out)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "README.md")); got != "my notes\n" {
+		t.Fatalf("--fix rewrote README.md: %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, workspaceComposeFile)); !strings.Contains(got, "container_name: demo-dev") {
+		t.Fatalf("regenerated %s:\n%s", workspaceComposeFile, got)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "code")); err != nil || !info.IsDir() {
+		t.Fatalf("code/ not created: %v", err)
+	}
+}
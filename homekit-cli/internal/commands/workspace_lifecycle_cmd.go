@@ -0,0 +1,335 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/docker"
+	executor "github.com/homekit/homekit-cli/internal/exec"
+	"github.com/homekit/homekit-cli/internal/util/pathformat"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+func newWorkspaceUpCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "up [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Start a workspace dev container (defaults to the current directory)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWorkspaceCompose(cmd, args, "up", "-d")
+		},
+	}
+}
+
+func newWorkspaceDownCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "down [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Stop a workspace dev container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runWorkspaceCompose(cmd, args, "down")
+		},
+	}
+}
+
+func newWorkspaceStatusCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "status [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Show workspace container status",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			ws, err := resolveWorkspace(rt, args)
+			if err != nil {
+				return err
+			}
+			return showComposeContainers(cmd, rt, ws.Path, output,
+				fmt.Sprintf("workspace %s has no containers (see `workspace up`)", ws.Name))
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	return cmd
+}
+
+func newWorkspaceLogsCommand() *cobra.Command {
+	var follow bool
+	var tail int
+
+	cmd := &cobra.Command{
+		Use:   "logs [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Show workspace container logs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			ws, err := resolveWorkspace(rt, args)
+			if err != nil {
+				return err
+			}
+			client, err := newDockerClient(rt)
+			if err != nil {
+				return err
+			}
+			opts := docker.LogsOptions{Follow: follow}
+			if tail >= 0 {
+				opts.Tail = strconv.Itoa(tail)
+			}
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			err = client.CopyLogs(ctx, workspaceContainerName(ws.Name), opts, cmd.OutOrStdout(), cmd.ErrOrStderr())
+			if errors.Is(err, docker.ErrNotFound) {
+				return fmt.Errorf("workspace %s: container %s not found (see `workspace up`)", ws.Name, workspaceContainerName(ws.Name))
+			}
+			if ctx.Err() != nil && cmd.Context().Err() == nil {
+				// interrupted by the user
+				return nil
+			}
+			return err
+		},
+	}
+
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	cmd.Flags().IntVar(&tail, "tail", -1, "Number of lines to show from the end (-1 for all)")
+	return cmd
+}
+
+func newWorkspaceShellCommand() *cobra.Command {
+	var shell string
+
+	cmd := &cobra.Command{
+		Use:   "shell [name]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Open an interactive shell in the workspace container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			ws, err := resolveWorkspace(rt, args)
+			if err != nil {
+				return err
+			}
+			container := workspaceContainerName(ws.Name)
+			if rt.DryRun {
+				rt.Logger.Info().Msgf("Would run %s in %s", shell, container)
+				return nil
+			}
+			client, err := newDockerClient(rt)
+			if err != nil {
+				return err
+			}
+			code, err := execInteractive(cmd, client, container, []string{shell})
+			if errors.Is(err, docker.ErrNotFound) {
+				return fmt.Errorf("workspace %s: container %s is not running (see `workspace up`)", ws.Name, container)
+			}
+			if err != nil {
+				return err
+			}
+			if code != 0 {
+				return &core.ExitError{Code: code}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&shell, "shell", "bash", "Shell to start inside the container")
+	return cmd
+}
+
+// execInteractive runs argv in a container with the command's stdin attached
+// and returns its exit code. When stdin is a terminal it is switched to raw
+// mode and the container gets a TTY that follows the terminal size.
+func execInteractive(cmd *cobra.Command, client *docker.Client, container string, argv []string) (int, error) {
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	in, out := cmd.InOrStdin(), cmd.OutOrStdout()
+	tty := stdinIsTerminal(cmd)
+
+	id, err := client.ExecCreate(ctx, container, docker.ExecOptions{Cmd: argv, Tty: tty, Stdin: true})
+	if err != nil {
+		return 0, err
+	}
+	conn, err := client.ExecAttach(ctx, id, tty)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	if tty {
+		fd := int(in.(*os.File).Fd())
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return 0, fmt.Errorf("set terminal raw mode: %w", err)
+		}
+		defer term.Restore(fd, state)
+		go followTerminalSize(ctx, client, id, fd)
+	}
+
+	go func() {
+		io.Copy(conn, in)
+		conn.CloseWrite()
+	}()
+	if tty {
+		_, err = io.Copy(out, conn)
+	} else {
+		err = docker.StdCopy(out, cmd.ErrOrStderr(), conn)
+	}
+	if err != nil && ctx.Err() == nil {
+		return 0, err
+	}
+
+	state, err := client.ExecInspect(context.WithoutCancel(ctx), id)
+	if err != nil {
+		return 0, err
+	}
+	return state.ExitCode, nil
+}
+
+// followTerminalSize resizes the exec TTY whenever the local terminal
+// changes size. Polling keeps it portable to systems without SIGWINCH.
+func followTerminalSize(ctx context.Context, client *docker.Client, id string, fd int) {
+	lastW, lastH := 0, 0
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if w, h, err := term.GetSize(fd); err == nil && (w != lastW || h != lastH) {
+			if client.ExecResize(ctx, id, h, w) == nil {
+				lastW, lastH = w, h
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// showComposeContainers lists the containers compose created for dir as a
+// table or JSON, printing empty instead of an empty table.
+func showComposeContainers(cmd *cobra.Command, rt *core.Runtime, dir, output, empty string) error {
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unknown output format %q (table|json)", output)
+	}
+	client, err := newDockerClient(rt)
+	if err != nil {
+		return err
+	}
+	containers, err := client.ContainerList(cmd.Context(), docker.ContainerListOptions{
+		All:     true,
+		Filters: docker.ComposeProjectFilter(dir),
+	})
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(containers)
+	}
+	if len(containers) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), empty)
+		return nil
+	}
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSERVICE\tIMAGE\tSTATE\tSTATUS")
+	for _, c := range containers {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Name(), c.Labels[docker.ComposeServiceLabel], c.Image, c.State, c.Status)
+	}
+	return tw.Flush()
+}
+
+// runWorkspaceCompose runs `docker compose -f compose.dev.yml <args>` in the
+// resolved workspace directory, streaming output to the terminal.
+func runWorkspaceCompose(cmd *cobra.Command, args []string, composeArgs ...string) error {
+	rt, err := runtimeFrom(cmd)
+	if err != nil {
+		return err
+	}
+	ws, err := resolveWorkspace(rt, args)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(ws.Path, workspaceComposeFile)); err != nil {
+		return fmt.Errorf("workspace %s has no %s: %w", ws.Name, workspaceComposeFile, err)
+	}
+
+	rt.Logger.Debug().Msgf("docker compose %v in %s", composeArgs, ws.Path)
+	_, err = executor.Run(cmd.Context(), executor.Spec{
+		Command: "docker",
+		Args:    append([]string{"compose", "-f", workspaceComposeFile}, composeArgs...),
+		Dir:     ws.Path,
+		DryRun:  rt.DryRun,
+	})
+	return err
+}
+
+// resolveWorkspace finds the workspace named in args, or the one containing
+// the current directory. Unregistered directories with a compose.dev.yml are
+// accepted and named after the directory, matching `workspace new`.
+func resolveWorkspace(rt *core.Runtime, args []string) (workspace.Entry, error) {
+	registry, err := loadWorkspaceRegistry(rt)
+	if err != nil {
+		return workspace.Entry{}, err
+	}
+	if len(args) > 0 {
+		e, err := registry.Get(args[0])
+		if err != nil {
+			return workspace.Entry{}, err
+		}
+		if !e.Exists() {
+			return workspace.Entry{}, fmt.Errorf("workspace %s: directory %s no longer exists (see `workspace prune`)", e.Name, e.Path)
+		}
+		return e, nil
+	}
+
+	dir := pathformat.Pwd()
+	for {
+		if e, ok := registry.FindByPath(dir); ok {
+			return e, nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, workspaceComposeFile)); err == nil {
+			return workspace.Entry{Name: pathformat.Base(dir), Path: dir}, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return workspace.Entry{}, errors.New("not inside a workspace: pass a workspace name or run from a workspace directory")
+}
+
+func workspaceContainerName(name string) string {
+	return name + "-dev"
+}
+
+// stdinIsTerminal reports whether the command reads from an interactive terminal.
+func stdinIsTerminal(cmd *cobra.Command) bool {
+	f, ok := cmd.InOrStdin().(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
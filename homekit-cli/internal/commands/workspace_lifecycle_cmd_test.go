@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/docker"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+// workspaceRuntime returns a runtime with the workspace "demo" registered
+// at <tmp>/demo, which holds a compose.dev.yml.
+func workspaceRuntime(t *testing.T) (*core.Runtime, string) {
+	t.Helper()
+	rt := &core.Runtime{Logger: zerolog.Nop()}
+	rt.Config.StateDir = t.TempDir()
+	dir := filepath.Join(t.TempDir(), "demo")
+	if err := os.MkdirAll(filepath.Join(dir, "code", "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, workspaceComposeFile), []byte("services: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	registry, err := workspace.LoadRegistry(rt.Config.StateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Add(workspace.Entry{Name: "demo", Path: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return rt, dir
+}
+
+func runWorkspace(t *testing.T, rt *core.Runtime, args ...string) (string, error) {
+	t.Helper()
+	cmd := NewWorkspaceCommand()
+	var out bytes.Buffer
+	cmd.SetIn(strings.NewReader(""))
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SilenceUsage, cmd.SilenceErrors = true, true
+	cmd.SetArgs(args)
+	err := cmd.ExecuteContext(core.WithRuntime(context.Background(), rt))
+	return out.String(), err
+}
+
+func TestWorkspaceUpDown(t *testing.T) {
+	calls := fakeDockerCLI(t)
+	rt, dir := workspaceRuntime(t)
+
+	if _, err := runWorkspace(t, rt, "up", "demo"); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, filepath.Join(dir, "code", "src"))
+	if _, err := runWorkspace(t, rt, "down"); err != nil {
+		t.Fatal(err)
+	}
+	rt.DryRun = true
+	if _, err := runWorkspace(t, rt, "up"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{dir + "|compose -f compose.dev.yml up -d", dir + "|compose -f compose.dev.yml down"}
+	if got := calls(); !slices.Equal(got, want) {
+		t.Fatalf("docker calls %q, want %q", got, want)
+	}
+
+	rt.DryRun = false
+	if err := os.Remove(filepath.Join(dir, workspaceComposeFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runWorkspace(t, rt, "up", "demo"); err == nil || !strings.Contains(err.Error(), "has no compose.dev.yml") {
+		t.Fatalf("up without compose file = %v", err)
+	}
+}
+
+func TestResolveWorkspace(t *testing.T) {
+	rt, dir := workspaceRuntime(t)
+	loose := filepath.Join(t.TempDir(), "loose")
+	if err := os.MkdirAll(filepath.Join(loose, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(loose, workspaceComposeFile), []byte("services: {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		cwd      string
+		args     []string
+		name     string
+		path     string
+		errMatch string
+	}{
+		{cwd: t.TempDir(), args: []string{"demo"}, name: "demo", path: dir},
+		{cwd: dir, name: "demo", path: dir},
+		{cwd: filepath.Join(dir, "code", "src"), name: "demo", path: dir},
+		{cwd: filepath.Join(loose, "sub"), name: "loose", path: loose},
+		{cwd: t.TempDir(), args: []string{"missing"}, errMatch: "missing"},
+		{cwd: t.TempDir(), errMatch: "not inside a workspace"},
+	}
+	for _, tt := range tests {
+		chdir(t, tt.cwd)
+		e, err := resolveWorkspace(rt, tt.args)
+		if tt.errMatch != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.errMatch) {
+				t.Errorf("resolveWorkspace(%v) in %s = %+v, %v; want %q", tt.args, tt.cwd, e, err, tt.errMatch)
+			}
+			continue
+		}
+		if err != nil || e.Name != tt.name || e.Path != tt.path {
+			t.Errorf("resolveWorkspace(%v) in %s = %+v, %v; want %s at %s", tt.args, tt.cwd, e, err, tt.name, tt.path)
+		}
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveWorkspace(rt, []string{"demo"}); err == nil || !strings.Contains(err.Error(), "no longer exists") {
+		t.Fatalf("resolveWorkspace of a removed directory = %v", err)
+	}
+}
+
+// serveComposeContainers fakes a daemon listing containers for the compose
+// working directory dir.
+func serveComposeContainers(t *testing.T, dir string, containers []docker.Container) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v"+docker.APIVersion+"/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		var filters docker.Filters
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("all") != "1" || !slices.Equal(filters["label"], []string{docker.ComposeWorkingDirLabel + "=" + dir}) {
+			json.NewEncoder(w).Encode([]docker.Container{})
+			return
+		}
+		json.NewEncoder(w).Encode(containers)
+	})
+	host := serveDockerAPI(t, mux)
+	saved := newDockerClient
+	newDockerClient = func(*core.Runtime) (*docker.Client, error) { return docker.NewClient(host) }
+	t.Cleanup(func() { newDockerClient = saved })
+}
+
+func TestWorkspaceStatus(t *testing.T) {
+	rt, dir := workspaceRuntime(t)
+	serveComposeContainers(t, dir, []docker.Container{{
+		ID: "c1", Names: []string{"/demo-dev"}, Image: "homekit/go:1", State: "running", Status: "Up 2 minutes",
+		Labels: map[string]string{docker.ComposeServiceLabel: "demo-dev", docker.ComposeWorkingDirLabel: dir},
+	}})
+
+	out, err := runWorkspace(t, rt, "status", "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "NAME      SERVICE   IMAGE         STATE    STATUS\n" +
+		"demo-dev  demo-dev  homekit/go:1  running  Up 2 minutes\n"
+	if out != want {
+		t.Fatalf("status:\n%s\nwant:\n%s", out, want)
+	}
+
+	out, err = runWorkspace(t, rt, "status", "demo", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var containers []docker.Container
+	if err := json.Unmarshal([]byte(out), &containers); err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 1 || containers[0].ID != "c1" {
+		t.Fatalf("status -o json = %s", out)
+	}
+
+	if _, err := runWorkspace(t, rt, "status", "demo", "-o", "yaml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestWorkspaceStatusEmpty(t *testing.T) {
+	rt, _ := workspaceRuntime(t)
+	serveComposeContainers(t, "/elsewhere", nil)
+
+	out, err := runWorkspace(t, rt, "status", "demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "workspace demo has no containers (see `workspace up`)\n" {
+		t.Fatalf("status = %q", out)
+	}
+	out, err = runWorkspace(t, rt, "status", "demo", "-o", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(out) != "[]" {
+		t.Fatalf("status -o json = %q", out)
+	}
+}
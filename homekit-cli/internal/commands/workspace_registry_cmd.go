@@ -0,0 +1,331 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/assets"
+	"github.com/homekit/homekit-cli/internal/core"
+	executor "github.com/homekit/homekit-cli/internal/exec"
+	"github.com/homekit/homekit-cli/internal/ui"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+const (
+	// workspaceComposeFile is the compose file generated for every workspace.
+	workspaceComposeFile = "compose.dev.yml"
+	// workspaceCodeDir holds the workspace's code, cloned with --repo.
+	workspaceCodeDir = "code"
+)
+
+func newWorkspaceListCommand() *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Args:  cobra.NoArgs,
+		Short: "List registered workspaces",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			registry, err := loadWorkspaceRegistry(rt)
+			if err != nil {
+				return err
+			}
+			entries := registry.List()
+
+			if output == "json" {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(entries)
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tTYPE\tCREATED\tPATH")
+			for _, e := range entries {
+				path := e.Path
+				if !e.Exists() {
+					path += " (missing)"
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Name, e.Type, e.CreatedAt.Local().Format(time.DateTime), path)
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	return cmd
+}
+
+func newWorkspaceInfoCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "info <name>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Show details about a registered workspace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			registry, err := loadWorkspaceRegistry(rt)
+			if err != nil {
+				return err
+			}
+			e, err := registry.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintf(tw, "name:\t%s\n", e.Name)
+			fmt.Fprintf(tw, "type:\t%s\n", e.Type)
+			fmt.Fprintf(tw, "path:\t%s\n", e.Path)
+			fmt.Fprintf(tw, "created:\t%s\n", e.CreatedAt.Local().Format(time.RFC3339))
+			fmt.Fprintf(tw, "exists:\t%t\n", e.Exists())
+			if e.Exists() {
+				for _, name := range []string{"README.md", "Makefile", workspaceComposeFile, "code"} {
+					_, statErr := os.Stat(filepath.Join(e.Path, name))
+					fmt.Fprintf(tw, "%s:\t%s\n", name, presence(statErr == nil))
+				}
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func newWorkspaceRmCommand() *cobra.Command {
+	var yes, keepFiles, force bool
+
+	cmd := &cobra.Command{
+		Use:   "rm <name>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Stop a workspace, delete its generated files and unregister it",
+		Long: `Stop a workspace with docker compose down, delete the files homekit
+generated in its directory (the skeleton, an empty code/ and exported
+devcontainer files), remove the directory once it is empty and unregister the
+workspace. When the directory holds anything else, including a repository
+cloned into code/, nothing is deleted and the leftover files are listed;
+--keep-files only stops and unregisters.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			registry, err := loadWorkspaceRegistry(rt)
+			if err != nil {
+				return err
+			}
+			e, err := registry.Get(args[0])
+			if err != nil {
+				return err
+			}
+
+			var removal workspace.Removal
+			if e.Exists() && !keepFiles {
+				removal, err = workspace.PlanRemoval(e.Path, workspaceGeneratedFiles(rt, e))
+				if err != nil {
+					return err
+				}
+				if len(removal.Leftover) > 0 {
+					return fmt.Errorf("%w (delete them first, or use --keep-files to only unregister)", removal.LeftoverError())
+				}
+			}
+
+			if !yes {
+				question := fmt.Sprintf("Remove workspace %s and delete %s with the %d file(s) homekit generated in it?", e.Name, e.Path, len(removal.Paths))
+				if keepFiles || !e.Exists() {
+					question = fmt.Sprintf("Stop and unregister workspace %s (files are kept)?", e.Name)
+				}
+				prompter := ui.Prompter{In: cmd.InOrStdin(), Out: cmd.OutOrStdout()}
+				ok, err := prompter.Confirm(question, false)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					rt.Logger.Info().Msg("Aborted")
+					return nil
+				}
+			}
+
+			if e.Exists() {
+				if err := composeDown(cmd, rt, e.Path); err != nil {
+					if !force {
+						return fmt.Errorf("stop workspace %s: %w (use --force to remove anyway)", e.Name, err)
+					}
+					rt.Logger.Warn().Err(err).Msg("docker compose down failed, continuing")
+				}
+				if !keepFiles {
+					if rt.DryRun {
+						for _, p := range removal.Paths {
+							rt.Logger.Info().Msgf("Would delete %s", filepath.Join(e.Path, filepath.FromSlash(p)))
+						}
+						rt.Logger.Info().Msgf("Would delete %s", e.Path)
+					} else if err := removal.Apply(); err != nil {
+						return err
+					}
+				}
+			}
+
+			if rt.DryRun {
+				rt.Logger.Info().Msgf("Would unregister workspace %s", e.Name)
+				return nil
+			}
+			if err := registry.Remove(e.Name); err != nil {
+				return err
+			}
+			if err := registry.Save(); err != nil {
+				return err
+			}
+			rt.Logger.Info().Msgf("Workspace %s removed", e.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().BoolVar(&keepFiles, "keep-files", false, "Only stop and unregister; keep the workspace directory")
+	cmd.Flags().BoolVar(&force, "force", false, "Continue even if docker compose down fails")
+	return cmd
+}
+
+func newWorkspacePruneCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "prune",
+		Args:  cobra.NoArgs,
+		Short: "Unregister workspaces whose directories no longer exist",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, err := runtimeFrom(cmd)
+			if err != nil {
+				return err
+			}
+			registry, err := loadWorkspaceRegistry(rt)
+			if err != nil {
+				return err
+			}
+
+			var pruned int
+			for _, e := range registry.List() {
+				if e.Exists() {
+					continue
+				}
+				pruned++
+				if rt.DryRun {
+					fmt.Fprintf(cmd.OutOrStdout(), "would prune %s (%s)\n", e.Name, e.Path)
+					continue
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "pruned %s (%s)\n", e.Name, e.Path)
+				if err := registry.Remove(e.Name); err != nil {
+					return err
+				}
+			}
+			if pruned == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "nothing to prune")
+				return nil
+			}
+			if rt.DryRun {
+				return nil
+			}
+			return registry.Save()
+		},
+	}
+}
+
+func loadWorkspaceRegistry(rt *core.Runtime) (*workspace.Registry, error) {
+	dir, err := rt.Config.StateDirectory()
+	if err != nil {
+		return nil, fmt.Errorf("resolve state directory: %w", err)
+	}
+	return workspace.LoadRegistry(dir)
+}
+
+// workspaceGeneratedFiles returns the files homekit generated for e.
+// Workspaces registered before they were recorded get the skeleton files,
+// the devcontainer file and the extra files of their type.
+func workspaceGeneratedFiles(rt *core.Runtime, e workspace.Entry) []string {
+	if e.Files != nil {
+		// earlier versions recorded a cloned code/ as a whole tree; it may
+		// hold local work, so only the empty directory is deleted
+		files := slices.Clone(e.Files)
+		for i, f := range files {
+			if f == workspaceCodeDir+"/" {
+				files[i] = workspaceCodeDir
+			}
+		}
+		return files
+	}
+	files := []string{"README.md", "Makefile", workspaceComposeFile, workspace.DevcontainerFile, path.Dir(workspace.DevcontainerFile), workspaceCodeDir}
+	manager := assets.NewManager(assets.Embedded(), overrideDirectory(rt.Config))
+	if types, err := loadWorkspaceTypes(manager); err == nil {
+		if spec, err := types.Lookup(e.Type); err == nil {
+			for _, f := range spec.Files {
+				files = append(files, f.Dest)
+			}
+		}
+	}
+	return files
+}
+
+func registerWorkspace(rt *core.Runtime, e workspace.Entry) error {
+	if rt.DryRun {
+		return nil
+	}
+	registry, err := loadWorkspaceRegistry(rt)
+	if err != nil {
+		return err
+	}
+	if existing, ok := registry.FindByPath(e.Path); ok && existing.Name == e.Name {
+		// creating a workspace again keeps what earlier runs generated
+		for _, f := range existing.Files {
+			if !slices.Contains(e.Files, f) {
+				e.Files = append(e.Files, f)
+			}
+		}
+	}
+	if err := registry.Add(e); err != nil {
+		return err
+	}
+	return registry.Save()
+}
+
+// recordGeneratedFile adds rel to the files homekit generated for e.
+func recordGeneratedFile(rt *core.Runtime, e workspace.Entry, rel string) error {
+	if rt.DryRun || e.Files == nil || slices.Contains(e.Files, rel) {
+		return nil
+	}
+	e.Files = append(e.Files, rel)
+	return registerWorkspace(rt, e)
+}
+
+// composeDown stops the workspace containers when a compose file is present.
+func composeDown(cmd *cobra.Command, rt *core.Runtime, dir string) error {
+	if _, err := os.Stat(filepath.Join(dir, workspaceComposeFile)); err != nil {
+		return nil
+	}
+	res, err := executor.Run(cmd.Context(), executor.Spec{
+		Command:       "docker",
+		Args:          []string{"compose", "-f", workspaceComposeFile, "down"},
+		Dir:           dir,
+		CaptureOutput: true,
+		DryRun:        rt.DryRun,
+	})
+	if err != nil && res.Stderr != "" {
+		return fmt.Errorf("%w: %s", err, res.Stderr)
+	}
+	return err
+}
+
+func presence(ok bool) string {
+	if ok {
+		return "present"
+	}
+	return "missing"
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+func TestWorkspacePrune(t *testing.T) {
+	rt, _ := workspaceRuntime(t)
+	gone := filepath.Join(t.TempDir(), "gone")
+	registry, err := workspace.LoadRegistry(rt.Config.StateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Add(workspace.Entry{Name: "gone", Path: gone}); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Save(); err != nil {
+		t.Fatal(err)
+	}
+	registered := func() []string {
+		t.Helper()
+		registry, err := workspace.LoadRegistry(rt.Config.StateDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range registry.List() {
+			names = append(names, e.Name)
+		}
+		return names
+	}
+
+	rt.DryRun = true
+	out, err := runWorkspace(t, rt, "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "would prune gone (" + gone + ")\n"; out != want {
+		t.Fatalf("dry run printed %q, want %q", out, want)
+	}
+	if names := registered(); len(names) != 2 {
+		t.Fatalf("dry run changed the registry: %v", names)
+	}
+
+	rt.DryRun = false
+	out, err = runWorkspace(t, rt, "prune")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "pruned gone (" + gone + ")\n"; out != want {
+		t.Fatalf("prune printed %q, want %q", out, want)
+	}
+	if names := registered(); len(names) != 1 || names[0] != "demo" {
+		t.Fatalf("registry after prune: %v", names)
+	}
+	if out, err := runWorkspace(t, rt, "prune"); err != nil || out != "nothing to prune\n" {
+		t.Fatalf("second prune = %q, %v", out, err)
+	}
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/homekit/homekit-cli/internal/core"
+	"github.com/homekit/homekit-cli/internal/ui"
+	"github.com/homekit/homekit-cli/internal/workspace"
+)
+
+func newWorkspaceSnapshotCommand() *cobra.Command {
+	var keep int
+
+	cmd := &cobra.Command{
+		Use:   "snapshot <name>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Archive a workspace directory with a checksum manifest",
+		Long: `Archive the workspace directory into a gzip-compressed tar stored under
+<state_dir>/snapshots/<name>. Paths matching patterns in the workspace's
+.homekitignore are excluded. With --keep, older snapshots beyond the
+retention count are deleted afterwards.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, e, store, err := workspaceSnapshotTarget(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			if !e.Exists() {
+				return fmt.Errorf("workspace %s: directory %s is missing", e.Name, e.Path)
+			}
+			if rt.DryRun {
+				rt.Logger.Info().Msgf("Would snapshot %s", e.Path)
+				return nil
+			}
+
+			snap, err := store.Create(e.Name, e.Path, time.Now())
+			if err != nil {
+				return err
+			}
+			rt.Logger.Info().Msgf("Snapshot %s created (%s) in %s", snap.ID, ui.HumanBytes(snap.Size), snap.Archive)
+
+			if cmd.Flags().Changed("keep") {
+				return pruneWorkspaceSnapshots(rt, store, e.Name, keep)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&keep, "keep", 0, "Keep only the newest N snapshots after creating one")
+	return cmd
+}
+
+func newWorkspaceSnapshotsCommand() *cobra.Command {
+	var (
+		output string
+		keep   int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "snapshots <name>",
+		Args:  cobra.ExactArgs(1),
+		Short: "List a workspace's snapshots, or prune them with --keep",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, e, store, err := workspaceSnapshotTarget(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("keep") {
+				return pruneWorkspaceSnapshots(rt, store, e.Name, keep)
+			}
+
+			snaps, err := store.List(e.Name)
+			if err != nil {
+				return err
+			}
+			if output == "json" {
+				if snaps == nil {
+					snaps = []workspace.Snapshot{}
+				}
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(snaps)
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tCREATED\tSIZE")
+			for _, s := range snaps {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", s.ID, s.CreatedAt.Local().Format(time.DateTime), ui.HumanBytes(s.Size))
+			}
+			return tw.Flush()
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	cmd.Flags().IntVar(&keep, "keep", 0, "Delete all but the newest N snapshots")
+	return cmd
+}
+
+func newWorkspaceRestoreCommand() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "restore <name> <snapshot|latest>",
+		Args:  cobra.ExactArgs(2),
+		Short: "Verify a snapshot and extract it over the workspace directory",
+		Long: `Verify the snapshot archive and every file against its manifest, then
+extract it over the workspace directory. Files that are not part of the
+snapshot are left in place. A failed extraction is rolled back.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rt, e, store, err := workspaceSnapshotTarget(cmd, args[0])
+			if err != nil {
+				return err
+			}
+			snap, err := store.Get(e.Name, args[1])
+			if err != nil {
+				return err
+			}
+			if _, err := store.Verify(snap); err != nil {
+				return fmt.Errorf("verify snapshot %s: %w", snap.ID, err)
+			}
+			rt.Logger.Info().Msgf("Snapshot %s verified", snap.ID)
+			if rt.DryRun {
+				rt.Logger.Info().Msgf("Would restore %s into %s", snap.ID, e.Path)
+				return nil
+			}
+
+			if !yes {
+				prompter := ui.Prompter{In: cmd.InOrStdin(), Out: cmd.OutOrStdout()}
+				ok, err := prompter.Confirm(fmt.Sprintf("Restore snapshot %s over %s, overwriting changed files?", snap.ID, e.Path), false)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					rt.Logger.Info().Msg("Aborted")
+					return nil
+				}
+			}
+
+			if err := store.Restore(snap, e.Path, rt.Config.TempDirectory()); err != nil {
+				return fmt.Errorf("restore snapshot %s: %w", snap.ID, err)
+			}
+			rt.Logger.Info().Msgf("Restored %s into %s", snap.ID, e.Path)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Do not ask for confirmation")
+	return cmd
+}
+
+// workspaceSnapshotTarget resolves a registered workspace and its snapshot store.
+func workspaceSnapshotTarget(cmd *cobra.Command, name string) (*core.Runtime, workspace.Entry, *workspace.SnapshotStore, error) {
+	rt, err := runtimeFrom(cmd)
+	if err != nil {
+		return nil, workspace.Entry{}, nil, err
+	}
+	registry, err := loadWorkspaceRegistry(rt)
+	if err != nil {
+		return nil, workspace.Entry{}, nil, err
+	}
+	e, err := registry.Get(name)
+	if err != nil {
+		return nil, workspace.Entry{}, nil, err
+	}
+	stateDir, err := rt.Config.StateDirectory()
+	if err != nil {
+		return nil, workspace.Entry{}, nil, fmt.Errorf("resolve state directory: %w", err)
+	}
+	return rt, e, workspace.NewSnapshotStore(stateDir), nil
+}
+
+func pruneWorkspaceSnapshots(rt *core.Runtime, store *workspace.SnapshotStore, name string, keep int) error {
+	if keep < 0 {
+		return errors.New("--keep must not be negative")
+	}
+	if rt.DryRun {
+		snaps, err := store.List(name)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(snaps)-keep; i++ {
+			rt.Logger.Info().Msgf("Would delete snapshot %s", snaps[i].ID)
+		}
+		return nil
+	}
+	removed, err := store.Prune(name, keep)
+	if err != nil {
+		return err
+	}
+	for _, s := range removed {
+		rt.Logger.Info().Msgf("Deleted snapshot %s", s.ID)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package compose
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity ranks a finding. Errors make a file unusable or break a house
+// rule outright; warnings are worth fixing.
+type Severity string
+
+// Severities.
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Rules reported by Parse, Validate and Lint. Every rule can be disabled
+// with --disable or, per service, with the IgnoreLabel.
+const (
+	RuleInterpolation   = "interpolation"
+	RuleSchema          = "schema"
+	RuleReference       = "reference"
+	RulePorts           = "ports"
+	RuleVolumes         = "volumes"
+	RuleNetworks        = "networks"
+	RuleDependencyCycle = "dependency-cycle"
+
+	RuleVersion     = "version"
+	RuleImageTag    = "image-tag"
+	RuleHealthcheck = "healthcheck"
+	RuleRestart     = "restart"
+	RulePrivileged  = "privileged"
+)
+
+// Rules lists every rule with a short description.
+var Rules = []struct{ Name, Description string }{
+	{RuleInterpolation, "variables used without a value or default"},
+	{RuleSchema, "unknown top-level keys, services without image or build"},
+	{RuleReference, "depends_on, links, volumes_from, extends and network_mode name known services"},
+	{RulePorts, "port syntax, ranges and host ports published twice"},
+	{RuleVolumes, "mount syntax and undeclared named volumes"},
+	{RuleNetworks, "undeclared networks"},
+	{RuleDependencyCycle, "depends_on cycles"},
+	{RuleVersion, "the obsolete top-level version key"},
+	{RuleImageTag, "images must be pinned to a tag other than latest or a digest"},
+	{RuleHealthcheck, "services must define a healthcheck (or disable it explicitly)"},
+	{RuleRestart, "services must set a restart policy"},
+	{RulePrivileged, "privileged services must explain why in a " + PrivilegedLabel + " label"},
+}
+
+const (
+	// IgnoreLabel lists rules, comma-separated, to skip for a service.
+	IgnoreLabel = "homekit.lint.ignore"
+	// PrivilegedLabel gives the reason a service runs privileged.
+	PrivilegedLabel = "homekit.privileged"
+)
+
+// Finding is one problem in a compose file.
+type Finding struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Service  string   `json:"service,omitempty"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (f Finding) String() string {
+	var b strings.Builder
+	b.WriteString(f.File)
+	if f.Line > 0 {
+		fmt.Fprintf(&b, ":%d", f.Line)
+	}
+	fmt.Fprintf(&b, ": %s: [%s] ", f.Severity, f.Rule)
+	if f.Service != "" {
+		fmt.Fprintf(&b, "service %s: ", f.Service)
+	}
+	b.WriteString(f.Message)
+	return b.String()
+}
+
+// HasErrors reports whether any finding is an error.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Check parses, validates and lints content, dropping findings of the
+// disabled rules. Findings are sorted by line. The error is only set when
+// the file cannot be parsed at all.
+func Check(file string, content []byte, lookup Lookup, disable ...string) ([]Finding, error) {
+	p, findings, err := Parse(file, content, lookup)
+	if err != nil {
+		return nil, err
+	}
+	findings = append(findings, Validate(p)...)
+	findings = append(findings, Lint(p)...)
+
+	disabled := map[string]bool{}
+	for _, rule := range disable {
+		disabled[rule] = true
+	}
+	kept := findings[:0]
+	for _, f := range findings {
+		if disabled[f.Rule] {
+			continue
+		}
+		if s := p.Service(f.Service); s != nil && s.ignores(f.Rule) {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Line < kept[j].Line })
+	return kept, nil
+}
+
+// ignores reports whether the service opts out of rule with the IgnoreLabel.
+func (s *Service) ignores(rule string) bool {
+	for _, r := range strings.Split(s.Labels[IgnoreLabel], ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// finder collects findings for one project.
+type finder struct {
+	file     string
+	findings []Finding
+}
+
+func (f *finder) add(severity Severity, rule string, line int, service, format string, args ...any) {
+	f.findings = append(f.findings, Finding{
+		File: f.file, Line: line, Service: service, Rule: rule,
+		Severity: severity, Message: fmt.Sprintf(format, args...),
+	})
+}
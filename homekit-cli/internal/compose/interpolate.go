@@ -0,0 +1,188 @@
+package compose
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Lookup returns the value of a variable used in ${VAR} interpolation.
+type Lookup func(name string) (string, bool)
+
+// EnvLookup resolves variables like docker compose does: from the process
+// environment first, then from the .env file in dir when there is one.
+func EnvLookup(dir string) (Lookup, error) {
+	dotenv, err := LoadEnvFile(filepath.Join(dir, ".env"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return func(name string) (string, bool) {
+		if v, ok := os.LookupEnv(name); ok {
+			return v, true
+		}
+		v, ok := dotenv[name]
+		return v, ok
+	}, nil
+}
+
+// LoadEnvFile reads KEY=VALUE lines. Blank lines and # comments are skipped,
+// an `export ` prefix is allowed and matching single or double quotes around
+// the value are removed.
+func LoadEnvFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	env := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i])
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+// Interpolate substitutes $VAR and ${VAR} in s, with the compose modifiers
+// ${VAR:-default}, ${VAR-default}, ${VAR:?error}, ${VAR?error},
+// ${VAR:+replacement} and ${VAR+replacement}; $$ is a literal $. Defaults may
+// contain further substitutions. Variables that are unset and have no
+// default are replaced by "" and returned in unset.
+func Interpolate(s string, lookup Lookup) (out string, unset []string, err error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		next := s[i+1]
+		switch {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return "", nil, fmt.Errorf("unterminated ${ in %q", s)
+			}
+			value, missing, err := expandBraced(s[i+2:end], lookup)
+			if err != nil {
+				return "", nil, err
+			}
+			b.WriteString(value)
+			unset = append(unset, missing...)
+			i = end
+		case isNameStart(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			name := s[i+1 : j]
+			value, ok := lookup(name)
+			if !ok {
+				unset = append(unset, name)
+			}
+			b.WriteString(value)
+			i = j - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), unset, nil
+}
+
+// expandBraced expands the inside of ${...}.
+func expandBraced(expr string, lookup Lookup) (string, []string, error) {
+	j := 0
+	for j < len(expr) && isNameChar(expr[j]) {
+		j++
+	}
+	name, rest := expr[:j], expr[j:]
+	if name == "" || !isNameStart(name[0]) {
+		return "", nil, fmt.Errorf("invalid variable name in ${%s}", expr)
+	}
+	value, set := lookup(name)
+	if rest == "" {
+		if !set {
+			return "", []string{name}, nil
+		}
+		return value, nil, nil
+	}
+
+	op, arg := rest[:1], rest[1:]
+	nonEmpty := false
+	if op == ":" && len(rest) > 1 {
+		nonEmpty = true
+		op, arg = rest[1:2], rest[2:]
+	}
+	present := set && (!nonEmpty || value != "")
+	switch op {
+	case "-":
+		if present {
+			return value, nil, nil
+		}
+		return Interpolate(arg, lookup)
+	case "+":
+		if present {
+			return Interpolate(arg, lookup)
+		}
+		return "", nil, nil
+	case "?":
+		if present {
+			return value, nil, nil
+		}
+		msg, _, err := Interpolate(arg, lookup)
+		if err != nil {
+			return "", nil, err
+		}
+		if msg == "" {
+			msg = "required variable is not set"
+		}
+		return "", nil, fmt.Errorf("%s: %s", name, msg)
+	}
+	return "", nil, fmt.Errorf("invalid substitution ${%s}", expr)
+}
+
+// matchingBrace returns the index of the } closing the { at open.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,97 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mapLookup(env map[string]string) Lookup {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := mapLookup(map[string]string{"EMPTY": "", "TAG": "1.2", "HOST": "db"})
+	tests := []struct {
+		in, want string
+		unset    []string
+		err      string
+	}{
+		{in: "plain", want: "plain"},
+		{in: "$TAG-${TAG}", want: "1.2-1.2"},
+		{in: "$$TAG costs $5$", want: "$TAG costs $5$"},
+		{in: "$MISSING/${MISSING}", want: "/", unset: []string{"MISSING", "MISSING"}},
+		{in: "${EMPTY:-def}", want: "def"},
+		{in: "${EMPTY-def}", want: ""},
+		{in: "${MISSING-def}", want: "def"},
+		{in: "${TAG:-def}", want: "1.2"},
+		{in: "${MISSING:-${HOST:-x}}", want: "db"},
+		{in: "${MISSING:-${OTHER:-x}}", want: "x"},
+		{in: "${MISSING:-$OTHER}", want: "", unset: []string{"OTHER"}},
+		{in: "${EMPTY:+set}", want: ""},
+		{in: "${EMPTY+set}", want: "set"},
+		{in: "${TAG:+v$TAG}", want: "v1.2"},
+		{in: "${MISSING+set}", want: ""},
+		{in: "${EMPTY?unused}", want: ""},
+		{in: "${EMPTY:?need a value}", err: "EMPTY: need a value"},
+		{in: "${MISSING?}", err: "MISSING: required variable is not set"},
+		{in: "${MISSING:?$HOST is down}", err: "MISSING: db is down"},
+		{in: "image:${TAG", err: "unterminated ${"},
+		{in: "${MISSING:-${TAG}", err: "unterminated ${"},
+		{in: "${1TAG}", err: "invalid variable name"},
+		{in: "${TAG/x}", err: "invalid substitution"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, unset, err := Interpolate(tt.in, lookup)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want || !reflect.DeepEqual(unset, tt.unset) {
+				t.Fatalf("Interpolate(%q) = %q, %v; want %q, %v", tt.in, got, unset, tt.want, tt.unset)
+			}
+		})
+	}
+}
+
+func TestEnvLookup(t *testing.T) {
+	dir := t.TempDir()
+	dotenv := "# comment\nexport A=from-file\nB=\"quoted # kept\"\nC=value # comment\n\nD='single'\n"
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte(dotenv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("A", "from-env")
+	lookup, err := EnvLookup(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"A": "from-env", "B": "quoted # kept", "C": "value", "D": "single"} {
+		if got, ok := lookup(name); !ok || got != want {
+			t.Errorf("%s = %q, %v; want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := lookup("HOMEKIT_TEST_UNSET"); ok {
+		t.Error("unset variable reported as set")
+	}
+
+	if _, err := EnvLookup(t.TempDir()); err != nil {
+		t.Fatalf("missing .env: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("NOEQUALS\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EnvLookup(dir); err == nil || !strings.Contains(err.Error(), ".env:1") {
+		t.Fatalf("error = %v, want a line number", err)
+	}
+}
@@ -0,0 +1,49 @@
+package compose
+
+import "strings"
+
+// Lint checks the house rules: no obsolete version key, pinned image tags,
+// a healthcheck and a restart policy on every service, and a reason label on
+// privileged services.
+func Lint(p *Project) []Finding {
+	f := &finder{file: p.File}
+	if p.Version != "" {
+		f.add(SeverityWarning, RuleVersion, p.VersionLine, "", "version %q is obsolete and ignored by docker compose; remove it", p.Version)
+	}
+	for _, s := range p.Services {
+		if s.Image != "" && !s.Build {
+			if problem := imageTagProblem(s.Image); problem != "" {
+				f.add(SeverityWarning, RuleImageTag, s.Line, s.Name, "image %s %s", s.Image, problem)
+			}
+		}
+		if s.Healthcheck == nil && s.Extends == "" {
+			f.add(SeverityWarning, RuleHealthcheck, s.Line, s.Name, "no healthcheck (set healthcheck.disable: true to opt out)")
+		}
+		if s.Restart == "" && s.RestartPolicy == "" && s.Extends == "" {
+			f.add(SeverityWarning, RuleRestart, s.Line, s.Name, "no restart policy (restart: unless-stopped is usual)")
+		}
+		if s.Privileged && strings.TrimSpace(s.Labels[PrivilegedLabel]) == "" {
+			f.add(SeverityError, RulePrivileged, s.Line, s.Name, "runs privileged without a %s label giving the reason", PrivilegedLabel)
+		}
+	}
+	return f.findings
+}
+
+// imageTagProblem explains why an image reference is not pinned, or returns
+// "".
+func imageTagProblem(image string) string {
+	if strings.Contains(image, "@") {
+		return ""
+	}
+	// a colon after the last slash separates the tag; one before it is a
+	// registry port
+	name := image[strings.LastIndex(image, "/")+1:]
+	_, tag, ok := strings.Cut(name, ":")
+	switch {
+	case !ok:
+		return "has no tag and resolves to latest"
+	case tag == "latest":
+		return "uses the latest tag"
+	}
+	return ""
+}
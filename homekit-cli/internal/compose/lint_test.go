@@ -0,0 +1,121 @@
+package compose
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestImageTagProblem(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                          "has no tag and resolves to latest",
+		"nginx:latest":                   "uses the latest tag",
+		"nginx:1.27":                     "",
+		"library/nginx:1.27-alpine":      "",
+		"registry:5000/img":              "has no tag and resolves to latest",
+		"registry:5000/img:2":            "",
+		"registry:5000/team/img:latest":  "uses the latest tag",
+		"nginx@sha256:0123456789abcdef":  "",
+		"registry:5000/img@sha256:01234": "",
+	}
+	for image, want := range tests {
+		if got := imageTagProblem(image); got != want {
+			t.Errorf("imageTagProblem(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name, content string
+		want          []string
+	}{
+		{"clean", `
+services:
+  web:
+    image: web:1
+    restart: unless-stopped
+    healthcheck: {test: curl -f http://localhost}
+  job:
+    build: .
+    deploy: {restart_policy: {}}
+    healthcheck: {disable: true}
+    privileged: true
+    labels: ["homekit.privileged=needs /dev/kvm"]
+  child: {extends: web}
+`, nil},
+		{"version", `
+version: "3.8"
+services:
+  web: {image: web:1, restart: always, healthcheck: {disable: true}}
+`, []string{"version"}},
+		{"image tag", `
+services:
+  a: {image: "registry:5000/img", restart: always, healthcheck: {disable: true}}
+  b: {image: "b:latest", restart: always, healthcheck: {disable: true}}
+`, []string{"image-tag", "image-tag"}},
+		{"healthcheck and restart", `
+services:
+  web: {image: web:1}
+`, []string{"healthcheck", "restart"}},
+		{"privileged", `
+services:
+  web:
+    image: web:1
+    restart: always
+    healthcheck: {disable: true}
+    privileged: true
+    labels: {homekit.privileged: " "}
+`, []string{"privileged"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _, err := Parse("compose.yaml", []byte(tt.content), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range Lint(p) {
+				got = append(got, f.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("rules %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	content := `
+services:
+  web:
+    image: web
+    labels:
+      homekit.lint.ignore: "healthcheck, image-tag"
+  db:
+    image: db:1
+    privileged: true
+    volumes: [data:/data]
+`
+	findings, err := Check("compose.yaml", []byte(content), nil, RuleRestart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.String())
+	}
+	want := []string{
+		"compose.yaml:7: warning: [healthcheck] service db: no healthcheck (set healthcheck.disable: true to opt out)",
+		"compose.yaml:7: error: [privileged] service db: runs privileged without a homekit.privileged label giving the reason",
+		`compose.yaml:10: error: [volumes] service db: volume "data" is not declared under top-level volumes`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("findings %q, want %q", got, want)
+	}
+	if !HasErrors(findings) || HasErrors(findings[:1]) {
+		t.Fatal("HasErrors does not follow the severities")
+	}
+	if _, err := Check("compose.yaml", []byte("[]"), nil); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
@@ -0,0 +1,466 @@
+// Package compose parses docker compose files into a small model, validates
+// references, ports and volumes, and lints them against house rules.
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// composeFilePattern matches compose file names such as compose.yaml,
+// docker-compose.yml and compose.dev.yml.
+var composeFilePattern = regexp.MustCompile(`^(docker-)?compose(\.[A-Za-z0-9_-]+)*\.ya?ml$`)
+
+// IsComposeFile reports whether a file name looks like a compose file. A
+// trailing .tmpl is ignored so templates can be recognised too.
+func IsComposeFile(name string) bool {
+	return composeFilePattern.MatchString(strings.TrimSuffix(filepath.Base(name), ".tmpl"))
+}
+
+// NormalizeProjectName turns a name into a valid compose project name the
+// way docker compose does for directory names: lowercased, stripped of
+// characters outside [a-z0-9_-] and of leading '_' and '-'.
+func NormalizeProjectName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(b.String(), "_-")
+}
+
+// topLevelKeys are the keys compose accepts at the top of a file, besides
+// x- extensions.
+var topLevelKeys = map[string]bool{
+	"version": true, "name": true, "include": true, "services": true,
+	"networks": true, "volumes": true, "secrets": true, "configs": true,
+}
+
+// Project is a parsed compose file after interpolation. Anchors, aliases and
+// merge keys are resolved.
+type Project struct {
+	File string `json:"file"`
+	Name string `json:"name,omitempty"`
+	// Version is the obsolete top-level version, kept for linting.
+	Version     string              `json:"version,omitempty"`
+	VersionLine int                 `json:"-"`
+	Services    []*Service          `json:"services"`
+	Networks    map[string]Resource `json:"networks,omitempty"`
+	Volumes     map[string]Resource `json:"volumes,omitempty"`
+	Secrets     map[string]Resource `json:"secrets,omitempty"`
+	Configs     map[string]Resource `json:"configs,omitempty"`
+	// Extensions holds the top-level x- keys.
+	Extensions map[string]any `json:"extensions,omitempty"`
+	// unknown lists other top-level keys with their lines.
+	unknown map[string]int
+}
+
+// Resource is a top-level network, volume, secret or config.
+type Resource struct {
+	Name     string `json:"name,omitempty"`
+	External bool   `json:"external,omitempty"`
+	Line     int    `json:"-"`
+}
+
+// Service is one entry of services.
+type Service struct {
+	Name          string            `json:"name"`
+	Line          int               `json:"line"`
+	Image         string            `json:"image,omitempty"`
+	ContainerName string            `json:"container_name,omitempty"`
+	Build         bool              `json:"build,omitempty"`
+	Extends       string            `json:"extends,omitempty"`
+	Ports         []Port            `json:"ports,omitempty"`
+	Volumes       []Mount           `json:"volumes,omitempty"`
+	DependsOn     []string          `json:"depends_on,omitempty"`
+	Links         []string          `json:"links,omitempty"`
+	VolumesFrom   []string          `json:"volumes_from,omitempty"`
+	Networks      []string          `json:"networks,omitempty"`
+	NetworkMode   string            `json:"network_mode,omitempty"`
+	Healthcheck   *Healthcheck      `json:"healthcheck,omitempty"`
+	Restart       string            `json:"restart,omitempty"`
+	RestartPolicy string            `json:"restart_policy,omitempty"`
+	Privileged    bool              `json:"privileged,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	// Extensions holds the service's x- keys.
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Healthcheck is the part of a service healthcheck the rules look at.
+type Healthcheck struct {
+	Test    []string `json:"test,omitempty"`
+	Disable bool     `json:"disable,omitempty"`
+}
+
+// Port is a port mapping in short ("127.0.0.1:8080:80/tcp") or long syntax.
+type Port struct {
+	Raw       string `json:"raw"`
+	HostIP    string `json:"host_ip,omitempty"`
+	Published string `json:"published,omitempty"`
+	Target    string `json:"target"`
+	Protocol  string `json:"protocol"`
+	Line      int    `json:"-"`
+	// Err describes why the mapping is invalid.
+	Err string `json:"-"`
+}
+
+// Mount is a service volume in short ("data:/var/lib/data:ro") or long
+// syntax.
+type Mount struct {
+	Raw      string `json:"raw"`
+	Type     string `json:"type"`
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target"`
+	ReadOnly bool   `json:"read_only,omitempty"`
+	Line     int    `json:"-"`
+	Err      string `json:"-"`
+}
+
+// Mount types.
+const (
+	MountVolume = "volume"
+	MountBind   = "bind"
+	MountTmpfs  = "tmpfs"
+)
+
+// Service returns the service called name, or nil.
+func (p *Project) Service(name string) *Service {
+	for _, s := range p.Services {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Load reads and parses a compose file, resolving variables with lookup.
+func Load(path string, lookup Lookup) (*Project, []Finding, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return Parse(path, content, lookup)
+}
+
+// Parse parses compose YAML. Variables are interpolated in every value
+// before the model is built; unset variables are reported as findings and
+// a failing ${VAR:?message} is an error. A nil lookup leaves values alone.
+func Parse(file string, content []byte, lookup Lookup) (*Project, []Finding, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", file, err)
+	}
+	if len(root.Content) == 0 {
+		return nil, nil, fmt.Errorf("parse %s: empty document", file)
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("parse %s: line %d: top level must be a mapping", file, doc.Line)
+	}
+
+	var findings []Finding
+	if lookup != nil {
+		var err error
+		if findings, err = interpolateNode(file, doc, lookup, map[*yaml.Node]bool{}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	p := &Project{File: file, unknown: map[string]int{}}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		key, value := doc.Content[i], doc.Content[i+1]
+		var err error
+		switch k := key.Value; {
+		case strings.HasPrefix(k, "x-"):
+			if p.Extensions == nil {
+				p.Extensions = map[string]any{}
+			}
+			var ext any
+			err = value.Decode(&ext)
+			p.Extensions[k] = ext
+		case k == "version":
+			p.Version, p.VersionLine = value.Value, key.Line
+		case k == "name":
+			p.Name = value.Value
+		case k == "services":
+			err = p.parseServices(value)
+		case k == "networks":
+			p.Networks, err = parseResources(value)
+		case k == "volumes":
+			p.Volumes, err = parseResources(value)
+		case k == "secrets":
+			p.Secrets, err = parseResources(value)
+		case k == "configs":
+			p.Configs, err = parseResources(value)
+		case !topLevelKeys[k]:
+			p.unknown[k] = key.Line
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse %s: %s: %w", file, key.Value, err)
+		}
+	}
+	sort.Slice(p.Services, func(i, j int) bool { return p.Services[i].Name < p.Services[j].Name })
+	return p, findings, nil
+}
+
+// interpolateNode substitutes variables in every scalar value below n.
+// Anchored nodes are visited once; aliases share them.
+func interpolateNode(file string, n *yaml.Node, lookup Lookup, seen map[*yaml.Node]bool) ([]Finding, error) {
+	if n == nil || seen[n] {
+		return nil, nil
+	}
+	seen[n] = true
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "$") {
+			return nil, nil
+		}
+		value, unset, err := Interpolate(n.Value, lookup)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, n.Line, err)
+		}
+		var findings []Finding
+		for _, name := range unset {
+			findings = append(findings, Finding{
+				File: file, Line: n.Line, Rule: RuleInterpolation, Severity: SeverityWarning,
+				Message: fmt.Sprintf("variable %s is not set, using an empty string", name),
+			})
+		}
+		if value != n.Value {
+			// let the new value resolve to its own type, as compose casts after interpolation
+			n.Value, n.Tag = value, ""
+		}
+		return findings, nil
+	case yaml.MappingNode:
+		var findings []Finding
+		for i := 1; i < len(n.Content); i += 2 {
+			found, err := interpolateNode(file, n.Content[i], lookup, seen)
+			if err != nil {
+				return nil, err
+			}
+			findings = append(findings, found...)
+		}
+		return findings, nil
+	case yaml.SequenceNode, yaml.DocumentNode:
+		var findings []Finding
+		for _, c := range n.Content {
+			found, err := interpolateNode(file, c, lookup, seen)
+			if err != nil {
+				return nil, err
+			}
+			findings = append(findings, found...)
+		}
+		return findings, nil
+	}
+	return nil, nil
+}
+
+// rawService is decoded from a service node; yaml.v3 applies merge keys.
+type rawService struct {
+	Image         string      `yaml:"image"`
+	ContainerName string      `yaml:"container_name"`
+	Build         yaml.Node   `yaml:"build"`
+	Extends       yaml.Node   `yaml:"extends"`
+	Ports         []yaml.Node `yaml:"ports"`
+	Volumes       []yaml.Node `yaml:"volumes"`
+	DependsOn     yaml.Node   `yaml:"depends_on"`
+	Links         []string    `yaml:"links"`
+	VolumesFrom   []string    `yaml:"volumes_from"`
+	Networks      yaml.Node   `yaml:"networks"`
+	NetworkMode   string      `yaml:"network_mode"`
+	Healthcheck   *struct {
+		Test    yaml.Node `yaml:"test"`
+		Disable bool      `yaml:"disable"`
+	} `yaml:"healthcheck"`
+	Restart string `yaml:"restart"`
+	Deploy  struct {
+		RestartPolicy *struct {
+			Condition string `yaml:"condition"`
+		} `yaml:"restart_policy"`
+	} `yaml:"deploy"`
+	Privileged bool           `yaml:"privileged"`
+	Labels     yaml.Node      `yaml:"labels"`
+	Extra      map[string]any `yaml:",inline"`
+}
+
+func (p *Project) parseServices(n *yaml.Node) error {
+	if n.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: must be a mapping", n.Line)
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		var raw rawService
+		if err := value.Decode(&raw); err != nil {
+			return fmt.Errorf("service %s: %w", key.Value, err)
+		}
+		s := &Service{
+			Name:          key.Value,
+			Line:          key.Line,
+			Image:         raw.Image,
+			ContainerName: raw.ContainerName,
+			Build:         !raw.Build.IsZero(),
+			Links:         raw.Links,
+			VolumesFrom:   raw.VolumesFrom,
+			NetworkMode:   raw.NetworkMode,
+			Restart:       raw.Restart,
+			Privileged:    raw.Privileged,
+		}
+		if raw.Deploy.RestartPolicy != nil {
+			s.RestartPolicy = raw.Deploy.RestartPolicy.Condition
+			if s.RestartPolicy == "" {
+				// compose defaults the condition to any
+				s.RestartPolicy = "any"
+			}
+		}
+		switch raw.Extends.Kind {
+		case yaml.ScalarNode:
+			s.Extends = raw.Extends.Value
+		case yaml.MappingNode:
+			var ext struct {
+				Service string `yaml:"service"`
+				File    string `yaml:"file"`
+			}
+			if err := raw.Extends.Decode(&ext); err != nil {
+				return fmt.Errorf("service %s: extends: %w", s.Name, err)
+			}
+			if ext.File == "" {
+				s.Extends = ext.Service
+			} else {
+				// services of other files cannot be checked here
+				s.Build = true
+			}
+		}
+		if raw.Healthcheck != nil {
+			s.Healthcheck = &Healthcheck{Disable: raw.Healthcheck.Disable}
+			switch raw.Healthcheck.Test.Kind {
+			case yaml.ScalarNode:
+				s.Healthcheck.Test = []string{"CMD-SHELL", raw.Healthcheck.Test.Value}
+			case yaml.SequenceNode:
+				if err := raw.Healthcheck.Test.Decode(&s.Healthcheck.Test); err != nil {
+					return fmt.Errorf("service %s: healthcheck.test: %w", s.Name, err)
+				}
+			}
+		}
+		var err error
+		if s.Labels, err = decodeStringMap(&raw.Labels); err != nil {
+			return fmt.Errorf("service %s: labels: %w", s.Name, err)
+		}
+		if s.DependsOn, err = decodeNames(&raw.DependsOn); err != nil {
+			return fmt.Errorf("service %s: depends_on: %w", s.Name, err)
+		}
+		if s.Networks, err = decodeNames(&raw.Networks); err != nil {
+			return fmt.Errorf("service %s: networks: %w", s.Name, err)
+		}
+		for j := range raw.Ports {
+			s.Ports = append(s.Ports, parsePort(&raw.Ports[j]))
+		}
+		for j := range raw.Volumes {
+			s.Volumes = append(s.Volumes, parseMount(&raw.Volumes[j]))
+		}
+		for k, v := range raw.Extra {
+			if strings.HasPrefix(k, "x-") {
+				if s.Extensions == nil {
+					s.Extensions = map[string]any{}
+				}
+				s.Extensions[k] = v
+			}
+		}
+		p.Services = append(p.Services, s)
+	}
+	return nil
+}
+
+// parseResources reads a top-level networks/volumes/secrets/configs map,
+// whose entries may be empty.
+func parseResources(n *yaml.Node) (map[string]Resource, error) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!null" {
+		return map[string]Resource{}, nil
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: must be a mapping", n.Line)
+	}
+	out := map[string]Resource{}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		r := Resource{Line: key.Line}
+		if value.Kind == yaml.MappingNode {
+			var raw struct {
+				Name     string    `yaml:"name"`
+				External yaml.Node `yaml:"external"`
+			}
+			if err := value.Decode(&raw); err != nil {
+				return nil, fmt.Errorf("%s: %w", key.Value, err)
+			}
+			r.Name = raw.Name
+			// external may be a bool or the legacy {name: ...} form
+			r.External = raw.External.Kind == yaml.MappingNode || raw.External.Value == "true"
+		}
+		out[key.Value] = r
+	}
+	return out, nil
+}
+
+// decodeStringMap reads a mapping or a list of key=value strings.
+func decodeStringMap(n *yaml.Node) (map[string]string, error) {
+	switch n.Kind {
+	case 0:
+		return nil, nil
+	case yaml.MappingNode:
+		var raw map[string]any
+		if err := n.Decode(&raw); err != nil {
+			return nil, err
+		}
+		out := make(map[string]string, len(raw))
+		for k, v := range raw {
+			if v != nil {
+				out[k] = fmt.Sprint(v)
+			} else {
+				out[k] = ""
+			}
+		}
+		return out, nil
+	case yaml.SequenceNode:
+		out := map[string]string{}
+		for _, item := range n.Content {
+			k, v, _ := strings.Cut(item.Value, "=")
+			out[k] = v
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("line %d: expected a mapping or a list", n.Line)
+}
+
+// decodeNames reads a list of names or a mapping keyed by name, as used by
+// depends_on and networks.
+func decodeNames(n *yaml.Node) ([]string, error) {
+	switch n.Kind {
+	case 0:
+		return nil, nil
+	case yaml.SequenceNode:
+		var out []string
+		if err := n.Decode(&out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	case yaml.MappingNode:
+		var raw map[string]any
+		if err := n.Decode(&raw); err != nil {
+			return nil, err
+		}
+		out := make([]string, 0, len(raw))
+		for k := range raw {
+			out = append(out, k)
+		}
+		sort.Strings(out)
+		return out, nil
+	}
+	return nil, errors.New("expected a list or a mapping")
+}
@@ -0,0 +1,86 @@
+package compose
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeProjectName(t *testing.T) {
+	tests := map[string]string{
+		"app":         "app",
+		"My App":      "myapp",
+		"my.app":      "myapp",
+		"Web_Stack-2": "web_stack-2",
+		"_-app":       "app",
+		"été":         "t",
+		"...":         "",
+	}
+	for in, want := range tests {
+		if got := NormalizeProjectName(in); got != want {
+			t.Errorf("NormalizeProjectName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseAnchorsAndExtensions(t *testing.T) {
+	content := `x-common: &common
+  restart: unless-stopped
+  image: app:${TAG:-1.0}
+  labels:
+    team: web
+services:
+  web:
+    <<: *common
+    x-owner: alice
+    ports: ["8080:80"]
+  worker:
+    <<: *common
+    image: worker:2
+    depends_on:
+      web:
+        condition: service_healthy
+x-unused: $MISSING
+`
+	p, findings, err := Parse("compose.yaml", []byte(content), mapLookup(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Services) != 2 {
+		t.Fatalf("services %v", p.Services)
+	}
+	web, worker := p.Service("web"), p.Service("worker")
+	if web.Image != "app:1.0" || web.Restart != "unless-stopped" || web.Labels["team"] != "web" {
+		t.Fatalf("web did not inherit the anchor: %+v", web)
+	}
+	if worker.Image != "worker:2" || worker.Restart != "unless-stopped" {
+		t.Fatalf("worker did not override the merge key: %+v", worker)
+	}
+	if !reflect.DeepEqual(worker.DependsOn, []string{"web"}) {
+		t.Fatalf("depends_on %v", worker.DependsOn)
+	}
+	if web.Extensions["x-owner"] != "alice" || worker.Extensions != nil {
+		t.Fatalf("service extensions %v, %v", web.Extensions, worker.Extensions)
+	}
+	if _, ok := p.Extensions["x-common"]; !ok || len(p.Extensions) != 2 {
+		t.Fatalf("top-level extensions %v", p.Extensions)
+	}
+	if len(findings) != 1 || findings[0].Rule != RuleInterpolation || findings[0].Line != 17 {
+		t.Fatalf("findings %v", findings)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"":                  "empty document",
+		"- a\n- b\n":        "top level must be a mapping",
+		"services: [web]\n": "must be a mapping",
+		"services:\n  web:\n    image: ${TAG:?set TAG}\n": "compose.yaml:3: TAG: set TAG",
+		"services:\n  web: {image: [\n":                   "parse compose.yaml",
+	}
+	for content, want := range tests {
+		if _, _, err := Parse("compose.yaml", []byte(content), mapLookup(nil)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package compose
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parsePort reads a port in short or long syntax. Problems are recorded in
+// Port.Err for Validate to report.
+func parsePort(n *yaml.Node) Port {
+	p := Port{Raw: n.Value, Line: n.Line, Protocol: "tcp"}
+	if n.Kind == yaml.MappingNode {
+		var long struct {
+			Target    string `yaml:"target"`
+			Published string `yaml:"published"`
+			HostIP    string `yaml:"host_ip"`
+			Protocol  string `yaml:"protocol"`
+		}
+		if err := n.Decode(&long); err != nil {
+			p.Err = err.Error()
+			return p
+		}
+		p.Raw = fmt.Sprintf("%s:%s:%s/%s", long.HostIP, long.Published, long.Target, long.Protocol)
+		p.HostIP, p.Published, p.Target = long.HostIP, long.Published, long.Target
+		if long.Protocol != "" {
+			p.Protocol = long.Protocol
+		}
+		if p.Target == "" {
+			p.Err = "target is required"
+			return p
+		}
+		p.Err = checkPortNumbers(p)
+		return p
+	}
+	if n.Kind != yaml.ScalarNode {
+		p.Err = "expected a string or a mapping"
+		return p
+	}
+
+	spec := n.Value
+	if i := strings.LastIndex(spec, "/"); i >= 0 {
+		spec, p.Protocol = spec[:i], spec[i+1:]
+	}
+	if strings.HasPrefix(spec, "[") {
+		end := strings.Index(spec, "]:")
+		if end < 0 {
+			p.Err = "unterminated IPv6 address"
+			return p
+		}
+		p.HostIP, spec = spec[1:end], spec[end+2:]
+		published, target, ok := strings.Cut(spec, ":")
+		if !ok {
+			p.Err = "expected [ip]:published:target"
+			return p
+		}
+		p.Published, p.Target = published, target
+	} else {
+		switch parts := strings.Split(spec, ":"); len(parts) {
+		case 1:
+			p.Target = parts[0]
+		case 2:
+			p.Published, p.Target = parts[0], parts[1]
+		case 3:
+			p.HostIP, p.Published, p.Target = parts[0], parts[1], parts[2]
+		default:
+			p.Err = "too many colons (wrap IPv6 addresses in [])"
+			return p
+		}
+	}
+	p.Err = checkPortNumbers(p)
+	return p
+}
+
+// checkPortNumbers validates ports, ranges and the protocol.
+func checkPortNumbers(p Port) string {
+	switch p.Protocol {
+	case "tcp", "udp", "sctp":
+	default:
+		return fmt.Sprintf("unknown protocol %q (tcp|udp|sctp)", p.Protocol)
+	}
+	targetLo, targetHi, err := portRange(p.Target)
+	if err != nil {
+		return "target: " + err.Error()
+	}
+	if p.Published == "" {
+		return ""
+	}
+	pubLo, pubHi, err := portRange(p.Published)
+	if err != nil {
+		return "published: " + err.Error()
+	}
+	if targetHi > targetLo && pubHi-pubLo != targetHi-targetLo {
+		return "published and target ranges differ in size"
+	}
+	return ""
+}
+
+// portRange parses "80" or "8000-8010".
+func portRange(s string) (lo, hi int, err error) {
+	first, last, isRange := strings.Cut(s, "-")
+	if lo, err = portNumber(first); err != nil {
+		return 0, 0, err
+	}
+	hi = lo
+	if isRange {
+		if hi, err = portNumber(last); err != nil {
+			return 0, 0, err
+		}
+		if hi < lo {
+			return 0, 0, fmt.Errorf("range %s ends before it starts", s)
+		}
+	}
+	return lo, hi, nil
+}
+
+func portNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return 0, fmt.Errorf("%q is not a port number (1-65535)", s)
+	}
+	return n, nil
+}
+
+// mountModes are the options allowed after the target in short syntax.
+var mountModes = map[string]bool{
+	"ro": true, "rw": true, "z": true, "Z": true, "nocopy": true,
+	"cached": true, "delegated": true, "consistent": true,
+	"shared": true, "slave": true, "private": true, "rshared": true, "rslave": true, "rprivate": true,
+}
+
+// parseMount reads a service volume in short or long syntax. Problems are
+// recorded in Mount.Err for Validate to report.
+func parseMount(n *yaml.Node) Mount {
+	m := Mount{Raw: n.Value, Line: n.Line}
+	if n.Kind == yaml.MappingNode {
+		var long struct {
+			Type     string `yaml:"type"`
+			Source   string `yaml:"source"`
+			Target   string `yaml:"target"`
+			ReadOnly bool   `yaml:"read_only"`
+		}
+		if err := n.Decode(&long); err != nil {
+			m.Err = err.Error()
+			return m
+		}
+		m.Type, m.Source, m.Target, m.ReadOnly = long.Type, long.Source, long.Target, long.ReadOnly
+		m.Raw = fmt.Sprintf("%s:%s (%s)", m.Source, m.Target, m.Type)
+		switch m.Type {
+		case MountVolume, MountBind, MountTmpfs, "npipe", "cluster", "image":
+		case "":
+			m.Err = "type is required"
+		default:
+			m.Err = fmt.Sprintf("unknown type %q", m.Type)
+		}
+		if m.Err == "" && m.Target == "" {
+			m.Err = "target is required"
+		}
+		if m.Err == "" && m.Type == MountBind && m.Source == "" {
+			m.Err = "bind mounts need a source"
+		}
+		return m
+	}
+	if n.Kind != yaml.ScalarNode {
+		m.Err = "expected a string or a mapping"
+		return m
+	}
+
+	parts := strings.Split(n.Value, ":")
+	switch len(parts) {
+	case 1:
+		m.Target = parts[0]
+	case 2, 3:
+		m.Source, m.Target = parts[0], parts[1]
+		if len(parts) == 3 {
+			for _, mode := range strings.Split(parts[2], ",") {
+				if !mountModes[mode] {
+					m.Err = fmt.Sprintf("unknown mode %q", mode)
+					return m
+				}
+				if mode == "ro" {
+					m.ReadOnly = true
+				}
+			}
+		}
+	default:
+		m.Err = "expected [source:]target[:mode]"
+		return m
+	}
+	switch {
+	case m.Source == "":
+		m.Type = MountVolume
+	case strings.HasPrefix(m.Source, "/"), strings.HasPrefix(m.Source, "."), strings.HasPrefix(m.Source, "~"):
+		m.Type = MountBind
+	default:
+		m.Type = MountVolume
+	}
+	if m.Target == "" || !path.IsAbs(m.Target) {
+		m.Err = fmt.Sprintf("target %q must be an absolute path", m.Target)
+	}
+	return m
+}
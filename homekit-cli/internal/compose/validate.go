@@ -0,0 +1,156 @@
+package compose
+
+import (
+	"sort"
+	"strings"
+)
+
+// Validate reports errors that stop compose from starting the project:
+// unknown keys, dangling references, bad ports and mounts, undeclared
+// networks and volumes, and depends_on cycles.
+func Validate(p *Project) []Finding {
+	f := &finder{file: p.File}
+
+	unknown := make([]string, 0, len(p.unknown))
+	for k := range p.unknown {
+		unknown = append(unknown, k)
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		f.add(SeverityError, RuleSchema, p.unknown[k], "", "unknown top-level key %q", k)
+	}
+	if len(p.Services) == 0 {
+		f.add(SeverityError, RuleSchema, 0, "", "no services defined")
+	}
+
+	published := map[string]string{}
+	for _, s := range p.Services {
+		if s.Image == "" && !s.Build && s.Extends == "" {
+			f.add(SeverityError, RuleSchema, s.Line, s.Name, "needs an image or a build")
+		}
+		validateReferences(f, p, s)
+
+		for _, port := range s.Ports {
+			if port.Err != "" {
+				f.add(SeverityError, RulePorts, port.Line, s.Name, "port %q: %s", port.Raw, port.Err)
+				continue
+			}
+			if port.Published == "" {
+				continue
+			}
+			key := port.Published + "/" + port.Protocol
+			if port.HostIP != "" {
+				key = port.HostIP + ":" + key
+			}
+			if other, ok := published[key]; ok {
+				f.add(SeverityError, RulePorts, port.Line, s.Name, "host port %s is already published by %s", key, other)
+				continue
+			}
+			published[key] = s.Name
+		}
+
+		for _, m := range s.Volumes {
+			if m.Err != "" {
+				f.add(SeverityError, RuleVolumes, m.Line, s.Name, "volume %q: %s", m.Raw, m.Err)
+				continue
+			}
+			if m.Type == MountVolume && m.Source != "" {
+				if _, ok := p.Volumes[m.Source]; !ok {
+					f.add(SeverityError, RuleVolumes, m.Line, s.Name, "volume %q is not declared under top-level volumes", m.Source)
+				}
+			}
+		}
+
+		for _, n := range s.Networks {
+			if _, ok := p.Networks[n]; !ok && n != "default" {
+				f.add(SeverityError, RuleNetworks, s.Line, s.Name, "network %q is not declared under top-level networks", n)
+			}
+		}
+	}
+
+	for _, cycle := range dependencyCycles(p) {
+		s := p.Service(cycle[0])
+		f.add(SeverityError, RuleDependencyCycle, s.Line, s.Name, "depends_on cycle: %s", strings.Join(cycle, " -> "))
+	}
+	return f.findings
+}
+
+func validateReferences(f *finder, p *Project, s *Service) {
+	check := func(kind, name string) {
+		if p.Service(name) == nil {
+			f.add(SeverityError, RuleReference, s.Line, s.Name, "%s refers to unknown service %q", kind, name)
+		}
+	}
+	for _, d := range s.DependsOn {
+		check("depends_on", d)
+	}
+	for _, l := range s.Links {
+		name, _, _ := strings.Cut(l, ":")
+		check("links", name)
+	}
+	for _, v := range s.VolumesFrom {
+		if strings.HasPrefix(v, "container:") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimPrefix(v, "service:"), ":")
+		check("volumes_from", name)
+	}
+	if name, ok := strings.CutPrefix(s.NetworkMode, "service:"); ok {
+		check("network_mode", name)
+	}
+	if s.Extends != "" {
+		check("extends", s.Extends)
+	}
+}
+
+// dependencyCycles returns each depends_on cycle once, starting at its
+// alphabetically first service and closed by repeating it.
+func dependencyCycles(p *Project) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+	var cycles [][]string
+	var stack []string
+	var visit func(name string)
+	visit = func(name string) {
+		state[name] = visiting
+		stack = append(stack, name)
+		if s := p.Service(name); s != nil {
+			for _, dep := range s.DependsOn {
+				switch state[dep] {
+				case unvisited:
+					visit(dep)
+				case visiting:
+					start := 0
+					for stack[start] != dep {
+						start++
+					}
+					cycles = append(cycles, rotateCycle(stack[start:]))
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[name] = done
+	}
+	for _, s := range p.Services {
+		if state[s.Name] == unvisited {
+			visit(s.Name)
+		}
+	}
+	return cycles
+}
+
+// rotateCycle starts a cycle at its smallest name and closes it.
+func rotateCycle(cycle []string) []string {
+	first := 0
+	for i, name := range cycle {
+		if name < cycle[first] {
+			first = i
+		}
+	}
+	out := append(append([]string{}, cycle[first:]...), cycle[:first]...)
+	return append(out, out[0])
+}
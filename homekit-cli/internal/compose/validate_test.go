@@ -0,0 +1,150 @@
+package compose
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validate parses content and returns the rule and message of every
+// Validate finding.
+func validate(t *testing.T, content string) []string {
+	t.Helper()
+	p, _, err := Parse("compose.yaml", []byte(content), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out []string
+	for _, f := range Validate(p) {
+		out = append(out, f.Rule+": "+f.Message)
+	}
+	return out
+}
+
+func TestParsePort(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Port
+	}{
+		{`"80"`, Port{Target: "80", Protocol: "tcp"}},
+		{`"8080:80"`, Port{Published: "8080", Target: "80", Protocol: "tcp"}},
+		{`"127.0.0.1:8080:80/udp"`, Port{HostIP: "127.0.0.1", Published: "8080", Target: "80", Protocol: "udp"}},
+		{`"[::1]:53:53/udp"`, Port{HostIP: "::1", Published: "53", Target: "53", Protocol: "udp"}},
+		{`"8000-8001:9000-9001"`, Port{Published: "8000-8001", Target: "9000-9001", Protocol: "tcp"}},
+		{`{target: 80, published: "8080", host_ip: 0.0.0.0}`, Port{HostIP: "0.0.0.0", Published: "8080", Target: "80", Protocol: "tcp"}},
+		{`"::1:80:80"`, Port{Err: "too many colons (wrap IPv6 addresses in [])"}},
+		{`"[::1:80:80"`, Port{Err: "unterminated IPv6 address"}},
+		{`"[::1]:80"`, Port{Err: "expected [ip]:published:target"}},
+		{`"80/icmp"`, Port{Err: `unknown protocol "icmp" (tcp|udp|sctp)`}},
+		{`"70000"`, Port{Err: `target: "70000" is not a port number (1-65535)`}},
+		{`"x:80"`, Port{Err: `published: "x" is not a port number (1-65535)`}},
+		{`"90-80"`, Port{Err: "target: range 90-80 ends before it starts"}},
+		{`"8000-8002:9000-9001"`, Port{Err: "published and target ranges differ in size"}},
+		{`{published: "8080"}`, Port{Err: "target is required"}},
+		{`[80]`, Port{Err: "expected a string or a mapping"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			content := fmt.Sprintf("services:\n  web:\n    image: web:1\n    ports:\n      - %s\n", tt.spec)
+			p, _, err := Parse("compose.yaml", []byte(content), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := p.Services[0].Ports[0]
+			if tt.want.Err != "" {
+				if got.Err != tt.want.Err {
+					t.Fatalf("Err = %q, want %q", got.Err, tt.want.Err)
+				}
+				return
+			}
+			got.Raw, got.Line = "", 0
+			if got != tt.want {
+				t.Fatalf("port %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name, content string
+		want          []string
+	}{
+		{"valid", `
+services:
+  web:
+    image: web:1
+    ports: ["8080:80", "127.0.0.1:8081:80", "8080:80/udp"]
+    volumes: [data:/data, ./src:/src, /cache]
+    networks: [default, back]
+    depends_on: [db]
+  db:
+    image: db:1
+    ports: ["127.0.0.2:8081:81", "5432"]
+    volumes_from: [web, "container:other"]
+volumes:
+  data:
+networks:
+  back:
+`, nil},
+		{"duplicate host port", `
+services:
+  a: {image: a:1, ports: ["8080:80"]}
+  b: {image: b:1, ports: [{target: 81, published: "8080"}]}
+`, []string{"ports: host port 8080/tcp is already published by a"}},
+		{"bad port", `
+services:
+  a: {image: a:1, ports: ["99999:80"]}
+`, []string{`ports: port "99999:80": published: "99999" is not a port number (1-65535)`}},
+		{"undeclared volume", `
+services:
+  a: {image: a:1, volumes: [data:/data, "./x:/x:rw,z", "logs:/logs:bogus"]}
+`, []string{
+			`volumes: volume "data" is not declared under top-level volumes`,
+			`volumes: volume "logs:/logs:bogus": unknown mode "bogus"`,
+		}},
+		{"relative mount target", `
+services:
+  a: {image: a:1, volumes: [{type: bind, target: /x}, "data:rel"]}
+volumes: {data: }
+`, []string{
+			`volumes: volume ":/x (bind)": bind mounts need a source`,
+			`volumes: volume "data:rel": target "rel" must be an absolute path`,
+		}},
+		{"unknown references", `
+services:
+  a:
+    image: a:1
+    depends_on: {missing: {condition: service_started}}
+    links: ["gone:alias"]
+    network_mode: "service:nowhere"
+    networks: [front]
+  b: {extends: a}
+`, []string{
+			`reference: depends_on refers to unknown service "missing"`,
+			`reference: links refers to unknown service "gone"`,
+			`reference: network_mode refers to unknown service "nowhere"`,
+			`networks: network "front" is not declared under top-level networks`,
+		}},
+		{"schema", `
+services:
+  a: {}
+volume: {}
+`, []string{`schema: unknown top-level key "volume"`, "schema: needs an image or a build"}},
+		{"no services", "name: empty\n", []string{"schema: no services defined"}},
+		{"cycle", `
+services:
+  c: {image: c:1, depends_on: [a]}
+  a: {image: a:1, depends_on: [b]}
+  b: {image: b:1, depends_on: [c]}
+`, []string{"dependency-cycle: depends_on cycle: a -> b -> c -> a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validate(t, tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package core
+
+import "fmt"
+
+// ExitError asks main to exit with Code. Err, when set, is printed first;
+// a nil Err means the command already reported its result.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
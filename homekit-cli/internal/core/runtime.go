@@ -13,7 +13,9 @@ import (
 	"time"
 
 	"github.com/go-viper/mapstructure/v2"
+	"github.com/homekit/homekit-cli/internal/sysinfo"
 	"github.com/homekit/homekit-cli/internal/util/bufutil"
+	"github.com/homekit/homekit-cli/internal/util/pathformat"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
@@ -53,10 +55,45 @@ type Config struct {
 	AssetOverrides string   `mapstructure:"asset_overrides"`
 	PluginPaths    []string `mapstructure:"plugin_paths"`
 	TempDir        string   `mapstructure:"temp_dir"`
+	StateDir       string   `mapstructure:"state_dir"`
+	StacksDir      string   `mapstructure:"stacks_dir"`
 	LogLevel       string   `mapstructure:"log_level"`
+	// TemplateFuncs maps extra template function names to external commands
+	// (for example a homekit-cli-* plugin) whose trimmed stdout is the result.
+	TemplateFuncs map[string]string `mapstructure:"template_funcs"`
+	// Health configures `sys health`.
+	Health HealthConfig `mapstructure:"health"`
+	// Docker configures the Engine API client.
+	Docker DockerConfig `mapstructure:"docker"`
+	// Proxy configures `proxy generate`.
+	Proxy ProxyConfig `mapstructure:"proxy"`
 	// Add other fields as needed
 }
 
+// HealthConfig holds `sys health` settings. Thresholds override the built-in
+// warning/critical levels per metric (cpu, memory, swap, disk, inodes, load,
+// temperature); a level left out keeps its default.
+type HealthConfig struct {
+	Thresholds sysinfo.ThresholdOverrides `mapstructure:"thresholds"`
+}
+
+// DockerConfig holds Engine API settings. Host is a unix://, tcp:// or
+// http:// daemon address; when empty, DOCKER_HOST and then the default socket
+// are used.
+type DockerConfig struct {
+	Host string `mapstructure:"host"`
+}
+
+// ProxyConfig holds `proxy generate` defaults. Type is caddy or traefik,
+// Output the generated file, Upstream how the proxy reaches services
+// (container or host) and ReloadCommand what --reload runs after a change.
+type ProxyConfig struct {
+	Type          string `mapstructure:"type"`
+	Output        string `mapstructure:"output"`
+	Upstream      string `mapstructure:"upstream"`
+	ReloadCommand string `mapstructure:"reload_command"`
+}
+
 // DefaultConfigPath returns the default user config file location.
 func DefaultConfigPath() (string, error) {
 	dir, err := os.UserConfigDir()
@@ -66,6 +103,49 @@ func DefaultConfigPath() (string, error) {
 	return filepath.Join(dir, "homekit", "config.yaml"), nil
 }
 
+// DefaultStateDir returns the default directory for persistent CLI state,
+// honouring XDG_STATE_HOME.
+func DefaultStateDir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "homekit"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "state", "homekit"), nil
+}
+
+// StateDirectory returns the configured state directory or the default one.
+func (c Config) StateDirectory() (string, error) {
+	if c.StateDir != "" {
+		return pathformat.ExpandHome(c.StateDir), nil
+	}
+	return DefaultStateDir()
+}
+
+// StacksDirectory returns the directory holding compose stacks, ~/stacks
+// when stacks_dir is unset.
+func (c Config) StacksDirectory() (string, error) {
+	if c.StacksDir != "" {
+		return pathformat.ExpandHome(c.StacksDir), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "stacks"), nil
+}
+
+// TempDirectory returns the configured scratch directory, or the system
+// temporary directory when temp_dir is unset.
+func (c Config) TempDirectory() string {
+	if c.TempDir != "" {
+		return pathformat.ExpandHome(c.TempDir)
+	}
+	return os.TempDir()
+}
+
 // WithRuntime attaches the runtime instance to a context for downstream use.
 func WithRuntime(ctx context.Context, rt *Runtime) context.Context {
 	return context.WithValue(ctx, ctxKey{}, rt)
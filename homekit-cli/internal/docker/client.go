@@ -0,0 +1,189 @@
+// Package docker is a small Docker Engine API client that speaks HTTP to the
+// daemon over its unix socket or a tcp DOCKER_HOST, without the docker CLI.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// APIVersion is the Engine API version requested by the client (Docker 20.10
+// and newer).
+const APIVersion = "1.41"
+
+// DefaultHost is used when neither the caller nor DOCKER_HOST names a daemon.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// ErrNotFound matches API errors with status 404.
+var ErrNotFound = errors.New("not found")
+
+// APIError is a non-2xx response from the daemon.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("docker API: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// Is makes errors.Is(err, ErrNotFound) work for 404 responses.
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// Client calls the Engine API. It is safe for concurrent use.
+type Client struct {
+	host string
+	base string
+	http *http.Client
+	// dial opens a raw connection to the daemon for hijacked streams.
+	dial func(ctx context.Context) (net.Conn, error)
+}
+
+// NewClient connects to host, a unix://, tcp:// or http:// address. An empty
+// host falls back to DOCKER_HOST and then DefaultHost. Every call honours
+// its context: cancelling it aborts the request or closes a running stream.
+func NewClient(host string) (*Client, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = DefaultHost
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("parse docker host %q: %w", host, err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c := &Client{host: host, http: &http.Client{Transport: transport}}
+	var d net.Dialer
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		c.dial = func(ctx context.Context) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", socket)
+		}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return c.dial(ctx)
+		}
+		c.base = "http://docker"
+	case "tcp", "http":
+		addr := u.Host
+		c.dial = func(ctx context.Context) (net.Conn, error) {
+			return d.DialContext(ctx, "tcp", addr)
+		}
+		c.base = "http://" + addr
+	default:
+		return nil, fmt.Errorf("unsupported docker host %q (unix://, tcp:// or http://)", host)
+	}
+	return c, nil
+}
+
+// Host returns the daemon address the client talks to.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// Filters is the Engine API filter argument, e.g. {"dangling": {"true"}}.
+type Filters map[string][]string
+
+func (f Filters) encode(q url.Values) {
+	if len(f) == 0 {
+		return
+	}
+	raw, _ := json.Marshal(f)
+	q.Set("filters", string(raw))
+}
+
+// newRequest builds an API request for path with an optional JSON body.
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body any) (*http.Request, error) {
+	target := c.base + "/v" + APIVersion + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do sends a request and returns the response when the status is 2xx. The
+// caller closes the body.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("docker API %s %s: %w", method, path, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, readAPIError(resp)
+	}
+	return resp, nil
+}
+
+func readAPIError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var msg struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(raw, &msg) != nil || msg.Message == "" {
+		msg.Message = strings.TrimSpace(string(raw))
+	}
+	if msg.Message == "" {
+		msg.Message = http.StatusText(resp.StatusCode)
+	}
+	return &APIError{StatusCode: resp.StatusCode, Message: msg.Message}
+}
+
+// call sends a request and decodes a JSON response into out when non-nil.
+func (c *Client) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode docker API %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// Ping checks that the daemon answers.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.call(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+}
@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// fakeDaemon serves handler on a unix socket under t.TempDir, the way the
+// Engine API is usually reached, and returns a client for it.
+func fakeDaemon(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// writeJSON answers a fake API call.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// frame encodes one frame of the multiplexed stream format.
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8, 8+len(payload))
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestNewClientHosts(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:2375")
+	c, err := NewClient("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Host() != "tcp://127.0.0.1:2375" || c.base != "http://127.0.0.1:2375" {
+		t.Errorf("host = %s, base = %s", c.Host(), c.base)
+	}
+
+	t.Setenv("DOCKER_HOST", "")
+	if c, err = NewClient(""); err != nil || c.Host() != DefaultHost {
+		t.Errorf("NewClient() = %v, %v; want %s", c, err, DefaultHost)
+	}
+	if _, err := NewClient("ssh://example.com"); err == nil {
+		t.Error("NewClient accepted an ssh host")
+	}
+}
+
+func TestClientCall(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v"+APIVersion+"/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		var filters Filters
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("all") != "1" || filters["label"][0] != "app=web" {
+			http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, []map[string]any{{"Id": "0123456789abcdef", "Names": []string{"/web-1"}, "State": "running"}})
+	})
+	mux.HandleFunc("GET /v"+APIVersion+"/_ping", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+	c := fakeDaemon(t, mux)
+
+	ctx := context.Background()
+	if err := c.Ping(ctx); err != nil {
+		t.Fatal(err)
+	}
+	list, err := c.ContainerList(ctx, ContainerListOptions{All: true, Filters: Filters{"label": {"app=web"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name() != "web-1" || ShortID(list[0].ID) != "0123456789ab" {
+		t.Fatalf("ContainerList = %+v", list)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v"+APIVersion+"/containers/missing/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]string{"message": "No such container: missing"})
+	})
+	mux.HandleFunc("/v"+APIVersion+"/containers/broken/json", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "daemon exploded", http.StatusInternalServerError)
+	})
+	c := fakeDaemon(t, mux)
+
+	_, err := c.ContainerInspect(context.Background(), "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "No such container: missing" {
+		t.Fatalf("err = %#v", err)
+	}
+
+	_, err = c.ContainerInspect(context.Background(), "broken")
+	if errors.Is(err, ErrNotFound) {
+		t.Fatal("a 500 response matches ErrNotFound")
+	}
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 || apiErr.Message != "daemon exploded" {
+		t.Fatalf("err = %#v", err)
+	}
+}
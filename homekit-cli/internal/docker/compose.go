@@ -0,0 +1,15 @@
+package docker
+
+// Labels docker compose sets on the containers, networks and volumes it
+// creates.
+const (
+	ComposeProjectLabel    = "com.docker.compose.project"
+	ComposeWorkingDirLabel = "com.docker.compose.project.working_dir"
+	ComposeServiceLabel    = "com.docker.compose.service"
+)
+
+// ComposeProjectFilter selects the resources of the compose project whose
+// files live in dir (an absolute path).
+func ComposeProjectFilter(dir string) Filters {
+	return Filters{"label": {ComposeWorkingDirLabel + "=" + dir}}
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Container is an entry of the container list.
+type Container struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	ImageID string            `json:"ImageID"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Created int64             `json:"Created"`
+	Labels  map[string]string `json:"Labels"`
+	// SizeRw is only filled when listing with Size.
+	SizeRw int64 `json:"SizeRw"`
+}
+
+// Name returns the primary container name without the leading slash.
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return ShortID(c.ID)
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// ContainerListOptions selects containers to list.
+type ContainerListOptions struct {
+	// All includes stopped containers.
+	All bool
+	// Size computes the writable layer size, which is slow on large hosts.
+	Size    bool
+	Filters Filters
+}
+
+// ContainerList lists containers.
+func (c *Client) ContainerList(ctx context.Context, opts ContainerListOptions) ([]Container, error) {
+	q := url.Values{}
+	if opts.All {
+		q.Set("all", "1")
+	}
+	if opts.Size {
+		q.Set("size", "1")
+	}
+	opts.Filters.encode(q)
+	var out []Container
+	err := c.call(ctx, http.MethodGet, "/containers/json", q, nil, &out)
+	return out, err
+}
+
+// ContainerRemove deletes a container. Force kills a running container
+// first; anonymous volumes are kept.
+func (c *Client) ContainerRemove(ctx context.Context, id string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.call(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id), q, nil, nil)
+}
+
+// ShortID returns the 12-character form of an ID, without any sha256 prefix.
+func ShortID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// ContainerDetails is the result of ContainerInspect.
+type ContainerDetails struct {
+	ID    string `json:"Id"`
+	Name  string `json:"Name"`
+	Image string `json:"Image"`
+	State struct {
+		Status     string `json:"Status"`
+		Running    bool   `json:"Running"`
+		Restarting bool   `json:"Restarting"`
+		ExitCode   int    `json:"ExitCode"`
+		Health     *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Labels map[string]string `json:"Labels"`
+		Tty    bool              `json:"Tty"`
+	} `json:"Config"`
+}
+
+// HealthStatus returns the healthcheck status, or "" when the container has
+// no healthcheck.
+func (d *ContainerDetails) HealthStatus() string {
+	if d.State.Health == nil {
+		return ""
+	}
+	return d.State.Health.Status
+}
+
+// ContainerInspect returns details of a container by ID or name.
+func (c *Client) ContainerInspect(ctx context.Context, id string) (*ContainerDetails, error) {
+	var out ContainerDetails
+	if err := c.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,74 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Event is one daemon event, such as a container start or image pull.
+type Event struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Scope    string `json:"scope"`
+	Time     int64  `json:"time"`
+	TimeNano int64  `json:"timeNano"`
+}
+
+// When returns the event time.
+func (e Event) When() time.Time {
+	if e.TimeNano != 0 {
+		return time.Unix(0, e.TimeNano)
+	}
+	return time.Unix(e.Time, 0)
+}
+
+// EventsOptions selects events. Without Until the stream stays open.
+type EventsOptions struct {
+	Since   time.Time
+	Until   time.Time
+	Filters Filters
+}
+
+// Events streams daemon events to fn until the stream ends, fn returns an
+// error or ctx is cancelled; the latter two errors are returned.
+func (c *Client) Events(ctx context.Context, opts EventsOptions, fn func(Event) error) error {
+	q := url.Values{}
+	if !opts.Since.IsZero() {
+		q.Set("since", strconv.FormatInt(opts.Since.Unix(), 10))
+	}
+	if !opts.Until.IsZero() {
+		q.Set("until", strconv.FormatInt(opts.Until.Unix(), 10))
+	}
+	opts.Filters.encode(q)
+	resp, err := c.do(ctx, http.MethodGet, "/events", q, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var ev Event
+		if err := dec.Decode(&ev); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("read docker events: %w", err)
+		}
+		if err := fn(ev); err != nil {
+			return err
+		}
+	}
+}
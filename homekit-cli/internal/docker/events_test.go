@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// eventsDaemon streams events and then, with hold, keeps the stream open
+// until the client goes away.
+func eventsDaemon(t *testing.T, events []Event, hold bool) *Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v"+APIVersion+"/events", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("since") != "1700000000" || q.Get("filters") != `{"type":["container"]}` {
+			http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		enc := json.NewEncoder(w)
+		for _, ev := range events {
+			enc.Encode(ev)
+			w.(http.Flusher).Flush()
+		}
+		if hold {
+			<-r.Context().Done()
+		}
+	})
+	return fakeDaemon(t, mux)
+}
+
+func testEvents() []Event {
+	events := make([]Event, 3)
+	for i, action := range []string{"create", "start", "die"} {
+		events[i].Type = "container"
+		events[i].Action = action
+		events[i].Actor.ID = "abc"
+		events[i].TimeNano = int64(1700000000+i) * int64(time.Second)
+	}
+	return events
+}
+
+var eventsOptions = EventsOptions{Since: time.Unix(1700000000, 0), Filters: Filters{"type": {"container"}}}
+
+func TestEvents(t *testing.T) {
+	c := eventsDaemon(t, testEvents(), false)
+	var got []Event
+	err := c.Events(context.Background(), eventsOptions, func(ev Event) error {
+		got = append(got, ev)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[2].Action != "die" || got[2].When().Unix() != 1700000002 {
+		t.Fatalf("events = %+v", got)
+	}
+}
+
+func TestEventsCallbackError(t *testing.T) {
+	c := eventsDaemon(t, testEvents(), true)
+	stop := errors.New("stop")
+	calls := 0
+	err := c.Events(context.Background(), eventsOptions, func(Event) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("Events = %v after %d calls", err, calls)
+	}
+}
+
+func TestEventsCanceled(t *testing.T) {
+	c := eventsDaemon(t, testEvents(), true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	seen := 0
+	err := c.Events(ctx, eventsOptions, func(Event) error {
+		if seen++; seen == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || seen != 3 {
+		t.Fatalf("Events = %v after %d events, want context.Canceled after 3", err, seen)
+	}
+}
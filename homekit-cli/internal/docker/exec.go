@@ -0,0 +1,101 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ExecOptions describes a command to run in a running container.
+type ExecOptions struct {
+	Cmd        []string
+	Env        []string
+	WorkingDir string
+	User       string
+	// Tty allocates a pseudo-terminal; output is then a raw stream.
+	Tty bool
+	// Stdin attaches standard input, which requires ExecAttach.
+	Stdin bool
+}
+
+// ExecCreate prepares a command in a container and returns the exec ID.
+func (c *Client) ExecCreate(ctx context.Context, container string, opts ExecOptions) (string, error) {
+	body := map[string]any{
+		"Cmd":          opts.Cmd,
+		"Env":          opts.Env,
+		"WorkingDir":   opts.WorkingDir,
+		"User":         opts.User,
+		"Tty":          opts.Tty,
+		"AttachStdin":  opts.Stdin,
+		"AttachStdout": true,
+		"AttachStderr": true,
+	}
+	var out struct {
+		ID string `json:"Id"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(container)+"/exec", nil, body, &out); err != nil {
+		return "", err
+	}
+	return out.ID, nil
+}
+
+// ExecAttach starts an exec instance and returns the connection carrying
+// its stdin and output. tty must match ExecOptions.Tty.
+func (c *Client) ExecAttach(ctx context.Context, id string, tty bool) (*HijackedConn, error) {
+	body := map[string]bool{"Detach": false, "Tty": tty}
+	return c.hijack(ctx, http.MethodPost, "/exec/"+url.PathEscape(id)+"/start", nil, body)
+}
+
+// ExecResize sets the terminal size of an exec instance started with a TTY.
+func (c *Client) ExecResize(ctx context.Context, id string, height, width int) error {
+	q := url.Values{"h": {strconv.Itoa(height)}, "w": {strconv.Itoa(width)}}
+	return c.call(ctx, http.MethodPost, "/exec/"+url.PathEscape(id)+"/resize", q, nil, nil)
+}
+
+// ExecState is the result of ExecInspect.
+type ExecState struct {
+	ID       string `json:"ID"`
+	Running  bool   `json:"Running"`
+	ExitCode int    `json:"ExitCode"`
+	Pid      int    `json:"Pid"`
+}
+
+// ExecInspect reports whether an exec instance still runs and its exit code.
+func (c *Client) ExecInspect(ctx context.Context, id string) (*ExecState, error) {
+	var out ExecState
+	if err := c.call(ctx, http.MethodGet, "/exec/"+url.PathEscape(id)+"/json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Exec runs a command without input in a container, writes its output to
+// stdout and stderr and returns its exit code.
+func (c *Client) Exec(ctx context.Context, container string, opts ExecOptions, stdout, stderr io.Writer) (int, error) {
+	opts.Stdin = false
+	id, err := c.ExecCreate(ctx, container, opts)
+	if err != nil {
+		return 0, err
+	}
+	conn, err := c.ExecAttach(ctx, id, opts.Tty)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	// there is no input to send
+	conn.CloseWrite()
+	if err := copyStream(opts.Tty, stdout, stderr, conn); err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+		return 0, fmt.Errorf("exec in %s: %w", container, err)
+	}
+	state, err := c.ExecInspect(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	return state.ExitCode, nil
+}
@@ -0,0 +1,107 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// execDaemon runs one exec instance. Its start handler upgrades the
+// connection, waits for the client to close stdin, then writes output and
+// closes the stream; with hold it keeps the stream open instead.
+func execDaemon(t *testing.T, tty bool, output []byte, exitCode int, hold bool) *Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v"+APIVersion+"/containers/web/exec", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Cmd         []string
+			Tty         bool
+			AttachStdin bool
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Cmd) == 0 || body.Tty != tty || body.AttachStdin {
+			http.Error(w, "unexpected exec config", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, map[string]string{"Id": "exec1"})
+	})
+	mux.HandleFunc("POST /v"+APIVersion+"/exec/exec1/start", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Detach, Tty bool }
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.Header.Get("Upgrade") != "tcp" || body.Detach || body.Tty != tty {
+			http.Error(w, "unexpected start request", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.multiplexed-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+		rw.Flush()
+		if hold {
+			io.Copy(io.Discard, conn)
+			return
+		}
+		// stdin is closed before any output is expected
+		io.Copy(io.Discard, rw)
+		conn.Write(output)
+	})
+	mux.HandleFunc("GET /v"+APIVersion+"/exec/exec1/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, ExecState{ID: "exec1", ExitCode: exitCode})
+	})
+	return fakeDaemon(t, mux)
+}
+
+func TestExec(t *testing.T) {
+	output := append(frame(streamStdout, "hello\n"), frame(streamStderr, "oops\n")...)
+	c := execDaemon(t, false, output, 3, false)
+	var stdout, stderr bytes.Buffer
+	code, err := c.Exec(context.Background(), "web", ExecOptions{Cmd: []string{"sh", "-c", "exit 3"}, Stdin: true}, &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 || stdout.String() != "hello\n" || stderr.String() != "oops\n" {
+		t.Fatalf("code = %d, stdout = %q, stderr = %q", code, stdout.String(), stderr.String())
+	}
+}
+
+func TestExecTTY(t *testing.T) {
+	c := execDaemon(t, true, []byte("raw\r\n"), 0, false)
+	var stdout bytes.Buffer
+	code, err := c.Exec(context.Background(), "web", ExecOptions{Cmd: []string{"true"}, Tty: true}, &stdout, io.Discard)
+	if err != nil || code != 0 || stdout.String() != "raw\r\n" {
+		t.Fatalf("Exec = %d, %v; stdout = %q", code, err, stdout.String())
+	}
+}
+
+func TestExecCanceled(t *testing.T) {
+	c := execDaemon(t, false, nil, 0, true)
+	ctx, cancel := context.WithCancel(context.Background())
+	conn, err := c.ExecAttach(ctx, "exec1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	cancel()
+	if _, err := io.Copy(io.Discard, conn); err == nil {
+		t.Fatal("stream survived its context")
+	}
+}
+
+func TestExecNotFound(t *testing.T) {
+	c := fakeDaemon(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, map[string]string{"message": "No such exec instance: exec1"})
+	}))
+	if _, err := c.ExecAttach(context.Background(), "exec1", false); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("ExecAttach = %v, want ErrNotFound", err)
+	}
+	if _, err := c.Exec(context.Background(), "gone", ExecOptions{Cmd: []string{"true"}}, io.Discard, io.Discard); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Exec = %v, want ErrNotFound", err)
+	}
+}
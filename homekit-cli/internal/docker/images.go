@@ -0,0 +1,111 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Image is an entry of the image list.
+type Image struct {
+	ID          string            `json:"Id"`
+	RepoTags    []string          `json:"RepoTags"`
+	RepoDigests []string          `json:"RepoDigests"`
+	Created     int64             `json:"Created"`
+	Size        int64             `json:"Size"`
+	Labels      map[string]string `json:"Labels"`
+}
+
+// ImageListOptions selects images to list.
+type ImageListOptions struct {
+	// All includes intermediate images.
+	All     bool
+	Filters Filters
+}
+
+// ImageList lists images.
+func (c *Client) ImageList(ctx context.Context, opts ImageListOptions) ([]Image, error) {
+	q := url.Values{}
+	if opts.All {
+		q.Set("all", "1")
+	}
+	opts.Filters.encode(q)
+	var out []Image
+	err := c.call(ctx, http.MethodGet, "/images/json", q, nil, &out)
+	return out, err
+}
+
+// ImageRemove deletes an image by ID or reference. Untagged parent images are
+// removed along with it.
+func (c *Client) ImageRemove(ctx context.Context, ref string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.call(ctx, http.MethodDelete, "/images/"+ref, q, nil, nil)
+}
+
+// ImageDetails is the result of ImageInspect.
+type ImageDetails struct {
+	ID          string   `json:"Id"`
+	RepoTags    []string `json:"RepoTags"`
+	RepoDigests []string `json:"RepoDigests"`
+	Created     string   `json:"Created"`
+	Size        int64    `json:"Size"`
+	Config      struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// ImageInspect returns details of a local image by ID or reference.
+func (c *Client) ImageInspect(ctx context.Context, ref string) (*ImageDetails, error) {
+	var out ImageDetails
+	if err := c.call(ctx, http.MethodGet, "/images/"+ref+"/json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImageTag adds the tag repo:tag to a local image.
+func (c *Client) ImageTag(ctx context.Context, id, repo, tag string) error {
+	q := url.Values{"repo": {repo}, "tag": {tag}}
+	return c.call(ctx, http.MethodPost, "/images/"+id+"/tag", q, nil, nil)
+}
+
+// PullMessage is one progress line of an image pull.
+type PullMessage struct {
+	ID       string `json:"id"`
+	Status   string `json:"status"`
+	Progress string `json:"progress"`
+	Error    string `json:"error"`
+}
+
+// ImagePull pulls ref (name with tag or digest) from its registry, calling
+// progress for every message the daemon streams. A pull that fails midway is
+// reported as an error even though the HTTP status was 200.
+func (c *Client) ImagePull(ctx context.Context, ref string, progress func(PullMessage)) error {
+	resp, err := c.do(ctx, http.MethodPost, "/images/create", url.Values{"fromImage": {ref}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg PullMessage
+		if err := dec.Decode(&msg); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("pull %s: %w", ref, err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("pull %s: %s", ref, msg.Error)
+		}
+		if progress != nil {
+			progress(msg)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// LogsOptions selects the log lines of a container.
+type LogsOptions struct {
+	// Follow keeps the stream open for new output until ctx is cancelled.
+	Follow bool
+	// Tail is the number of lines from the end, or "" for all.
+	Tail string
+	// Since drops lines written before it when set.
+	Since      time.Time
+	Timestamps bool
+}
+
+// ContainerLogs opens the stdout and stderr log stream of a container. The
+// stream is multiplexed (see StdCopy) unless the container has a TTY; use
+// CopyLogs to handle both. The caller closes the stream.
+func (c *Client) ContainerLogs(ctx context.Context, id string, opts LogsOptions) (io.ReadCloser, error) {
+	q := url.Values{"stdout": {"1"}, "stderr": {"1"}}
+	if opts.Follow {
+		q.Set("follow", "1")
+	}
+	if opts.Tail != "" {
+		q.Set("tail", opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		q.Set("since", strconv.FormatInt(opts.Since.Unix(), 10))
+	}
+	if opts.Timestamps {
+		q.Set("timestamps", "1")
+	}
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/logs", q, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// CopyLogs writes the logs of a container to stdout and stderr until the
+// stream ends or ctx is cancelled, in which case it returns ctx.Err().
+func (c *Client) CopyLogs(ctx context.Context, id string, opts LogsOptions, stdout, stderr io.Writer) error {
+	details, err := c.ContainerInspect(ctx, id)
+	if err != nil {
+		return err
+	}
+	stream, err := c.ContainerLogs(ctx, details.ID, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	if err := copyStream(details.Config.Tty, stdout, stderr, stream); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Network describes a network. Containers is only filled by NetworkInspect.
+type Network struct {
+	ID         string                     `json:"Id"`
+	Name       string                     `json:"Name"`
+	Driver     string                     `json:"Driver"`
+	Scope      string                     `json:"Scope"`
+	Internal   bool                       `json:"Internal"`
+	Labels     map[string]string          `json:"Labels"`
+	Containers map[string]NetworkEndpoint `json:"Containers"`
+}
+
+// NetworkEndpoint is a container attached to a network.
+type NetworkEndpoint struct {
+	Name        string `json:"Name"`
+	IPv4Address string `json:"IPv4Address"`
+	IPv6Address string `json:"IPv6Address"`
+}
+
+// NetworkList lists networks.
+func (c *Client) NetworkList(ctx context.Context, filters Filters) ([]Network, error) {
+	q := url.Values{}
+	filters.encode(q)
+	var out []Network
+	err := c.call(ctx, http.MethodGet, "/networks", q, nil, &out)
+	return out, err
+}
+
+// NetworkInspect returns a network by ID or name, with its containers.
+func (c *Client) NetworkInspect(ctx context.Context, id string) (*Network, error) {
+	var out Network
+	if err := c.call(ctx, http.MethodGet, "/networks/"+url.PathEscape(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NetworkRemove deletes a network without attached containers.
+func (c *Client) NetworkRemove(ctx context.Context, id string) error {
+	return c.call(ctx, http.MethodDelete, "/networks/"+url.PathEscape(id), nil, nil, nil)
+}
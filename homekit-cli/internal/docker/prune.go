@@ -0,0 +1,237 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// KeepLabel protects a container, image or volume from pruning when set to
+// "true".
+const KeepLabel = "homekit.keep"
+
+// ResourceKind names a prunable resource type.
+type ResourceKind string
+
+const (
+	KindContainer  ResourceKind = "container"
+	KindImage      ResourceKind = "image"
+	KindVolume     ResourceKind = "volume"
+	KindBuildCache ResourceKind = "build-cache"
+)
+
+// Candidate is one resource considered for pruning. ID is the full ID (the
+// name for volumes). Reason explains why it would be removed or, for kept
+// resources, why it is protected.
+type Candidate struct {
+	Kind   ResourceKind `json:"kind"`
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Size   int64        `json:"size"`
+	Reason string       `json:"reason"`
+}
+
+// PrunePlan lists what would be removed and what was protected.
+type PrunePlan struct {
+	Remove []Candidate `json:"remove"`
+	Keep   []Candidate `json:"keep"`
+}
+
+// Reclaimable sums the sizes of the resources to remove.
+func (p *PrunePlan) Reclaimable() int64 {
+	var total int64
+	for _, c := range p.Remove {
+		total += max(0, c.Size)
+	}
+	return total
+}
+
+// PruneOptions selects the resource kinds to consider.
+type PruneOptions struct {
+	Containers bool
+	Images     bool
+	Volumes    bool
+	BuildCache bool
+	// AllVolumes also removes unused named volumes; by default only
+	// anonymous volumes are candidates.
+	AllVolumes bool
+	// Protect returns a non-empty reason when a resource with the given name
+	// and labels must be kept. KeepLabel is always honoured.
+	Protect func(name string, labels map[string]string) string
+	// Now is used to describe ages; it defaults to time.Now.
+	Now func() time.Time
+}
+
+var anonymousVolume = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// PlanPrune lists stopped containers, dangling images, unused volumes and
+// unused build cache, separating protected resources.
+func (c *Client) PlanPrune(ctx context.Context, opts PruneOptions) (*PrunePlan, error) {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	protect := func(name string, labels map[string]string) string {
+		if labels[KeepLabel] == "true" {
+			return KeepLabel + "=true"
+		}
+		if opts.Protect != nil {
+			return opts.Protect(name, labels)
+		}
+		return ""
+	}
+	plan := &PrunePlan{}
+	add := func(cand Candidate, protected string) {
+		if protected != "" {
+			cand.Reason = protected
+			plan.Keep = append(plan.Keep, cand)
+			return
+		}
+		plan.Remove = append(plan.Remove, cand)
+	}
+
+	containers, err := c.ContainerList(ctx, ContainerListOptions{All: true, Size: opts.Containers})
+	if err != nil {
+		return nil, fmt.Errorf("list containers: %w", err)
+	}
+	// images stay in use while any container that is kept references them
+	imageUsers := map[string]string{}
+	for _, ctr := range containers {
+		stopped := ctr.State == "exited" || ctr.State == "created" || ctr.State == "dead"
+		reason := protect(ctr.Name(), ctr.Labels)
+		if !stopped || !opts.Containers || reason != "" {
+			imageUsers[ctr.ImageID] = ctr.Name()
+		}
+		if !stopped || !opts.Containers {
+			continue
+		}
+		add(Candidate{
+			Kind:   KindContainer,
+			ID:     ctr.ID,
+			Name:   ctr.Name(),
+			Size:   ctr.SizeRw,
+			Reason: fmt.Sprintf("%s (%s), created %s", ctr.State, ctr.Status, age(now(), time.Unix(ctr.Created, 0))),
+		}, reason)
+	}
+
+	if opts.Images {
+		images, err := c.ImageList(ctx, ImageListOptions{Filters: Filters{"dangling": {"true"}}})
+		if err != nil {
+			return nil, fmt.Errorf("list images: %w", err)
+		}
+		for _, img := range images {
+			reason := protect(ShortID(img.ID), img.Labels)
+			if user, ok := imageUsers[img.ID]; ok && reason == "" {
+				reason = "used by container " + user
+			}
+			add(Candidate{
+				Kind:   KindImage,
+				ID:     img.ID,
+				Name:   "<none>",
+				Size:   img.Size,
+				Reason: "dangling, created " + age(now(), time.Unix(img.Created, 0)),
+			}, reason)
+		}
+	}
+
+	if opts.Volumes || opts.BuildCache {
+		df, err := c.DiskUsage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("disk usage: %w", err)
+		}
+		if opts.Volumes {
+			for _, vol := range df.Volumes {
+				if vol.UsageData == nil || vol.UsageData.RefCount != 0 {
+					continue
+				}
+				anonymous := anonymousVolume.MatchString(vol.Name)
+				cand := Candidate{Kind: KindVolume, ID: vol.Name, Name: vol.Name, Size: vol.UsageData.Size, Reason: "unused named volume"}
+				if anonymous {
+					cand.Name, cand.Reason = ShortID(vol.Name), "unused anonymous volume"
+				}
+				reason := protect(vol.Name, vol.Labels)
+				if reason == "" && !anonymous && !opts.AllVolumes {
+					reason = "named volume (use --all-volumes)"
+				}
+				add(cand, reason)
+			}
+		}
+		if opts.BuildCache {
+			for _, rec := range df.BuildCache {
+				if rec.InUse {
+					continue
+				}
+				reason := "unused " + rec.Type + " cache"
+				if rec.LastUsedAt != nil {
+					reason += ", last used " + age(now(), *rec.LastUsedAt)
+				}
+				add(Candidate{Kind: KindBuildCache, ID: rec.ID, Name: rec.Description, Size: rec.Size, Reason: reason}, "")
+			}
+		}
+	}
+
+	order := map[ResourceKind]int{KindContainer: 0, KindImage: 1, KindVolume: 2, KindBuildCache: 3}
+	for _, list := range [][]Candidate{plan.Remove, plan.Keep} {
+		sort.SliceStable(list, func(i, j int) bool { return order[list[i].Kind] < order[list[j].Kind] })
+	}
+	return plan, nil
+}
+
+// ApplyPrune removes the planned resources in order (containers before the
+// images and volumes they may hold) and returns the space reclaimed. Build
+// cache is pruned as a whole. onRemoved, when set, is called after each
+// removal; failures do not stop the run and are returned joined.
+func (c *Client) ApplyPrune(ctx context.Context, plan *PrunePlan, onRemoved func(Candidate)) (int64, error) {
+	var (
+		reclaimed  int64
+		errs       []error
+		buildCache bool
+	)
+	for _, cand := range plan.Remove {
+		var err error
+		switch cand.Kind {
+		case KindContainer:
+			err = c.ContainerRemove(ctx, cand.ID, false)
+		case KindImage:
+			err = c.ImageRemove(ctx, cand.ID, false)
+		case KindVolume:
+			err = c.VolumeRemove(ctx, cand.ID)
+		case KindBuildCache:
+			buildCache = true
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("remove %s %s: %w", cand.Kind, cand.Name, err))
+			continue
+		}
+		reclaimed += max(0, cand.Size)
+		if onRemoved != nil {
+			onRemoved(cand)
+		}
+	}
+	if buildCache {
+		n, err := c.BuildCachePrune(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("prune build cache: %w", err))
+		}
+		reclaimed += n
+	}
+	return reclaimed, errors.Join(errs...)
+}
+
+func age(now, t time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case t.IsZero() || t.Unix() <= 0:
+		return "at an unknown time"
+	case d < time.Hour:
+		return fmt.Sprintf("%d minutes ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d days ago", int(d.Hours()/24))
+	}
+}
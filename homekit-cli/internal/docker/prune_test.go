@@ -0,0 +1,211 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeEngine is an Engine API with prunable resources. It records removals
+// and fails those listed in fail.
+type fakeEngine struct {
+	mu         sync.Mutex
+	containers []Container
+	images     []Image
+	df         DiskUsage
+	fail       map[string]bool
+	removed    []string
+}
+
+func (e *fakeEngine) handler() http.Handler {
+	mux := http.NewServeMux()
+	prefix := "/v" + APIVersion
+	mux.HandleFunc("GET "+prefix+"/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, e.containers)
+	})
+	mux.HandleFunc("GET "+prefix+"/images/json", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filters") != `{"dangling":["true"]}` {
+			http.Error(w, "only dangling images are listed", http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, e.images)
+	})
+	mux.HandleFunc("GET "+prefix+"/system/df", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, e.df)
+	})
+	for _, kind := range []string{"containers", "images", "volumes"} {
+		mux.HandleFunc("DELETE "+prefix+"/"+kind+"/{id}", func(w http.ResponseWriter, r *http.Request) {
+			e.remove(w, strings.TrimSuffix(kind, "s")+" "+r.PathValue("id"))
+		})
+	}
+	mux.HandleFunc("POST "+prefix+"/build/prune", func(w http.ResponseWriter, r *http.Request) {
+		e.remove(w, "build-cache")
+		writeJSON(w, map[string]int64{"SpaceReclaimed": 7})
+	})
+	return mux
+}
+
+func (e *fakeEngine) remove(w http.ResponseWriter, what string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.fail[what] {
+		w.WriteHeader(http.StatusConflict)
+		writeJSON(w, map[string]string{"message": what + " is in use"})
+		return
+	}
+	e.removed = append(e.removed, what)
+}
+
+const anonVolume = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func newFakeEngine() *fakeEngine {
+	lastUsed := time.Unix(1700000000, 0)
+	return &fakeEngine{
+		containers: []Container{
+			{ID: "c-web", Names: []string{"/web"}, ImageID: "sha256:live", State: "running"},
+			{ID: "c-old", Names: []string{"/old"}, ImageID: "sha256:old", State: "exited", SizeRw: 100},
+			{ID: "c-kept", Names: []string{"/kept"}, ImageID: "sha256:pinned", State: "exited", Labels: map[string]string{KeepLabel: "true"}},
+			{ID: "c-db", Names: []string{"/proj-db-1"}, State: "exited", Labels: map[string]string{ComposeProjectLabel: "proj"}},
+		},
+		images: []Image{
+			{ID: "sha256:old", Size: 1000},
+			{ID: "sha256:pinned", Size: 2000},
+			{ID: "sha256:labelled", Size: 3000, Labels: map[string]string{KeepLabel: "true"}},
+		},
+		df: DiskUsage{
+			Volumes: []Volume{
+				{Name: anonVolume, UsageData: &VolumeUsage{Size: 10}},
+				{Name: "named", UsageData: &VolumeUsage{Size: 20}},
+				{Name: "used", UsageData: &VolumeUsage{Size: 30, RefCount: 1}},
+				{Name: "keepme", Labels: map[string]string{KeepLabel: "true"}, UsageData: &VolumeUsage{Size: 40}},
+				{Name: "proj_data", Labels: map[string]string{ComposeProjectLabel: "proj"}, UsageData: &VolumeUsage{Size: 50}},
+			},
+			BuildCache: []BuildCacheRecord{
+				{ID: "b-free", Type: "regular", Size: 5, LastUsedAt: &lastUsed},
+				{ID: "b-busy", Type: "regular", Size: 6, InUse: true},
+			},
+		},
+	}
+}
+
+// protectProject keeps the resources of the compose project "proj", the way
+// the CLI protects registered workspaces.
+func protectProject(_ string, labels map[string]string) string {
+	if labels[ComposeProjectLabel] == "proj" {
+		return "workspace proj"
+	}
+	return ""
+}
+
+func allKinds() PruneOptions {
+	return PruneOptions{Containers: true, Images: true, Volumes: true, BuildCache: true, Protect: protectProject}
+}
+
+// planIDs returns "kind id" for each candidate, with kept ones followed by
+// their reason.
+func planIDs(list []Candidate, withReason bool) []string {
+	var ids []string
+	for _, c := range list {
+		id := string(c.Kind) + " " + c.ID
+		if withReason {
+			id += ": " + c.Reason
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestPlanPrune(t *testing.T) {
+	c := fakeDaemon(t, newFakeEngine().handler())
+	plan, err := c.PlanPrune(context.Background(), allKinds())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRemove := []string{"container c-old", "image sha256:old", "volume " + anonVolume, "build-cache b-free"}
+	if got := planIDs(plan.Remove, false); !slices.Equal(got, wantRemove) {
+		t.Errorf("Remove = %q\nwant %q", got, wantRemove)
+	}
+	wantKeep := []string{
+		"container c-kept: homekit.keep=true",
+		"container c-db: workspace proj",
+		"image sha256:pinned: used by container kept",
+		"image sha256:labelled: homekit.keep=true",
+		"volume named: named volume (use --all-volumes)",
+		"volume keepme: homekit.keep=true",
+		"volume proj_data: workspace proj",
+	}
+	if got := planIDs(plan.Keep, true); !slices.Equal(got, wantKeep) {
+		t.Errorf("Keep = %q\nwant %q", got, wantKeep)
+	}
+	if got := plan.Reclaimable(); got != 100+1000+10+5 {
+		t.Errorf("Reclaimable = %d", got)
+	}
+}
+
+func TestPlanPruneAllVolumes(t *testing.T) {
+	c := fakeDaemon(t, newFakeEngine().handler())
+	opts := allKinds()
+	opts.AllVolumes = true
+	plan, err := c.PlanPrune(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := planIDs(plan.Remove, false)
+	kept := planIDs(plan.Keep, false)
+	if !slices.Contains(removed, "volume named") {
+		t.Errorf("named volume not removed with AllVolumes: %q", removed)
+	}
+	for _, v := range []string{"volume keepme", "volume proj_data"} {
+		if !slices.Contains(kept, v) {
+			t.Errorf("%s not kept with AllVolumes: %q", v, kept)
+		}
+	}
+}
+
+func TestPlanPruneKinds(t *testing.T) {
+	c := fakeDaemon(t, newFakeEngine().handler())
+	plan, err := c.PlanPrune(context.Background(), PruneOptions{Images: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// stopped containers are not pruned, so their images stay in use
+	if got := planIDs(plan.Remove, false); len(got) != 0 {
+		t.Errorf("Remove = %q, want nothing", got)
+	}
+	if got := planIDs(plan.Keep, false); !slices.Equal(got, []string{"image sha256:old", "image sha256:pinned", "image sha256:labelled"}) {
+		t.Errorf("Keep = %q", got)
+	}
+}
+
+func TestApplyPrune(t *testing.T) {
+	engine := newFakeEngine()
+	engine.fail = map[string]bool{"volume " + anonVolume: true}
+	c := fakeDaemon(t, engine.handler())
+	plan, err := c.PlanPrune(context.Background(), allKinds())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reported []string
+	reclaimed, err := c.ApplyPrune(context.Background(), plan, func(cand Candidate) {
+		reported = append(reported, string(cand.Kind)+" "+cand.ID)
+	})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Fatalf("ApplyPrune error = %v, want the failed volume removal", err)
+	}
+	wantRemoved := []string{"container c-old", "image sha256:old", "build-cache"}
+	if !slices.Equal(engine.removed, wantRemoved) {
+		t.Errorf("removed = %q, want %q", engine.removed, wantRemoved)
+	}
+	if !slices.Equal(reported, []string{"container c-old", "image sha256:old"}) {
+		t.Errorf("onRemoved saw %q", reported)
+	}
+	if reclaimed != 100+1000+7 {
+		t.Errorf("reclaimed = %d", reclaimed)
+	}
+}
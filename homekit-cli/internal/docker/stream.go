@@ -0,0 +1,129 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// Stream identifiers of the multiplexed log and exec format.
+const (
+	streamStdin  = 0
+	streamStdout = 1
+	streamStderr = 2
+	streamSystem = 3
+)
+
+// StdCopy demultiplexes a log or exec stream of a container without a TTY:
+// every frame has an 8-byte header naming the stream and payload length.
+// Frames for stderr go to stderr, everything else to stdout. It returns nil at
+// the end of the stream.
+func StdCopy(stdout, stderr io.Writer, src io.Reader) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(src, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("read stream header: %w", err)
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		var dst io.Writer
+		switch header[0] {
+		case streamStdin, streamStdout:
+			dst = stdout
+		case streamStderr:
+			dst = stderr
+		case streamSystem:
+			msg, _ := io.ReadAll(io.LimitReader(src, size))
+			return fmt.Errorf("docker stream: %s", msg)
+		default:
+			return fmt.Errorf("docker stream: unknown stream id %d", header[0])
+		}
+		if _, err := io.CopyN(dst, src, size); err != nil {
+			return err
+		}
+	}
+}
+
+// copyStream writes a container stream to stdout and stderr: raw when the
+// container has a TTY, demultiplexed otherwise.
+func copyStream(tty bool, stdout, stderr io.Writer, src io.Reader) error {
+	if tty {
+		_, err := io.Copy(stdout, src)
+		return err
+	}
+	return StdCopy(stdout, stderr, src)
+}
+
+// HijackedConn is a connection the daemon took over after an upgrade, used
+// for interactive exec sessions. Reads return the container output; writes
+// go to its stdin.
+type HijackedConn struct {
+	net.Conn
+	reader *bufio.Reader
+	stop   func() bool
+}
+
+func (h *HijackedConn) Read(p []byte) (int, error) {
+	return h.reader.Read(p)
+}
+
+// CloseWrite signals end of input to the container while output keeps
+// flowing.
+func (h *HijackedConn) CloseWrite() error {
+	if cw, ok := h.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+// Close releases the connection.
+func (h *HijackedConn) Close() error {
+	h.stop()
+	return h.Conn.Close()
+}
+
+// hijack sends a request asking the daemon to upgrade the connection to a
+// raw stream. Cancelling ctx closes the connection.
+func (c *Client) hijack(ctx context.Context, method, path string, query url.Values, body any) (*HijackedConn, error) {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("docker API %s %s: %w", method, path, err)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	fail := func(err error) (*HijackedConn, error) {
+		stop()
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if err := req.Write(conn); err != nil {
+		return fail(fmt.Errorf("docker API %s %s: %w", method, path, err))
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return fail(fmt.Errorf("docker API %s %s: %w", method, path, err))
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return fail(readAPIError(resp))
+	}
+	return &HijackedConn{Conn: conn, reader: reader, stop: stop}, nil
+}
@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestStdCopy(t *testing.T) {
+	var src bytes.Buffer
+	src.Write(frame(streamStdout, "out 1\n"))
+	src.Write(frame(streamStderr, "err 1\n"))
+	src.Write(frame(streamStdout, ""))
+	src.Write(frame(streamStdin, "in\n"))
+	var stdout, stderr bytes.Buffer
+	if err := StdCopy(&stdout, &stderr, &src); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "out 1\nin\n" || stderr.String() != "err 1\n" {
+		t.Fatalf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+
+	for name, input := range map[string][]byte{
+		"system":           frame(streamSystem, "exec failed"),
+		"unknown stream":   frame(7, "x"),
+		"truncated header": frame(streamStdout, "x")[:5],
+		"short payload":    frame(streamStdout, "hello")[:10],
+	} {
+		if err := StdCopy(io.Discard, io.Discard, bytes.NewReader(input)); err == nil {
+			t.Errorf("%s: StdCopy succeeded", name)
+		}
+	}
+}
+
+// logsDaemon serves a container with the given TTY setting whose logs are
+// body, optionally followed by a stream that stays open until the client
+// goes away.
+func logsDaemon(t *testing.T, tty bool, body []byte, follow bool) *Client {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v"+APIVersion+"/containers/web/json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]any{"Id": "abc", "Config": map[string]any{"Tty": tty}})
+	})
+	mux.HandleFunc("GET /v"+APIVersion+"/containers/abc/logs", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("stdout") != "1" || q.Get("stderr") != "1" || q.Get("tail") != "10" {
+			http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		w.Write(body)
+		if q.Get("follow") != "1" {
+			return
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	return fakeDaemon(t, mux)
+}
+
+func TestCopyLogs(t *testing.T) {
+	body := append(frame(streamStdout, "ready\n"), frame(streamStderr, "warning\n")...)
+	c := logsDaemon(t, false, body, false)
+	var stdout, stderr bytes.Buffer
+	if err := c.CopyLogs(context.Background(), "web", LogsOptions{Tail: "10"}, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "ready\n" || stderr.String() != "warning\n" {
+		t.Fatalf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+}
+
+func TestCopyLogsTTY(t *testing.T) {
+	c := logsDaemon(t, true, []byte("raw output\n"), false)
+	var stdout bytes.Buffer
+	if err := c.CopyLogs(context.Background(), "web", LogsOptions{Tail: "10"}, &stdout, io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "raw output\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+}
+
+// cancelWriter cancels a context once it receives data. It does not embed
+// the buffer, whose ReadFrom would bypass Write.
+type cancelWriter struct {
+	buf    bytes.Buffer
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	defer w.cancel()
+	return w.buf.Write(p)
+}
+
+func TestCopyLogsFollowCanceled(t *testing.T) {
+	c := logsDaemon(t, false, frame(streamStdout, "first\n"), true)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stdout := &cancelWriter{cancel: cancel}
+	err := c.CopyLogs(ctx, "web", LogsOptions{Follow: true, Tail: "10"}, stdout, io.Discard)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("CopyLogs = %v, want context.Canceled", err)
+	}
+	if stdout.buf.String() != "first\n" {
+		t.Fatalf("stdout = %q", stdout.buf.String())
+	}
+}
@@ -0,0 +1,46 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// BuildCacheRecord is one entry of the builder cache.
+type BuildCacheRecord struct {
+	ID          string     `json:"ID"`
+	Type        string     `json:"Type"`
+	Description string     `json:"Description"`
+	InUse       bool       `json:"InUse"`
+	Shared      bool       `json:"Shared"`
+	Size        int64      `json:"Size"`
+	LastUsedAt  *time.Time `json:"LastUsedAt"`
+}
+
+// DiskUsage is the daemon's view of space used by each resource type.
+type DiskUsage struct {
+	LayersSize int64              `json:"LayersSize"`
+	Images     []Image            `json:"Images"`
+	Containers []Container        `json:"Containers"`
+	Volumes    []Volume           `json:"Volumes"`
+	BuildCache []BuildCacheRecord `json:"BuildCache"`
+}
+
+// DiskUsage returns sizes of images, containers, volumes and build cache.
+func (c *Client) DiskUsage(ctx context.Context) (*DiskUsage, error) {
+	var out DiskUsage
+	if err := c.call(ctx, http.MethodGet, "/system/df", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// BuildCachePrune removes every build cache record that is not in use and
+// returns the space reclaimed.
+func (c *Client) BuildCachePrune(ctx context.Context) (int64, error) {
+	var out struct {
+		SpaceReclaimed int64 `json:"SpaceReclaimed"`
+	}
+	err := c.call(ctx, http.MethodPost, "/build/prune", nil, nil, &out)
+	return out.SpaceReclaimed, err
+}
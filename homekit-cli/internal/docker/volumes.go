@@ -0,0 +1,47 @@
+package docker
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Volume describes a volume. UsageData is only filled by DiskUsage.
+type Volume struct {
+	Name       string            `json:"Name"`
+	Driver     string            `json:"Driver"`
+	Mountpoint string            `json:"Mountpoint"`
+	Labels     map[string]string `json:"Labels"`
+	UsageData  *VolumeUsage      `json:"UsageData"`
+}
+
+// VolumeUsage is the size and reference count of a volume; -1 means unknown.
+type VolumeUsage struct {
+	Size     int64 `json:"Size"`
+	RefCount int64 `json:"RefCount"`
+}
+
+// VolumeList lists volumes.
+func (c *Client) VolumeList(ctx context.Context, filters Filters) ([]Volume, error) {
+	q := url.Values{}
+	filters.encode(q)
+	var out struct {
+		Volumes []Volume `json:"Volumes"`
+	}
+	err := c.call(ctx, http.MethodGet, "/volumes", q, nil, &out)
+	return out.Volumes, err
+}
+
+// VolumeInspect returns a volume by name.
+func (c *Client) VolumeInspect(ctx context.Context, name string) (*Volume, error) {
+	var out Volume
+	if err := c.call(ctx, http.MethodGet, "/volumes/"+url.PathEscape(name), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// VolumeRemove deletes a volume that no container uses.
+func (c *Client) VolumeRemove(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), nil, nil, nil)
+}
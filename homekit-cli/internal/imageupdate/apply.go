@@ -0,0 +1,194 @@
+package imageupdate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/homekit/homekit-cli/internal/docker"
+	"github.com/homekit/homekit-cli/internal/registry"
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+)
+
+// Applier pulls new images for one compose file, recreates the services and
+// rolls back when they do not become healthy.
+type Applier struct {
+	Docker *docker.Client
+	// Compose runs `docker compose -f file <args>` in the file's directory.
+	Compose func(ctx context.Context, file string, args ...string) error
+	// HealthTimeout bounds the wait for recreated services.
+	HealthTimeout time.Duration
+	// PollInterval defaults to two seconds.
+	PollInterval time.Duration
+	// Logf reports progress; it may be nil.
+	Logf func(format string, args ...any)
+}
+
+// Apply performs the pending updates of one compose file:
+//
+//  1. rewrite changed tags in the compose file,
+//  2. pull every target image,
+//  3. `docker compose up -d` the affected services,
+//  4. wait until their containers are healthy (or running, without a
+//     healthcheck).
+//
+// When any step fails, the compose file is restored, the previous images are
+// tagged again and the services are recreated from them.
+func (a Applier) Apply(ctx context.Context, file string, updates []Update) error {
+	logf := a.Logf
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	original, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	previous := map[string]string{}
+	changes := map[int][2]string{}
+	var services []string
+	for _, u := range updates {
+		if !u.Pending() || u.File != file {
+			continue
+		}
+		services = append(services, u.Service)
+		if img, err := a.Docker.ImageInspect(ctx, u.Image); err == nil {
+			previous[u.Image] = img.ID
+		} else if !errors.Is(err, docker.ErrNotFound) {
+			return err
+		}
+		if u.Kind == KindTag {
+			changes[u.Line] = [2]string{u.Image, u.Target}
+		}
+	}
+	if len(services) == 0 {
+		return nil
+	}
+
+	rollback := func(cause error) error {
+		// keep rolling back when the caller's context was cancelled
+		ctx := context.WithoutCancel(ctx)
+		errs := []error{cause}
+		if len(changes) > 0 {
+			if err := fileutil.WriteAtomic(file, original, fileutil.ModeOr(file, 0o644)); err != nil {
+				errs = append(errs, fmt.Errorf("restore %s: %w", file, err))
+			}
+		}
+		for image, id := range previous {
+			ref, err := registry.ParseReference(image)
+			if err == nil {
+				err = a.Docker.ImageTag(ctx, id, ref.Name, ref.Tag)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("re-tag %s: %w", image, err))
+			}
+		}
+		if err := a.Compose(ctx, file, append([]string{"up", "-d"}, services...)...); err != nil {
+			errs = append(errs, fmt.Errorf("recreate previous services: %w", err))
+		}
+		logf("Rolled back %s", file)
+		return fmt.Errorf("update %s rolled back: %w", file, errors.Join(errs...))
+	}
+
+	if len(changes) > 0 {
+		updated, err := rewriteImages(original, changes)
+		if err != nil {
+			return fmt.Errorf("update %s: %w", file, err)
+		}
+		if err := fileutil.WriteAtomic(file, updated, fileutil.ModeOr(file, 0o644)); err != nil {
+			return err
+		}
+	}
+	for _, u := range updates {
+		if !u.Pending() || u.File != file {
+			continue
+		}
+		logf("Pulling %s", u.Target)
+		if err := a.Docker.ImagePull(ctx, u.Target, nil); err != nil {
+			return rollback(err)
+		}
+	}
+	logf("Recreating %v from %s", services, file)
+	if err := a.Compose(ctx, file, append([]string{"up", "-d"}, services...)...); err != nil {
+		return rollback(err)
+	}
+	if err := a.waitHealthy(ctx, file, services); err != nil {
+		return rollback(err)
+	}
+	return nil
+}
+
+// waitHealthy polls the containers compose created for the services.
+func (a Applier) waitHealthy(ctx context.Context, file string, services []string) error {
+	timeout, poll := a.HealthTimeout, a.PollInterval
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	if poll <= 0 {
+		poll = 2 * time.Second
+	}
+	dir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pending := append([]string(nil), services...)
+	for {
+		var waiting []string
+		for _, svc := range pending {
+			ready, err := a.serviceReady(ctx, dir, svc)
+			if err != nil {
+				return err
+			}
+			if !ready {
+				waiting = append(waiting, svc)
+			}
+		}
+		if len(waiting) == 0 {
+			return nil
+		}
+		pending = waiting
+
+		timer := time.NewTimer(poll)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("services %v not healthy after %s", pending, timeout)
+		case <-timer.C:
+		}
+	}
+}
+
+// serviceReady reports whether every container of the service is healthy,
+// or running when it has no healthcheck. Unhealthy or exited containers fail.
+func (a Applier) serviceReady(ctx context.Context, dir, service string) (bool, error) {
+	containers, err := a.Docker.ContainerList(ctx, docker.ContainerListOptions{
+		All:     true,
+		Filters: docker.Filters{"label": {docker.ComposeWorkingDirLabel + "=" + dir, docker.ComposeServiceLabel + "=" + service}},
+	})
+	if err != nil || len(containers) == 0 {
+		return false, err
+	}
+	for _, c := range containers {
+		details, err := a.Docker.ContainerInspect(ctx, c.ID)
+		if err != nil {
+			return false, err
+		}
+		switch health := details.HealthStatus(); {
+		case health == "unhealthy":
+			return false, fmt.Errorf("service %s: container %s is unhealthy", service, c.Name())
+		case health == "starting", details.State.Restarting:
+			return false, nil
+		case !details.State.Running:
+			return false, fmt.Errorf("service %s: container %s exited with code %d", service, c.Name(), details.State.ExitCode)
+		case health != "" && health != "healthy":
+			return false, nil
+		}
+	}
+	return true, nil
+}
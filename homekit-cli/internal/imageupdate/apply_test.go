@@ -0,0 +1,160 @@
+package imageupdate
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/homekit/homekit-cli/internal/docker"
+)
+
+const composeFile = `services:
+  app:
+    image: example/app:1.0 # pinned by the update command
+    restart: unless-stopped
+`
+
+// fakeDocker is an Engine API on a unix socket holding example/app:1.0.
+// Pulls fail with pullError and the recreated container is running when
+// healthy is set, exited otherwise. It records tag calls.
+type fakeDocker struct {
+	pullError string
+	healthy   bool
+
+	mu   sync.Mutex
+	tags []string
+}
+
+func (f *fakeDocker) client(t *testing.T) *docker.Client {
+	t.Helper()
+	prefix := "/v" + docker.APIVersion
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, prefix)
+		switch {
+		case r.Method == http.MethodGet && path == "/images/example/app:1.0/json":
+			json.NewEncoder(w).Encode(map[string]string{"Id": "sha256:previous"})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/images/"):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "no such image"})
+		case r.Method == http.MethodPost && path == "/images/create":
+			json.NewEncoder(w).Encode(docker.PullMessage{Status: "Pulling " + r.URL.Query().Get("fromImage")})
+			if f.pullError != "" {
+				json.NewEncoder(w).Encode(docker.PullMessage{Error: f.pullError})
+			}
+		case r.Method == http.MethodPost && strings.HasSuffix(path, "/tag"):
+			f.mu.Lock()
+			f.tags = append(f.tags, strings.TrimSuffix(strings.TrimPrefix(path, "/images/"), "/tag")+" "+r.URL.Query().Get("repo")+":"+r.URL.Query().Get("tag"))
+			f.mu.Unlock()
+			w.WriteHeader(http.StatusCreated)
+		case path == "/containers/json":
+			json.NewEncoder(w).Encode([]docker.Container{{ID: "c1", Names: []string{"/app-app-1"}}})
+		case path == "/containers/c1/json":
+			json.NewEncoder(w).Encode(map[string]any{"Id": "c1", "State": map[string]any{"Running": f.healthy, "ExitCode": 1}})
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+path, http.StatusNotImplemented)
+		}
+	})
+
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener.Close()
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	c, err := docker.NewClient("unix://" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// applyUpdate moves app from 1.0 to 1.1 and returns the compose file
+// afterwards and the compose invocations.
+func applyUpdate(t *testing.T, f *fakeDocker) (string, []string, error) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "compose.yaml")
+	if err := os.WriteFile(file, []byte(composeFile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	usages, _, err := ScanCompose(file, PolicyMinor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := []Update{{Usage: usages[0], Kind: KindTag, Target: "example/app:1.1"}}
+
+	var calls []string
+	a := Applier{
+		Docker: f.client(t),
+		Compose: func(_ context.Context, _ string, args ...string) error {
+			calls = append(calls, strings.Join(args, " "))
+			return nil
+		},
+		HealthTimeout: time.Second,
+		PollInterval:  10 * time.Millisecond,
+	}
+	err = a.Apply(context.Background(), file, updates)
+	content, readErr := os.ReadFile(file)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return string(content), calls, err
+}
+
+func TestApply(t *testing.T) {
+	f := &fakeDocker{healthy: true}
+	content, calls, err := applyUpdate(t, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.Replace(composeFile, "app:1.0", "app:1.1", 1); content != want {
+		t.Errorf("compose file:\n%s\nwant:\n%s", content, want)
+	}
+	if !slices.Equal(calls, []string{"up -d app"}) || len(f.tags) != 0 {
+		t.Errorf("compose calls = %q, tags = %q", calls, f.tags)
+	}
+}
+
+func TestApplyRollsBackUnhealthyServices(t *testing.T) {
+	f := &fakeDocker{}
+	content, calls, err := applyUpdate(t, f)
+	if err == nil || !strings.Contains(err.Error(), "rolled back") || !strings.Contains(err.Error(), "exited with code 1") {
+		t.Fatalf("Apply = %v, want a rollback after the container exited", err)
+	}
+	if content != composeFile {
+		t.Errorf("compose file not restored:\n%s", content)
+	}
+	if want := []string{"sha256:previous example/app:1.0"}; !slices.Equal(f.tags, want) {
+		t.Errorf("tags = %q, want %q", f.tags, want)
+	}
+	if want := []string{"up -d app", "up -d app"}; !slices.Equal(calls, want) {
+		t.Errorf("compose calls = %q, want %q", calls, want)
+	}
+}
+
+func TestApplyRollsBackFailedPull(t *testing.T) {
+	f := &fakeDocker{pullError: "manifest unknown", healthy: true}
+	content, calls, err := applyUpdate(t, f)
+	if err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Fatalf("Apply = %v, want the pull error", err)
+	}
+	if content != composeFile {
+		t.Errorf("compose file not restored:\n%s", content)
+	}
+	// services were never recreated from the new image, only from the old one
+	if want := []string{"up -d app"}; !slices.Equal(calls, want) {
+		t.Errorf("compose calls = %q, want %q", calls, want)
+	}
+}
@@ -0,0 +1,114 @@
+package imageupdate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/homekit/homekit-cli/internal/registry"
+)
+
+// Kind is the type of a pending update.
+type Kind string
+
+const (
+	// KindTag moves the service to a newer version tag.
+	KindTag Kind = "tag"
+	// KindDigest pulls a new image published under the same tag.
+	KindDigest Kind = "digest"
+)
+
+// Update is the planned change of one Usage. Kind is empty when the image is
+// up to date, pinned or could not be checked (Error).
+type Update struct {
+	Usage
+	Kind         Kind   `json:"kind,omitempty"`
+	Target       string `json:"target,omitempty"`
+	LocalDigest  string `json:"local_digest,omitempty"`
+	RemoteDigest string `json:"remote_digest,omitempty"`
+	Note         string `json:"note"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Pending reports whether the update changes anything.
+func (u Update) Pending() bool {
+	return u.Kind != ""
+}
+
+// Planner checks usages against their registries.
+type Planner struct {
+	Registry *registry.Client
+	// LocalDigests returns the repo digests (name@sha256:...) of a local
+	// image, or nil when the image has not been pulled.
+	LocalDigests func(ctx context.Context, image string) ([]string, error)
+}
+
+// Plan checks every usage. Registry answers are shared between services
+// using the same repository.
+func (p Planner) Plan(ctx context.Context, usages []Usage) []Update {
+	tags := map[string][]string{}
+	digests := map[string]string{}
+	updates := make([]Update, 0, len(usages))
+	for _, u := range usages {
+		up := Update{Usage: u}
+		if err := p.plan(ctx, &up, tags, digests); err != nil {
+			up.Error = err.Error()
+		}
+		updates = append(updates, up)
+	}
+	return updates
+}
+
+func (p Planner) plan(ctx context.Context, up *Update, tags map[string][]string, digests map[string]string) error {
+	ref := up.Ref
+	if up.Policy == PolicyPinned {
+		up.Note = "pinned"
+		return nil
+	}
+
+	if IsVersionTag(ref.Tag) {
+		list, ok := tags[ref.Repository()]
+		if !ok {
+			var err error
+			if list, err = p.Registry.Tags(ctx, ref); err != nil {
+				return err
+			}
+			tags[ref.Repository()] = list
+		}
+		if newest, ok := NewestTag(ref.Tag, list, up.Policy); ok {
+			up.Kind, up.Target = KindTag, ref.WithTag(newest)
+			up.Note = fmt.Sprintf("%s -> %s", ref.Tag, newest)
+			return nil
+		}
+	}
+
+	key := ref.Repository() + ":" + ref.Tag
+	remote, ok := digests[key]
+	if !ok {
+		var err error
+		if remote, err = p.Registry.Digest(ctx, ref, ref.Tag); err != nil {
+			return err
+		}
+		digests[key] = remote
+	}
+	up.RemoteDigest = remote
+
+	locals, err := p.LocalDigests(ctx, up.Image)
+	if err != nil {
+		return err
+	}
+	if len(locals) == 0 {
+		up.Kind, up.Target, up.Note = KindDigest, up.Image, "not pulled yet"
+		return nil
+	}
+	for _, d := range locals {
+		_, digest, _ := strings.Cut(d, "@")
+		up.LocalDigest = digest
+		if digest == remote {
+			up.Note = "up to date"
+			return nil
+		}
+	}
+	up.Kind, up.Target, up.Note = KindDigest, up.Image, "new image for tag "+ref.Tag
+	return nil
+}
@@ -0,0 +1,129 @@
+// Package imageupdate finds newer images for compose services and applies
+// the updates with a health-checked rollback.
+package imageupdate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Policy limits how far a tag may move.
+type Policy string
+
+const (
+	// PolicyPatch allows 1.2.3 -> 1.2.9.
+	PolicyPatch Policy = "patch"
+	// PolicyMinor allows 1.2.3 -> 1.9.0.
+	PolicyMinor Policy = "minor"
+	// PolicyMajor allows any newer version.
+	PolicyMajor Policy = "major"
+	// PolicyPinned never changes the image.
+	PolicyPinned Policy = "pinned"
+)
+
+// PolicyLabel overrides the policy of a compose service.
+const PolicyLabel = "homekit.update"
+
+// ParsePolicy validates a policy name.
+func ParsePolicy(s string) (Policy, error) {
+	switch p := Policy(strings.ToLower(strings.TrimSpace(s))); p {
+	case PolicyPatch, PolicyMinor, PolicyMajor, PolicyPinned:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown update policy %q (patch|minor|major|pinned)", s)
+	}
+}
+
+// version is a numeric tag such as v1.2.3 or 1.25-alpine. Prefix and
+// variant must match for two tags to be comparable, as must the number of
+// numeric parts, so 1.25-alpine only moves to other X.Y-alpine tags.
+type version struct {
+	prefix  string
+	parts   []int
+	variant string
+}
+
+var versionPattern = regexp.MustCompile(`^(v?)(\d+(?:\.\d+){0,2})(-[0-9A-Za-z][0-9A-Za-z.-]*)?$`)
+
+func parseVersion(tag string) (version, bool) {
+	m := versionPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return version{}, false
+	}
+	v := version{prefix: m[1], variant: m[3]}
+	for _, part := range strings.Split(m[2], ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return version{}, false
+		}
+		v.parts = append(v.parts, n)
+	}
+	return v, true
+}
+
+func (v version) comparable(o version) bool {
+	return v.prefix == o.prefix && v.variant == o.variant && len(v.parts) == len(o.parts)
+}
+
+func (v version) compare(o version) int {
+	for i := range v.parts {
+		switch {
+		case v.parts[i] < o.parts[i]:
+			return -1
+		case v.parts[i] > o.parts[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// allows reports whether moving from v to o stays within the policy.
+func (v version) allows(o version, p Policy) bool {
+	fixed := 0
+	switch p {
+	case PolicyPatch:
+		fixed = 2
+	case PolicyMinor:
+		fixed = 1
+	case PolicyMajor:
+		fixed = 0
+	default:
+		return false
+	}
+	for i := 0; i < fixed && i < len(v.parts); i++ {
+		if v.parts[i] != o.parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewestTag returns the highest tag in tags that current may move to under
+// the policy. ok is false when current is not a version tag or nothing newer
+// is allowed.
+func NewestTag(current string, tags []string, p Policy) (string, bool) {
+	cur, isVersion := parseVersion(current)
+	if !isVersion {
+		return "", false
+	}
+	best, bestTag := cur, ""
+	for _, tag := range tags {
+		v, ok := parseVersion(tag)
+		if !ok || !cur.comparable(v) || !cur.allows(v, p) {
+			continue
+		}
+		if v.compare(best) > 0 {
+			best, bestTag = v, tag
+		}
+	}
+	return bestTag, bestTag != ""
+}
+
+// IsVersionTag reports whether tag is a numeric version tag that policies
+// apply to; other tags, such as latest, are checked by digest.
+func IsVersionTag(tag string) bool {
+	_, ok := parseVersion(tag)
+	return ok
+}
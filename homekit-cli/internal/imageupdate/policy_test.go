@@ -0,0 +1,48 @@
+package imageupdate
+
+import "testing"
+
+func TestNewestTag(t *testing.T) {
+	semver := []string{"1.2.2", "1.2.3", "1.2.4", "1.2.10", "1.3.0", "1.10.1", "2.0.0", "latest", "1.2.11-rc1", "1.2.12rc1"}
+	tests := []struct {
+		name    string
+		current string
+		tags    []string
+		policy  Policy
+		want    string
+	}{
+		{"patch", "1.2.3", semver, PolicyPatch, "1.2.10"},
+		{"minor", "1.2.3", semver, PolicyMinor, "1.10.1"},
+		{"major", "1.2.3", semver, PolicyMajor, "2.0.0"},
+		{"pinned", "1.2.3", semver, PolicyPinned, ""},
+		{"unknown policy", "1.2.3", semver, Policy("weekly"), ""},
+		{"already newest", "2.0.0", semver, PolicyMajor, ""},
+		{"no older tags", "1.2.3", []string{"1.2.3", "1.2.2", "1.1.9"}, PolicyMajor, ""},
+		{"numeric order", "3", []string{"4", "10", "9"}, PolicyMajor, "10"},
+		{"not a version", "latest", semver, PolicyMajor, ""},
+		{"variant kept", "1.25-alpine", []string{"1.26", "1.26-alpine", "1.25.3-alpine", "1.27-alpine-slim"}, PolicyMinor, "1.26-alpine"},
+		{"prefix kept", "v1.2", []string{"1.3", "v1.3", "v1.2.1"}, PolicyMinor, "v1.3"},
+		{"component count kept", "1.25", []string{"1.25.1", "1.26", "2"}, PolicyMajor, "1.26"},
+		{"patch on two components", "1.25", []string{"1.26"}, PolicyPatch, ""},
+		{"minor on one component", "7", []string{"8"}, PolicyMinor, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NewestTag(tt.current, tt.tags, tt.policy)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Fatalf("NewestTag(%q, %s) = %q, %t; want %q", tt.current, tt.policy, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for in, want := range map[string]Policy{"patch": PolicyPatch, " Minor ": PolicyMinor, "MAJOR": PolicyMajor, "pinned": PolicyPinned} {
+		if got, err := ParsePolicy(in); err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParsePolicy("latest"); err == nil {
+		t.Error("ParsePolicy accepted latest")
+	}
+}
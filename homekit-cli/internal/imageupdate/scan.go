@@ -0,0 +1,107 @@
+package imageupdate
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/homekit/homekit-cli/internal/registry"
+)
+
+// Usage is one compose service image.
+type Usage struct {
+	File    string `json:"file"`
+	Service string `json:"service"`
+	Image   string `json:"image"`
+	Policy  Policy `json:"policy"`
+	// Line is the line of the image value, used to rewrite it.
+	Line int                `json:"-"`
+	Ref  registry.Reference `json:"-"`
+}
+
+type composeService struct {
+	Image  yaml.Node `yaml:"image"`
+	Labels yaml.Node `yaml:"labels"`
+}
+
+// ScanCompose returns the images of every service in a compose file. The
+// policy comes from the service's homekit.update label, else defaultPolicy.
+// Services without an image (build-only) are skipped; images that are not
+// plain references, such as ones using ${VAR}, are returned in skipped.
+func ScanCompose(path string, defaultPolicy Policy) (usages []Usage, skipped []string, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var doc struct {
+		Services map[string]composeService `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	names := make([]string, 0, len(doc.Services))
+	for name := range doc.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		svc := doc.Services[name]
+		image := strings.TrimSpace(svc.Image.Value)
+		if image == "" {
+			continue
+		}
+		ref, err := registry.ParseReference(image)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: service %s: %v", path, name, err))
+			continue
+		}
+		policy := defaultPolicy
+		if label := serviceLabel(svc.Labels, PolicyLabel); label != "" {
+			if policy, err = ParsePolicy(label); err != nil {
+				return nil, nil, fmt.Errorf("%s: service %s: %w", path, name, err)
+			}
+		}
+		if ref.Digest != "" {
+			policy = PolicyPinned
+		}
+		usages = append(usages, Usage{File: path, Service: name, Image: image, Policy: policy, Line: svc.Image.Line, Ref: ref})
+	}
+	return usages, skipped, nil
+}
+
+// serviceLabel reads a label from either compose form: a mapping or a list
+// of key=value strings.
+func serviceLabel(labels yaml.Node, key string) string {
+	switch labels.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(labels.Content); i += 2 {
+			if labels.Content[i].Value == key {
+				return labels.Content[i+1].Value
+			}
+		}
+	case yaml.SequenceNode:
+		for _, item := range labels.Content {
+			if k, v, ok := strings.Cut(item.Value, "="); ok && k == key {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+// rewriteImages replaces image values on their recorded lines, leaving the
+// rest of the file untouched.
+func rewriteImages(content []byte, changes map[int][2]string) ([]byte, error) {
+	lines := strings.SplitAfter(string(content), "\n")
+	for line, change := range changes {
+		if line < 1 || line > len(lines) || !strings.Contains(lines[line-1], change[0]) {
+			return nil, fmt.Errorf("line %d no longer contains %s", line, change[0])
+		}
+		lines[line-1] = strings.Replace(lines[line-1], change[0], change[1], 1)
+	}
+	return []byte(strings.Join(lines, "")), nil
+}
@@ -0,0 +1,224 @@
+// Package proxy turns homekit.expose.* labels on compose services into
+// reverse-proxy routes, which embedded templates render as a Caddyfile or a
+// Traefik dynamic configuration.
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/homekit/homekit-cli/internal/compose"
+)
+
+// Labels read from compose services.
+const (
+	// LabelHost lists the host names to route, comma-separated. A service is
+	// exposed when it has this label.
+	LabelHost = "homekit.expose.host"
+	// LabelPort is the container port to proxy to. It may be omitted when the
+	// service maps exactly one port.
+	LabelPort = "homekit.expose.port"
+	// LabelAuth names a Caddy snippet to import or a Traefik middleware to
+	// apply in front of the service.
+	LabelAuth = "homekit.expose.auth"
+	// LabelTLS is one of the TLS modes.
+	LabelTLS = "homekit.expose.tls"
+)
+
+// TLS modes.
+const (
+	// TLSAuto obtains certificates from an ACME issuer.
+	TLSAuto = "auto"
+	// TLSInternal uses the proxy's own CA, for names that are not public.
+	TLSInternal = "internal"
+	// TLSOff serves plain HTTP.
+	TLSOff = "off"
+)
+
+// Upstream modes select how the proxy reaches a service.
+const (
+	// UpstreamContainer addresses the container by its container_name or
+	// compose name (<project>-<service>-1), for a proxy on a shared docker
+	// network.
+	UpstreamContainer = "container"
+	// UpstreamHost addresses the published port on the host, for a proxy
+	// running outside docker.
+	UpstreamHost = "host"
+)
+
+// Proxy types with an embedded template.
+const (
+	TypeCaddy   = "caddy"
+	TypeTraefik = "traefik"
+)
+
+// ErrInvalidLabel is wrapped by errors about homekit.expose.* labels.
+var ErrInvalidLabel = errors.New("invalid expose label")
+
+// Label values end up verbatim in the generated configuration, so they are
+// restricted to characters that cannot break out of a Caddyfile or YAML token.
+var (
+	// hostPattern matches a DNS name, optionally a wildcard (*.example.com).
+	hostPattern = regexp.MustCompile(`^(\*\.)?([A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?\.)*[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+	// authPattern matches Caddy snippet and Traefik middleware names.
+	authPattern = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+)
+
+// Route is one exposed service.
+type Route struct {
+	// Name is unique across routes: <project>-<service>.
+	Name     string   `json:"name"`
+	Project  string   `json:"project"`
+	Service  string   `json:"service"`
+	Hosts    []string `json:"hosts"`
+	Upstream string   `json:"upstream"`
+	Auth     string   `json:"auth,omitempty"`
+	TLS      string   `json:"tls"`
+	// File is the compose file the route comes from.
+	File string `json:"file"`
+}
+
+// ProjectName returns the compose project name of p: its name key, else
+// the name of the directory holding the file, as docker compose derives it.
+func ProjectName(p *compose.Project) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	abs, err := filepath.Abs(p.File)
+	if err != nil {
+		abs = p.File
+	}
+	return compose.NormalizeProjectName(filepath.Base(filepath.Dir(abs)))
+}
+
+// Routes returns the routes of the exposed services in p, sorted by name.
+func Routes(p *compose.Project, upstream string) ([]Route, error) {
+	project := ProjectName(p)
+	var routes []Route
+	var errs []error
+	for _, s := range p.Services {
+		if _, ok := s.Labels[LabelHost]; !ok {
+			continue
+		}
+		r, err := route(project, s, upstream)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: service %s: %w", p.File, s.Name, err))
+			continue
+		}
+		r.File = p.File
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Name < routes[j].Name })
+	return routes, errors.Join(errs...)
+}
+
+func route(project string, s *compose.Service, upstream string) (Route, error) {
+	r := Route{
+		Name:    project + "-" + s.Name,
+		Project: project,
+		Service: s.Name,
+		Auth:    strings.TrimSpace(s.Labels[LabelAuth]),
+		TLS:     strings.TrimSpace(s.Labels[LabelTLS]),
+	}
+	for _, h := range strings.Split(s.Labels[LabelHost], ",") {
+		if h = strings.TrimSpace(h); h == "" {
+			continue
+		}
+		if len(h) > 253 || !hostPattern.MatchString(h) {
+			return Route{}, fmt.Errorf("%w: %s: %q is not a host name", ErrInvalidLabel, LabelHost, h)
+		}
+		r.Hosts = append(r.Hosts, h)
+	}
+	if len(r.Hosts) == 0 {
+		return Route{}, fmt.Errorf("%w: %s is empty", ErrInvalidLabel, LabelHost)
+	}
+	if r.Auth != "" && !authPattern.MatchString(r.Auth) {
+		return Route{}, fmt.Errorf("%w: %s=%q may only contain letters, digits and _.@-", ErrInvalidLabel, LabelAuth, r.Auth)
+	}
+	switch r.TLS {
+	case "":
+		r.TLS = TLSAuto
+	case TLSAuto, TLSInternal, TLSOff:
+	default:
+		return Route{}, fmt.Errorf("%w: %s=%q (auto|internal|off)", ErrInvalidLabel, LabelTLS, r.TLS)
+	}
+
+	port, err := containerPort(s)
+	if err != nil {
+		return Route{}, err
+	}
+	switch upstream {
+	case UpstreamContainer, "":
+		name := s.ContainerName
+		if name == "" {
+			name = fmt.Sprintf("%s-%s-1", project, s.Name)
+		}
+		r.Upstream = name + ":" + port
+	case UpstreamHost:
+		addr, err := publishedAddress(s, port)
+		if err != nil {
+			return Route{}, err
+		}
+		r.Upstream = addr
+	default:
+		return Route{}, fmt.Errorf("unknown upstream mode %q (container|host)", upstream)
+	}
+	return r, nil
+}
+
+// containerPort returns the port label, or the target of the only mapped
+// port.
+func containerPort(s *compose.Service) (string, error) {
+	if label := strings.TrimSpace(s.Labels[LabelPort]); label != "" {
+		if n, err := strconv.Atoi(label); err != nil || n < 1 || n > 65535 {
+			return "", fmt.Errorf("%w: %s=%q is not a port number", ErrInvalidLabel, LabelPort, label)
+		}
+		return label, nil
+	}
+	if len(s.Ports) == 1 && s.Ports[0].Err == "" && !strings.Contains(s.Ports[0].Target, "-") {
+		return s.Ports[0].Target, nil
+	}
+	return "", fmt.Errorf("%w: set %s, the service maps %d ports", ErrInvalidLabel, LabelPort, len(s.Ports))
+}
+
+// publishedAddress returns the host address a container port is published
+// on. Wildcard addresses are reached through the loopback interface.
+func publishedAddress(s *compose.Service, target string) (string, error) {
+	for _, p := range s.Ports {
+		if p.Err != "" || p.Target != target || p.Published == "" || p.Protocol != "tcp" || strings.Contains(p.Published, "-") {
+			continue
+		}
+		switch p.HostIP {
+		case "", "0.0.0.0":
+			return "127.0.0.1:" + p.Published, nil
+		case "::":
+			return "[::1]:" + p.Published, nil
+		}
+		if strings.Contains(p.HostIP, ":") {
+			return "[" + p.HostIP + "]:" + p.Published, nil
+		}
+		return p.HostIP + ":" + p.Published, nil
+	}
+	return "", fmt.Errorf("port %s is not published on the host, which upstream mode %s needs", target, UpstreamHost)
+}
+
+// CheckHosts returns an error for host names claimed by more than one route.
+func CheckHosts(routes []Route) error {
+	owner := map[string]string{}
+	var errs []error
+	for _, r := range routes {
+		for _, h := range r.Hosts {
+			if other, ok := owner[h]; ok {
+				errs = append(errs, fmt.Errorf("host %s is exposed by both %s and %s", h, other, r.Name))
+				continue
+			}
+			owner[h] = r.Name
+		}
+	}
+	return errors.Join(errs...)
+}
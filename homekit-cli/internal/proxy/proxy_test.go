@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/homekit/homekit-cli/internal/compose"
+)
+
+func parse(t *testing.T, content string) *compose.Project {
+	t.Helper()
+	p, _, err := compose.Parse("/srv/app/compose.yaml", []byte(content), func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestRoutesValidatesLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		labels string
+		ok     bool
+	}{
+		{"host", `homekit.expose.host: app.example.com`, true},
+		{"hosts", `homekit.expose.host: "a.example.com, b.example.com"`, true},
+		{"wildcard", `homekit.expose.host: "*.example.com"`, true},
+		{"single label", `homekit.expose.host: localhost`, true},
+		{"auth", "homekit.expose.host: a.example.com\n      homekit.expose.auth: basic-auth@file", true},
+		{"empty host", `homekit.expose.host: ""`, false},
+		{"inner wildcard", `homekit.expose.host: "a.*.example.com"`, false},
+		{"brace", `homekit.expose.host: "a.example.com {"`, false},
+		{"space", `homekit.expose.host: "a.example.com b.example.com"`, false},
+		{"leading dash", `homekit.expose.host: -a.example.com`, false},
+		{"trailing dot", `homekit.expose.host: a.example.com.`, false},
+		{"auth newline", "homekit.expose.host: a.example.com\n      homekit.expose.auth: \"x\\n}\"", false},
+		{"auth space", "homekit.expose.host: a.example.com\n      homekit.expose.auth: \"a b\"", false},
+		{"tls", "homekit.expose.host: a.example.com\n      homekit.expose.tls: on", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := parse(t, "services:\n  web:\n    image: nginx\n    ports: [\"8080:80\"]\n    labels:\n      "+tt.labels+"\n")
+			routes, err := Routes(p, UpstreamContainer)
+			if tt.ok {
+				if err != nil || len(routes) != 1 {
+					t.Fatalf("Routes = %v, %v", routes, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidLabel) {
+				t.Fatalf("err = %v, want ErrInvalidLabel", err)
+			}
+		})
+	}
+}
+
+func TestRoutesUpstream(t *testing.T) {
+	p := parse(t, `services:
+  web:
+    image: nginx
+    ports: ["127.0.0.1:8080:80"]
+    labels:
+      homekit.expose.host: app.example.com
+`)
+	for upstream, want := range map[string]string{
+		UpstreamContainer: "app-web-1:80",
+		UpstreamHost:      "127.0.0.1:8080",
+	} {
+		routes, err := Routes(p, upstream)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := routes[0].Upstream; got != want {
+			t.Errorf("%s upstream = %q, want %q", upstream, got, want)
+		}
+	}
+
+	p = parse(t, `services:
+  web:
+    image: nginx
+    container_name: ${NAME:-front}
+    ports: ["8080:80"]
+    labels:
+      homekit.expose.host: app.example.com
+`)
+	routes, err := Routes(p, UpstreamContainer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := routes[0].Upstream; got != "front:80" {
+		t.Errorf("container_name upstream = %q, want front:80", got)
+	}
+}
+
+func TestCheckHosts(t *testing.T) {
+	routes := []Route{
+		{Name: "a-web", Hosts: []string{"a.example.com"}},
+		{Name: "b-web", Hosts: []string{"b.example.com", "a.example.com"}},
+	}
+	if err := CheckHosts(routes); err == nil {
+		t.Fatal("CheckHosts accepted a host claimed twice")
+	}
+	if err := CheckHosts(routes[:1]); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProjectName(t *testing.T) {
+	p := &compose.Project{File: "/srv/My.App/compose.yaml"}
+	if got := ProjectName(p); got != "myapp" {
+		t.Fatalf("ProjectName = %q, want myapp", got)
+	}
+	p.Name = "custom"
+	if got := ProjectName(p); got != "custom" {
+		t.Fatalf("ProjectName = %q, want custom", got)
+	}
+}
@@ -0,0 +1,250 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// manifestTypes are accepted when resolving a tag, so multi-platform images
+// resolve to the index digest that docker records after a pull.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// ErrNotFound is returned for unknown repositories and tags.
+var ErrNotFound = errors.New("not found in registry")
+
+// Client talks to registries anonymously, fetching bearer tokens when a
+// registry asks for them. It is safe for concurrent use.
+type Client struct {
+	HTTP *http.Client
+	// PlainHTTP reports whether a registry host is reached over http
+	// instead of https. It defaults to loopback hosts only.
+	PlainHTTP func(host string) bool
+
+	mu     sync.Mutex
+	tokens map[string]string // by repository
+}
+
+// NewClient returns a client using http.DefaultClient.
+func NewClient() *Client {
+	return &Client{HTTP: http.DefaultClient}
+}
+
+func (c *Client) baseURL(domain string) string {
+	if domain == DefaultDomain {
+		domain = "registry-1.docker.io"
+	}
+	plain := c.PlainHTTP
+	if plain == nil {
+		plain = isLoopback
+	}
+	if plain(domain) {
+		return "http://" + domain
+	}
+	return "https://" + domain
+}
+
+func isLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Tags lists every tag of the repository, following pagination.
+func (c *Client) Tags(ctx context.Context, ref Reference) ([]string, error) {
+	next := c.baseURL(ref.Domain) + "/v2/" + ref.Path + "/tags/list?n=1000"
+	var tags []string
+	for next != "" {
+		resp, err := c.get(ctx, http.MethodGet, next, ref, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decode tags of %s: %w", ref.Repository(), err)
+		}
+		tags = append(tags, page.Tags...)
+		next = nextLink(resp, next)
+	}
+	return tags, nil
+}
+
+// Digest resolves a tag of the repository to its manifest digest.
+func (c *Client) Digest(ctx context.Context, ref Reference, tag string) (string, error) {
+	target := c.baseURL(ref.Domain) + "/v2/" + ref.Path + "/manifests/" + tag
+	header := http.Header{"Accept": {strings.Join(manifestTypes, ", ")}}
+	resp, err := c.get(ctx, http.MethodHead, target, ref, header)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		return "", fmt.Errorf("%s:%s: registry returned no digest", ref.Repository(), tag)
+	}
+	return digest, nil
+}
+
+// get performs a request, authenticating once when the registry answers 401
+// with a bearer challenge. Non-2xx responses become errors.
+func (c *Client) get(ctx context.Context, method, target string, ref Reference, header http.Header) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		if token := c.token(ref); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return c.HTTP.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(ctx, ref, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = send(); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%s: %w", ref.Repository(), ErrNotFound)
+		}
+		return nil, fmt.Errorf("%s %s: registry returned %s", method, target, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) token(ref Reference) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[ref.Repository()]
+}
+
+// authenticate fetches an anonymous pull token for the challenge
+// `Bearer realm="...",service="...",scope="..."`.
+func (c *Client) authenticate(ctx context.Context, ref Reference, challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("%s: registry requires %q authentication, which is not supported", ref.Repository(), scheme)
+	}
+	fields := parseChallenge(params)
+	realm, err := url.Parse(fields["realm"])
+	if err != nil || fields["realm"] == "" {
+		return fmt.Errorf("%s: invalid auth challenge %q", ref.Repository(), challenge)
+	}
+	q := realm.Query()
+	if service := fields["service"]; service != "" {
+		q.Set("service", service)
+	}
+	scope := fields["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Path + ":pull"
+	}
+	q.Set("scope", scope)
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: token request returned %s", ref.Repository(), resp.Status)
+	}
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return fmt.Errorf("%s: decode token: %w", ref.Repository(), err)
+	}
+	token := body.Token
+	if token == "" {
+		token = body.AccessToken
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[string]string{}
+	}
+	c.tokens[ref.Repository()] = token
+	return nil
+}
+
+// parseChallenge splits `key="value",key2="value2"`.
+func parseChallenge(s string) map[string]string {
+	out := map[string]string{}
+	for s != "" {
+		key, rest, ok := strings.Cut(strings.TrimLeft(s, " ,"), "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, s = rest[1:end+1], rest[end+2:]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+		}
+		out[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return out
+}
+
+// nextLink resolves the RFC 5988 `Link: <...>; rel="next"` header.
+func nextLink(resp *http.Response, current string) string {
+	link := resp.Header.Get("Link")
+	start, end := strings.Index(link, "<"), strings.Index(link, ">")
+	if start < 0 || end < start || !strings.Contains(link, `rel="next"`) {
+		return ""
+	}
+	base, err := url.Parse(current)
+	if err != nil {
+		return ""
+	}
+	next, err := base.Parse(link[start+1 : end])
+	if err != nil {
+		return ""
+	}
+	return next.String()
+}
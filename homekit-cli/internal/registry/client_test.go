@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeRegistry serves org/app behind a bearer challenge. Tags are paged two
+// at a time through Link headers.
+type fakeRegistry struct {
+	srv           *httptest.Server
+	tags          []string
+	tokenRequests atomic.Int32
+}
+
+func newFakeRegistry(t *testing.T, tags []string) *fakeRegistry {
+	f := &fakeRegistry{tags: tags}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /token", func(w http.ResponseWriter, r *http.Request) {
+		f.tokenRequests.Add(1)
+		q := r.URL.Query()
+		if q.Get("service") != "fake" || q.Get("scope") != "repository:org/app:pull" {
+			http.Error(w, "bad token request "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"token": "secret"})
+	})
+	authorized := func(h http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+f.srv.URL+`/token",service="fake",scope="repository:org/app:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			h(w, r)
+		}
+	}
+	mux.HandleFunc("GET /v2/org/app/tags/list", authorized(func(w http.ResponseWriter, r *http.Request) {
+		start := slices.Index(f.tags, r.URL.Query().Get("last")) + 1
+		end := min(start+2, len(f.tags))
+		if end < len(f.tags) {
+			w.Header().Set("Link", `</v2/org/app/tags/list?n=2&last=`+f.tags[end-1]+`>; rel="next"`)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"name": "org/app", "tags": f.tags[start:end]})
+	}))
+	mux.HandleFunc("HEAD /v2/org/app/manifests/{tag}", authorized(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+			http.Error(w, "manifest lists not accepted", http.StatusNotAcceptable)
+			return
+		}
+		switch r.PathValue("tag") {
+		case "1.0":
+			w.Header().Set("Docker-Content-Digest", "sha256:one")
+		case "nodigest":
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeRegistry) ref(t *testing.T, repo string) Reference {
+	t.Helper()
+	ref, err := ParseReference(strings.TrimPrefix(f.srv.URL, "http://") + "/" + repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ref
+}
+
+func TestTagsPaginationAndToken(t *testing.T) {
+	f := newFakeRegistry(t, []string{"1.0", "1.1", "1.2", "2.0", "latest"})
+	c := &Client{HTTP: f.srv.Client()}
+	ref := f.ref(t, "org/app")
+
+	tags, err := c.Tags(context.Background(), ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(tags, f.tags) {
+		t.Fatalf("Tags = %q, want %q", tags, f.tags)
+	}
+	if _, err := c.Digest(context.Background(), ref, "1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.tokenRequests.Load(); n != 1 {
+		t.Errorf("%d token requests, want 1 reused for every page", n)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	f := newFakeRegistry(t, nil)
+	c := &Client{HTTP: f.srv.Client()}
+	ref := f.ref(t, "org/app")
+
+	digest, err := c.Digest(context.Background(), ref, "1.0")
+	if err != nil || digest != "sha256:one" {
+		t.Fatalf("Digest = %q, %v", digest, err)
+	}
+	if _, err := c.Digest(context.Background(), ref, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Digest of a missing tag = %v, want ErrNotFound", err)
+	}
+	if _, err := c.Digest(context.Background(), ref, "nodigest"); err == nil {
+		t.Error("Digest accepted a response without Docker-Content-Digest")
+	}
+}
+
+func TestUnsupportedChallenge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="private"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	ref, _ := ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/org/app")
+	_, err := (&Client{HTTP: srv.Client()}).Tags(context.Background(), ref)
+	if err == nil || !strings.Contains(err.Error(), `"Basic"`) {
+		t.Fatalf("Tags = %v, want an unsupported authentication error", err)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	c := NewClient()
+	for domain, want := range map[string]string{
+		DefaultDomain:    "https://registry-1.docker.io",
+		"ghcr.io":        "https://ghcr.io",
+		"localhost:5000": "http://localhost:5000",
+		"127.0.0.1:5000": "http://127.0.0.1:5000",
+		"[::1]:5000":     "http://[::1]:5000",
+	} {
+		if got := c.baseURL(domain); got != want {
+			t.Errorf("baseURL(%s) = %s, want %s", domain, got, want)
+		}
+	}
+	c.PlainHTTP = func(string) bool { return true }
+	if got := c.baseURL("registry.lan"); got != "http://registry.lan" {
+		t.Errorf("baseURL with PlainHTTP = %s", got)
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	got := parseChallenge(`realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+	want := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull,push",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
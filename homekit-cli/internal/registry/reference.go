@@ -0,0 +1,90 @@
+// Package registry queries OCI/Docker registries over the distribution HTTP
+// API v2 for tags and manifest digests.
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultDomain is the registry of references without a domain.
+const DefaultDomain = "docker.io"
+
+// tagPattern is the tag grammar of the distribution spec.
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// Reference is a parsed image reference such as ghcr.io/org/app:1.2.3.
+type Reference struct {
+	// Domain is the registry host, DefaultDomain when the reference has none.
+	Domain string
+	// Path is the repository path; official Docker Hub images get library/.
+	Path   string
+	Tag    string
+	Digest string
+	// Name is the reference as written, without tag and digest.
+	Name string
+}
+
+// ParseReference parses an image reference. A reference without tag or
+// digest gets the tag "latest".
+func ParseReference(s string) (Reference, error) {
+	ref := Reference{}
+	rest := strings.TrimSpace(s)
+	if rest == "" {
+		return ref, fmt.Errorf("empty image reference")
+	}
+	if i := strings.Index(rest, "@"); i >= 0 {
+		ref.Digest = rest[i+1:]
+		rest = rest[:i]
+		if !strings.Contains(ref.Digest, ":") {
+			return ref, fmt.Errorf("image reference %q: invalid digest", s)
+		}
+	}
+	if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		ref.Tag = rest[i+1:]
+		rest = rest[:i]
+		if !tagPattern.MatchString(ref.Tag) {
+			return ref, fmt.Errorf("image reference %q: invalid tag %q", s, ref.Tag)
+		}
+	}
+	if rest == "" || strings.ContainsAny(rest, " \t${}") {
+		return ref, fmt.Errorf("image reference %q is not a plain name", s)
+	}
+	ref.Name = rest
+
+	domain, path, found := strings.Cut(rest, "/")
+	if !found || !(strings.ContainsAny(domain, ".:") || domain == "localhost") {
+		domain, path = DefaultDomain, rest
+	}
+	if domain == DefaultDomain && !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+	ref.Domain, ref.Path = domain, strings.ToLower(path)
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// Repository returns domain/path.
+func (r Reference) Repository() string {
+	return r.Domain + "/" + r.Path
+}
+
+// WithTag returns the reference as written with tag replaced and no digest.
+func (r Reference) WithTag(tag string) string {
+	return r.Name + ":" + tag
+}
+
+// String returns the reference as written, with the implied latest tag.
+func (r Reference) String() string {
+	s := r.Name
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package registry
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		in                              string
+		domain, path, tag, digest, name string
+	}{
+		{"nginx", "docker.io", "library/nginx", "latest", "", "nginx"},
+		{"nginx:1.25-alpine", "docker.io", "library/nginx", "1.25-alpine", "", "nginx"},
+		{"grafana/grafana:10.2.0", "docker.io", "grafana/grafana", "10.2.0", "", "grafana/grafana"},
+		{"docker.io/library/redis:7", "docker.io", "library/redis", "7", "", "docker.io/library/redis"},
+		{"ghcr.io/Org/App:v1", "ghcr.io", "org/app", "v1", "", "ghcr.io/Org/App"},
+		{"localhost/app", "localhost", "app", "latest", "", "localhost/app"},
+		{"localhost:5000/team/app:2", "localhost:5000", "team/app", "2", "", "localhost:5000/team/app"},
+		{"registry:5000/app", "registry:5000", "app", "latest", "", "registry:5000/app"},
+		{"nginx@sha256:abc", "docker.io", "library/nginx", "", "sha256:abc", "nginx"},
+		{"nginx:1.25@sha256:abc", "docker.io", "library/nginx", "1.25", "sha256:abc", "nginx"},
+	}
+	for _, tt := range tests {
+		ref, err := ParseReference(tt.in)
+		if err != nil {
+			t.Errorf("ParseReference(%q): %v", tt.in, err)
+			continue
+		}
+		if ref.Domain != tt.domain || ref.Path != tt.path || ref.Tag != tt.tag || ref.Digest != tt.digest || ref.Name != tt.name {
+			t.Errorf("ParseReference(%q) = %+v", tt.in, ref)
+		}
+	}
+
+	for _, in := range []string{"", "  ", "nginx@abc", "${IMAGE}", "app:${TAG}", "app:", ":1.0"} {
+		if ref, err := ParseReference(in); err == nil {
+			t.Errorf("ParseReference(%q) = %+v, want an error", in, ref)
+		}
+	}
+}
+
+func TestReferenceStrings(t *testing.T) {
+	ref, err := ParseReference("ghcr.io/org/app:1.0@sha256:abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ref.String(); got != "ghcr.io/org/app:1.0@sha256:abc" {
+		t.Errorf("String = %q", got)
+	}
+	if got := ref.WithTag("1.1"); got != "ghcr.io/org/app:1.1" {
+		t.Errorf("WithTag = %q", got)
+	}
+	if got := ref.Repository(); got != "ghcr.io/org/app" {
+		t.Errorf("Repository = %q", got)
+	}
+}
@@ -0,0 +1,198 @@
+// Package stack manages self-hosted compose applications kept side by side
+// in a stacks directory. A stack is a directory with a compose file; stacks
+// created from a template also keep the template and its values so the
+// compose file can be rendered again, optionally for another profile.
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/homekit/homekit-cli/internal/templating"
+)
+
+const (
+	// TemplateDir holds the stack's template tree, rendered into the stack
+	// directory.
+	TemplateDir = "template"
+	// ValuesFile holds the base template values.
+	ValuesFile = "values.yaml"
+	// DefaultProfile is used when neither the caller nor the values name one.
+	DefaultProfile = "default"
+)
+
+// ComposeFileNames are tried, in order, to find a stack's compose file.
+var ComposeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+var (
+	// ErrNotFound is returned for a stack directory that does not exist.
+	ErrNotFound = errors.New("stack not found")
+	// ErrNoComposeFile is returned for a stack without a compose file.
+	ErrNoComposeFile = errors.New("no compose file")
+	// ErrUnknownProfile is returned when a profile has no values overlay.
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+// namePattern matches compose project names, which stacks are started as.
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateName checks that name can be used as a directory and compose
+// project name.
+func ValidateName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid stack name %q: use lowercase letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// Stack is one directory below the stacks directory.
+type Stack struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// ComposeFile is the compose file path, empty until it has been rendered.
+	ComposeFile string `json:"compose_file,omitempty"`
+	// Templated reports whether the stack has a template to render.
+	Templated bool `json:"templated"`
+}
+
+// Open loads the stack name below dir.
+func Open(dir, name string) (Stack, error) {
+	if err := ValidateName(name); err != nil {
+		return Stack{}, err
+	}
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return Stack{}, fmt.Errorf("%w: %s in %s", ErrNotFound, name, dir)
+	}
+	if err != nil {
+		return Stack{}, err
+	}
+	return load(name, path), nil
+}
+
+func load(name, path string) Stack {
+	s := Stack{Name: name, Path: path}
+	if info, err := os.Stat(filepath.Join(path, TemplateDir)); err == nil && info.IsDir() {
+		s.Templated = true
+	}
+	if file, err := FindComposeFile(path); err == nil {
+		s.ComposeFile = file
+	}
+	return s
+}
+
+// List returns the stacks below dir, sorted by name: directories holding a
+// compose file or a template. A missing dir yields no stacks.
+func List(dir string) ([]Stack, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stacks []Stack
+	for _, e := range entries {
+		if !e.IsDir() || ValidateName(e.Name()) != nil {
+			continue
+		}
+		s := load(e.Name(), filepath.Join(dir, e.Name()))
+		if s.ComposeFile != "" || s.Templated {
+			stacks = append(stacks, s)
+		}
+	}
+	sort.Slice(stacks, func(i, j int) bool { return stacks[i].Name < stacks[j].Name })
+	return stacks, nil
+}
+
+// FindComposeFile returns the first of ComposeFileNames present in dir.
+func FindComposeFile(dir string) (string, error) {
+	for _, name := range ComposeFileNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w in %s", ErrNoComposeFile, dir)
+}
+
+// ProfileValuesFile returns the overlay file name of a profile.
+func ProfileValuesFile(profile string) string {
+	return "values." + profile + ".yaml"
+}
+
+// Profiles lists the profiles with a values overlay.
+func (s Stack) Profiles() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(s.Path, "values.*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]string, 0, len(matches))
+	for _, m := range matches {
+		base := filepath.Base(m)
+		profiles = append(profiles, strings.TrimSuffix(strings.TrimPrefix(base, "values."), ".yaml"))
+	}
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// Data returns the template values for profile: values.yaml deep-merged with
+// values.<profile>.yaml, plus Stack (the name) and Profile. An empty profile
+// uses the Profile from values.yaml, else DefaultProfile, whose overlay is
+// optional; a profile named by the caller must have one.
+func (s Stack) Data(profile string) (map[string]any, error) {
+	var files []string
+	base := filepath.Join(s.Path, ValuesFile)
+	if _, err := os.Stat(base); err == nil {
+		files = append(files, base)
+	}
+	data, err := templating.LoadData(templating.DataSources{Files: files})
+	if err != nil {
+		return nil, err
+	}
+
+	explicit := profile != ""
+	if !explicit {
+		profile, _ = data["Profile"].(string)
+	}
+	if profile == "" {
+		profile = DefaultProfile
+	}
+	overlay := filepath.Join(s.Path, ProfileValuesFile(profile))
+	if _, err := os.Stat(overlay); err == nil {
+		data, err = templating.LoadData(templating.DataSources{Files: append(files, overlay)})
+		if err != nil {
+			return nil, err
+		}
+	} else if explicit && profile != DefaultProfile {
+		profiles, _ := s.Profiles()
+		return nil, fmt.Errorf("stack %s: %w %q (have %v)", s.Name, ErrUnknownProfile, profile, profiles)
+	}
+	data["Stack"] = s.Name
+	data["Profile"] = profile
+	return data, nil
+}
+
+// Render renders the stack template for profile. The files are relative to
+// the stack directory.
+func (s Stack) Render(r templating.Renderer, profile string) ([]templating.RenderedFile, error) {
+	if !s.Templated {
+		return nil, fmt.Errorf("stack %s has no %s/ to render", s.Name, TemplateDir)
+	}
+	data, err := s.Data(profile)
+	if err != nil {
+		return nil, err
+	}
+	files, err := r.RenderDir(os.DirFS(filepath.Join(s.Path, TemplateDir)), data)
+	if err != nil {
+		return nil, fmt.Errorf("stack %s: %w", s.Name, err)
+	}
+	return files, nil
+}
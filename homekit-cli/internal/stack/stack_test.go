@@ -0,0 +1,176 @@
+package stack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/homekit/homekit-cli/internal/templating"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"app": true, "media-server": true, "app_2": true, "9": true,
+		"": false, "App": false, "-app": false, "my app": false, "../app": false,
+	} {
+		if err := ValidateName(name); (err == nil) != valid {
+			t.Errorf("ValidateName(%q) = %v, want valid %v", name, err, valid)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"web/compose.yaml":                 "services: {}\n",
+		"legacy/docker-compose.yml":        "services: {}\n",
+		"both/compose.yml":                 "services: {}\n",
+		"both/docker-compose.yaml":         "services: {}\n",
+		"fresh/template/compose.yaml.tmpl": "services: {}\n",
+		"notes/README.md":                  "not a stack\n",
+		"Upper/compose.yaml":               "services: {}\n",
+		"loose.yaml":                       "services: {}\n",
+	})
+	stacks, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Stack{
+		{Name: "both", Path: filepath.Join(dir, "both"), ComposeFile: filepath.Join(dir, "both", "compose.yml")},
+		{Name: "fresh", Path: filepath.Join(dir, "fresh"), Templated: true},
+		{Name: "legacy", Path: filepath.Join(dir, "legacy"), ComposeFile: filepath.Join(dir, "legacy", "docker-compose.yml")},
+		{Name: "web", Path: filepath.Join(dir, "web"), ComposeFile: filepath.Join(dir, "web", "compose.yaml")},
+	}
+	if !reflect.DeepEqual(stacks, want) {
+		t.Fatalf("List = %+v\nwant %+v", stacks, want)
+	}
+
+	if stacks, err := List(filepath.Join(dir, "missing")); err != nil || stacks != nil {
+		t.Fatalf("List(missing) = %v, %v", stacks, err)
+	}
+	if _, err := Open(dir, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open(missing) = %v", err)
+	}
+	if _, err := Open(dir, "Upper"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open(Upper) = %v, want a name error", err)
+	}
+	if s, err := Open(dir, "notes"); err != nil || s.ComposeFile != "" || s.Templated {
+		t.Fatalf("Open(notes) = %+v, %v", s, err)
+	}
+}
+
+func TestData(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		profile string
+		want    map[string]any
+		err     error
+	}{
+		{
+			name:  "no values",
+			files: map[string]string{},
+			want:  map[string]any{"Stack": "app", "Profile": "default"},
+		},
+		{
+			name:  "implicit default overlay",
+			files: map[string]string{"values.yaml": "port: 80\n", "values.default.yaml": "port: 8080\n"},
+			want:  map[string]any{"port": 8080, "Stack": "app", "Profile": "default"},
+		},
+		{
+			name:    "explicit default without overlay",
+			files:   map[string]string{"values.yaml": "port: 80\n"},
+			profile: "default",
+			want:    map[string]any{"port": 80, "Stack": "app", "Profile": "default"},
+		},
+		{
+			name: "profile named in values",
+			files: map[string]string{
+				"values.yaml":      "Profile: prod\nport: 80\ndb: {host: db, user: app}\n",
+				"values.prod.yaml": "db: {host: db.prod}\n",
+			},
+			want: map[string]any{"port": 80, "db": map[string]any{"host": "db.prod", "user": "app"}, "Stack": "app", "Profile": "prod"},
+		},
+		{
+			name:  "profile named in values without overlay",
+			files: map[string]string{"values.yaml": "Profile: staging\n"},
+			want:  map[string]any{"Stack": "app", "Profile": "staging"},
+		},
+		{
+			name: "explicit profile wins",
+			files: map[string]string{
+				"values.yaml":      "Profile: prod\nport: 80\n",
+				"values.prod.yaml": "port: 443\n",
+				"values.dev.yaml":  "port: 3000\n",
+			},
+			profile: "dev",
+			want:    map[string]any{"port": 3000, "Stack": "app", "Profile": "dev"},
+		},
+		{
+			name:    "explicit unknown profile",
+			files:   map[string]string{"values.yaml": "port: 80\n", "values.prod.yaml": "port: 443\n"},
+			profile: "dev",
+			err:     ErrUnknownProfile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "app")
+			if err := os.Mkdir(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			writeFiles(t, dir, tt.files)
+			data, err := Stack{Name: "app", Path: dir}.Data(tt.profile)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(data, tt.want) {
+				t.Fatalf("Data = %v, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "app")
+	writeFiles(t, dir, map[string]string{
+		"template/compose.yaml.tmpl": "name: {{ .Stack }}-{{ .Profile }}\nport: {{ .port }}\n",
+		"values.yaml":                "port: 80\n",
+		"values.prod.yaml":           "port: 443\n",
+	})
+	s := Stack{Name: "app", Path: dir, Templated: true}
+	files, err := s.Render(templating.Renderer{}, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Path != "compose.yaml" || string(files[0].Content) != "name: app-prod\nport: 443\n" {
+		t.Fatalf("Render = %+v", files)
+	}
+	if profiles, err := s.Profiles(); err != nil || !reflect.DeepEqual(profiles, []string{"prod"}) {
+		t.Fatalf("Profiles = %v, %v", profiles, err)
+	}
+	if _, err := (Stack{Name: "plain", Path: dir}).Render(templating.Renderer{}, ""); err == nil {
+		t.Fatal("expected an error for an untemplated stack")
+	}
+}
@@ -0,0 +1,41 @@
+package sysinfo
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache shares one Report between callers for TTL, so frequent scrapes do not
+// each trigger a full collection. Concurrent callers wait for the collection
+// in progress instead of starting their own. Failed collections are not
+// cached.
+type Cache struct {
+	Collector Collector
+	TTL       time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	report  *Report
+	expires time.Time
+}
+
+// Get returns the cached report, collecting a new one once it has expired.
+func (c *Cache) Get(ctx context.Context) (*Report, error) {
+	now := c.Now
+	if now == nil {
+		now = time.Now
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.report != nil && now().Before(c.expires) {
+		return c.report, nil
+	}
+	report, err := c.Collector.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.report, c.expires = report, now().Add(c.TTL)
+	return report, nil
+}
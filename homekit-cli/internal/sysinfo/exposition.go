@@ -0,0 +1,187 @@
+package sysinfo
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ExpositionFormat selects the text format written by WriteMetrics.
+type ExpositionFormat int
+
+const (
+	// FormatOpenMetrics is the OpenMetrics 1.0 text format served on /metrics.
+	FormatOpenMetrics ExpositionFormat = iota
+	// FormatPrometheus is the classic Prometheus text format read by the
+	// node_exporter textfile collector.
+	FormatPrometheus
+)
+
+// ContentType returns the HTTP content type of the format.
+func (f ExpositionFormat) ContentType() string {
+	if f == FormatPrometheus {
+		return "text/plain; version=0.0.4; charset=utf-8"
+	}
+	return "application/openmetrics-text; version=1.0.0; charset=utf-8"
+}
+
+type metricKind string
+
+const (
+	gauge   metricKind = "gauge"
+	counter metricKind = "counter"
+)
+
+type metricSample struct {
+	labels []string // alternating names and values
+	value  float64
+}
+
+type metricFamily struct {
+	name    string
+	help    string
+	kind    metricKind
+	samples []metricSample
+}
+
+func (f *metricFamily) add(value float64, labels ...string) {
+	f.samples = append(f.samples, metricSample{labels: labels, value: value})
+}
+
+// WriteMetrics writes the report and its evaluation as homekit_* metrics.
+// Counter families are named without the _total suffix, which is appended to
+// their samples.
+func WriteMetrics(w io.Writer, r *Report, eval Evaluation, format ExpositionFormat) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range metricFamilies(r, eval) {
+		if len(f.samples) == 0 {
+			continue
+		}
+		sampleName := f.name
+		if f.kind == counter {
+			sampleName += "_total"
+		}
+		typeName := f.name
+		if format == FormatPrometheus {
+			typeName = sampleName
+		}
+		bw.WriteString("# HELP " + typeName + " " + f.help + "\n")
+		bw.WriteString("# TYPE " + typeName + " " + string(f.kind) + "\n")
+		for _, s := range f.samples {
+			bw.WriteString(sampleName)
+			if len(s.labels) > 0 {
+				bw.WriteByte('{')
+				for i := 0; i+1 < len(s.labels); i += 2 {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					bw.WriteString(s.labels[i] + `="` + escapeLabel(s.labels[i+1]) + `"`)
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+		}
+	}
+	if format == FormatOpenMetrics {
+		bw.WriteString("# EOF\n")
+	}
+	return bw.Flush()
+}
+
+func metricFamilies(r *Report, eval Evaluation) []*metricFamily {
+	newFamily := func(name string, kind metricKind, help string) *metricFamily {
+		return &metricFamily{name: "homekit_" + name, kind: kind, help: help}
+	}
+
+	status := newFamily("health_status", gauge, "Threshold status: 0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN.")
+	status.add(float64(eval.Status))
+	findings := newFamily("health_findings", gauge, "Metrics at or above a threshold, by status.")
+	counts := map[Status]int{}
+	for _, f := range eval.Findings {
+		counts[f.Status]++
+	}
+	for _, s := range []Status{StatusWarning, StatusCritical} {
+		findings.add(float64(counts[s]), "status", s.String())
+	}
+	collected := newFamily("collection_timestamp_seconds", gauge, "Unix time the metrics were collected.")
+	collected.add(float64(r.Time.UnixNano()) / 1e9)
+	uptime := newFamily("uptime_seconds", gauge, "Host uptime in seconds.")
+	if r.Host.Uptime > 0 {
+		uptime.add(float64(r.Host.Uptime))
+	}
+
+	cores := newFamily("cpu_cores", gauge, "Number of logical CPUs.")
+	cores.add(float64(r.CPU.Cores))
+	cpuUsage := newFamily("cpu_usage_ratio", gauge, "CPU busy time over the sampling interval, 0 to 1.")
+	cpuUsage.add(r.CPU.Total / 100)
+	for i, pct := range r.CPU.PerCPU {
+		cpuUsage.add(pct/100, "cpu", strconv.Itoa(i))
+	}
+	load := newFamily("load_average", gauge, "System load average.")
+	load.add(r.CPU.Load1, "window", "1m")
+	load.add(r.CPU.Load5, "window", "5m")
+	load.add(r.CPU.Load15, "window", "15m")
+
+	memTotal := newFamily("memory_total_bytes", gauge, "Total RAM in bytes.")
+	memTotal.add(float64(r.Memory.Total))
+	memUsed := newFamily("memory_used_bytes", gauge, "Used RAM in bytes.")
+	memUsed.add(float64(r.Memory.Used))
+	swapTotal := newFamily("swap_total_bytes", gauge, "Total swap in bytes.")
+	swapTotal.add(float64(r.Swap.Total))
+	swapUsed := newFamily("swap_used_bytes", gauge, "Used swap in bytes.")
+	swapUsed.add(float64(r.Swap.Used))
+
+	fsSize := newFamily("filesystem_size_bytes", gauge, "Filesystem size in bytes.")
+	fsUsed := newFamily("filesystem_used_bytes", gauge, "Used filesystem space in bytes.")
+	fsInodes := newFamily("filesystem_inodes", gauge, "Total inodes of the filesystem.")
+	fsInodesUsed := newFamily("filesystem_inodes_used", gauge, "Used inodes of the filesystem.")
+	for _, d := range r.Disks {
+		labels := []string{"mountpoint", d.Mountpoint, "device", d.Device, "fstype", d.Fstype}
+		fsSize.add(float64(d.Total), labels...)
+		fsUsed.add(float64(d.Used), labels...)
+		if d.InodesTotal > 0 {
+			fsInodes.add(float64(d.InodesTotal), labels...)
+			fsInodesUsed.add(float64(d.InodesUsed), labels...)
+		}
+	}
+
+	diskRead := newFamily("disk_read_bytes", counter, "Bytes read from the block device.")
+	diskWritten := newFamily("disk_written_bytes", counter, "Bytes written to the block device.")
+	for _, d := range r.DiskIO {
+		diskRead.add(float64(d.ReadBytes), "device", d.Name)
+		diskWritten.add(float64(d.WriteBytes), "device", d.Name)
+	}
+
+	netRecv := newFamily("network_receive_bytes", counter, "Bytes received on the interface.")
+	netSent := newFamily("network_transmit_bytes", counter, "Bytes sent on the interface.")
+	netErrors := newFamily("network_errors", counter, "Receive and transmit errors on the interface.")
+	netDrops := newFamily("network_drops", counter, "Dropped packets on the interface.")
+	for _, n := range r.Network {
+		netRecv.add(float64(n.BytesRecv), "interface", n.Name)
+		netSent.add(float64(n.BytesSent), "interface", n.Name)
+		netErrors.add(float64(n.Errors), "interface", n.Name)
+		netDrops.add(float64(n.Drops), "interface", n.Name)
+	}
+
+	temps := newFamily("temperature_celsius", gauge, "Sensor temperature in degrees Celsius.")
+	for _, t := range r.Temperatures {
+		temps.add(t.Celsius, "sensor", t.Sensor)
+	}
+
+	return []*metricFamily{
+		status, findings, collected, uptime,
+		cores, cpuUsage, load,
+		memTotal, memUsed, swapTotal, swapUsed,
+		fsSize, fsUsed, fsInodes, fsInodesUsed,
+		diskRead, diskWritten,
+		netRecv, netSent, netErrors, netDrops,
+		temps,
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,51 @@
+package sysinfo
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	p := &fakeProvider{cpuBusy: 50, cores: 1, memory: 96, disks: map[string]float64{"/": 20}, temps: map[string]float64{`a"b`: 40}}
+	r := collect(t, p)
+	eval := DefaultThresholds().Evaluate(r)
+
+	var om strings.Builder
+	if err := WriteMetrics(&om, r, eval, FormatOpenMetrics); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE homekit_health_status gauge",
+		"homekit_health_status 2",
+		`homekit_health_findings{status="CRITICAL"} 1`,
+		`homekit_health_findings{status="WARNING"} 0`,
+		"homekit_collection_timestamp_seconds 1.7e+09",
+		`homekit_cpu_usage_ratio{cpu="0"} 0.5`,
+		`homekit_filesystem_used_bytes{mountpoint="/",device="/dev/sda1",fstype="ext4"} 200`,
+		"# TYPE homekit_network_receive_bytes counter",
+		`homekit_network_receive_bytes_total{interface="eth0"} 2000`,
+		`homekit_temperature_celsius{sensor="a\"b"} 40`,
+	} {
+		if !strings.Contains(om.String(), line+"\n") {
+			t.Errorf("OpenMetrics output lacks %q", line)
+		}
+	}
+	if !strings.HasSuffix(om.String(), "# EOF\n") {
+		t.Error("OpenMetrics output does not end with # EOF")
+	}
+
+	var prom strings.Builder
+	if err := WriteMetrics(&prom, r, eval, FormatPrometheus); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prom.String(), "# TYPE homekit_network_receive_bytes_total counter\n") {
+		t.Error("Prometheus output does not name counter families with _total")
+	}
+	if strings.Contains(prom.String(), "# EOF") {
+		t.Error("Prometheus output ends with # EOF")
+	}
+	// swap is absent, so its gauges are zero but present; uptime is set
+	if !strings.Contains(prom.String(), "homekit_swap_total_bytes 0\n") || !strings.Contains(prom.String(), "homekit_uptime_seconds 3600\n") {
+		t.Error("Prometheus output lacks swap or uptime gauges")
+	}
+}
@@ -0,0 +1,482 @@
+package sysinfo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+)
+
+const (
+	// HistoryDir holds recorded samples inside the state directory.
+	HistoryDir = "metrics"
+
+	rawFile    = "raw.jsonl"
+	hourlyFile = "hourly.jsonl"
+	lockFile   = ".lock"
+)
+
+// Default retention of recorded samples.
+const (
+	DefaultRawRetention = 48 * time.Hour
+	DefaultRetention    = 90 * 24 * time.Hour
+)
+
+// Sample is one recorded point. Percentages are 0-100 and Load is the
+// 5-minute load average per core. Downsampled samples hold the mean of N raw
+// samples; raw samples leave N unset.
+type Sample struct {
+	Unix   int64                 `json:"t"`
+	CPU    float64               `json:"cpu"`
+	Memory float64               `json:"mem"`
+	Swap   float64               `json:"swap,omitempty"`
+	Load   float64               `json:"load"`
+	Disks  map[string]DiskSample `json:"disks,omitempty"`
+	N      int                   `json:"n,omitempty"`
+}
+
+// DiskSample is the usage of one filesystem in bytes.
+type DiskSample struct {
+	Used  uint64 `json:"used"`
+	Total uint64 `json:"total"`
+}
+
+// Time returns the sample time.
+func (s Sample) Time() time.Time {
+	return time.Unix(s.Unix, 0)
+}
+
+func (s Sample) weight() int {
+	return max(1, s.N)
+}
+
+// SampleFrom reduces a report to the metrics kept in history.
+func SampleFrom(r *Report) Sample {
+	s := Sample{
+		Unix:   r.Time.Unix(),
+		CPU:    r.CPU.Total,
+		Memory: r.Memory.UsedPercent,
+		Swap:   r.Swap.UsedPercent,
+	}
+	if r.CPU.Cores > 0 {
+		s.Load = r.CPU.Load5 / float64(r.CPU.Cores)
+	}
+	if len(r.Disks) > 0 {
+		s.Disks = make(map[string]DiskSample, len(r.Disks))
+		for _, d := range r.Disks {
+			s.Disks[d.Mountpoint] = DiskSample{Used: d.Used, Total: d.Total}
+		}
+	}
+	return s
+}
+
+// History is an append-only store of samples below <stateDir>/metrics.
+// New samples are appended to raw.jsonl; Compact folds raw samples older
+// than RawRetention into hourly means in hourly.jsonl and drops hourly
+// samples older than Retention.
+type History struct {
+	dir          string
+	RawRetention time.Duration
+	Retention    time.Duration
+}
+
+// NewHistory returns the store inside stateDir with the default retention.
+func NewHistory(stateDir string) *History {
+	return &History{
+		dir:          filepath.Join(stateDir, HistoryDir),
+		RawRetention: DefaultRawRetention,
+		Retention:    DefaultRetention,
+	}
+}
+
+// Dir returns the directory holding the store.
+func (h *History) Dir() string {
+	return h.dir
+}
+
+// lock serialises writers of the store, such as overlapping cron runs of
+// sys record, with an advisory lock on metrics/.lock.
+func (h *History) lock() (func() error, error) {
+	if err := os.MkdirAll(h.dir, 0o755); err != nil {
+		return nil, err
+	}
+	return fileutil.Lock(filepath.Join(h.dir, lockFile))
+}
+
+// Append adds one sample to the raw file with a single write.
+func (h *History) Append(s Sample) (err error) {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	unlock, err := h.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, unlock()) }()
+	f, err := os.OpenFile(filepath.Join(h.dir, rawFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load returns every sample at or after since, oldest first.
+func (h *History) Load(since time.Time) ([]Sample, error) {
+	var out []Sample
+	for _, name := range []string{hourlyFile, rawFile} {
+		samples, err := readSamples(filepath.Join(h.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range samples {
+			if s.Unix >= since.Unix() {
+				out = append(out, s)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Unix < out[j].Unix })
+	return out, nil
+}
+
+// Compact downsamples raw samples older than RawRetention into hourly means
+// and applies Retention. Samples appended meanwhile wait for it to finish.
+func (h *History) Compact(now time.Time) (err error) {
+	unlock, err := h.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.Join(err, unlock()) }()
+
+	rawPath := filepath.Join(h.dir, rawFile)
+	hourlyPath := filepath.Join(h.dir, hourlyFile)
+	raw, err := readSamples(rawPath)
+	if err != nil {
+		return err
+	}
+	hourly, err := readSamples(hourlyPath)
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-h.RawRetention).Unix()
+	expired := now.Add(-h.Retention).Unix()
+	buckets := make(map[int64]Sample, len(hourly))
+	for _, s := range hourly {
+		if s.Unix >= expired {
+			buckets[s.Unix] = s
+		}
+	}
+	var keep []Sample
+	for _, s := range raw {
+		if s.Unix >= cutoff {
+			keep = append(keep, s)
+			continue
+		}
+		if s.Unix < expired {
+			continue
+		}
+		hour := s.Unix - s.Unix%3600
+		s.Unix = hour
+		if b, ok := buckets[hour]; ok {
+			s = mergeSamples(b, s)
+		}
+		buckets[hour] = s
+	}
+	if len(keep) == len(raw) && len(buckets) == len(hourly) {
+		return nil
+	}
+
+	merged := make([]Sample, 0, len(buckets))
+	for _, s := range buckets {
+		merged = append(merged, s)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Unix < merged[j].Unix })
+	for i := range merged {
+		merged[i].N = merged[i].weight()
+	}
+	if err := writeSamples(hourlyPath, merged); err != nil {
+		return err
+	}
+	return writeSamples(rawPath, keep)
+}
+
+// mergeSamples returns the weighted mean of two samples, keeping a's time.
+func mergeSamples(a, b Sample) Sample {
+	wa, wb := float64(a.weight()), float64(b.weight())
+	mean := func(x, y float64) float64 { return (x*wa + y*wb) / (wa + wb) }
+	out := Sample{
+		Unix:   a.Unix,
+		CPU:    mean(a.CPU, b.CPU),
+		Memory: mean(a.Memory, b.Memory),
+		Swap:   mean(a.Swap, b.Swap),
+		Load:   mean(a.Load, b.Load),
+		N:      a.weight() + b.weight(),
+		Disks:  make(map[string]DiskSample, len(a.Disks)),
+	}
+	for mount, d := range a.Disks {
+		out.Disks[mount] = d
+	}
+	for mount, d := range b.Disks {
+		if prev, ok := out.Disks[mount]; ok {
+			d.Used = uint64(mean(float64(prev.Used), float64(d.Used)))
+		}
+		out.Disks[mount] = d
+	}
+	return out
+}
+
+// readSamples parses a JSON lines file. Lines that do not parse, such as a
+// write torn by a crash, are skipped.
+func readSamples(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Sample
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		var s Sample
+		if json.Unmarshal(scanner.Bytes(), &s) == nil && s.Unix > 0 {
+			out = append(out, s)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return out, nil
+}
+
+func writeSamples(path string, samples []Sample) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, s := range samples {
+		if err := enc.Encode(s); err != nil {
+			return err
+		}
+	}
+	return fileutil.WriteAtomic(path, buf.Bytes(), 0o644)
+}
+
+// MetricSummary aggregates one metric over a period. Avg is weighted by the
+// number of raw samples behind each point.
+type MetricSummary struct {
+	Metric string  `json:"metric"`
+	Min    float64 `json:"min"`
+	Avg    float64 `json:"avg"`
+	Max    float64 `json:"max"`
+	P95    float64 `json:"p95"`
+}
+
+// DiskTrend is the usage trend of one filesystem. GrowthPerDay is the slope
+// of a least-squares fit of used bytes over time; FullAt is where that line
+// reaches the filesystem size and is nil when usage is flat or shrinking, or
+// when that is further away than ProjectionHorizon.
+type DiskTrend struct {
+	Mountpoint   string     `json:"mountpoint"`
+	Used         uint64     `json:"used"`
+	Total        uint64     `json:"total"`
+	UsedPercent  float64    `json:"used_percent"`
+	GrowthPerDay float64    `json:"growth_bytes_per_day"`
+	FullAt       *time.Time `json:"full_at,omitempty"`
+}
+
+// Summary describes the samples of a period.
+type Summary struct {
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Samples int             `json:"samples"`
+	Metrics []MetricSummary `json:"metrics"`
+	Disks   []DiskTrend     `json:"disks"`
+}
+
+// Summarize aggregates samples, which must be sorted oldest first.
+func Summarize(samples []Sample) Summary {
+	if len(samples) == 0 {
+		return Summary{}
+	}
+	sum := Summary{
+		From:    samples[0].Time(),
+		To:      samples[len(samples)-1].Time(),
+		Samples: len(samples),
+	}
+
+	type series struct {
+		values  []float64
+		weights []int
+	}
+	var names []string
+	all := map[string]*series{}
+	add := func(name string, v float64, w int) {
+		s, ok := all[name]
+		if !ok {
+			s = &series{}
+			all[name] = s
+			names = append(names, name)
+		}
+		s.values = append(s.values, v)
+		s.weights = append(s.weights, w)
+	}
+	hasSwap := false
+	for _, s := range samples {
+		hasSwap = hasSwap || s.Swap > 0
+	}
+	var mounts []string
+	seen := map[string]bool{}
+	for _, s := range samples {
+		add(MetricCPU, s.CPU, s.weight())
+		add(MetricMemory, s.Memory, s.weight())
+		if hasSwap {
+			add(MetricSwap, s.Swap, s.weight())
+		}
+		add(MetricLoad, s.Load, s.weight())
+		for mount := range s.Disks {
+			if !seen[mount] {
+				seen[mount] = true
+				mounts = append(mounts, mount)
+			}
+		}
+	}
+	sort.Strings(mounts)
+	for _, mount := range mounts {
+		for _, s := range samples {
+			if d, ok := s.Disks[mount]; ok && d.Total > 0 {
+				add(MetricDisk+" "+mount, float64(d.Used)/float64(d.Total)*100, s.weight())
+			}
+		}
+	}
+
+	for _, name := range names {
+		s := all[name]
+		m := MetricSummary{Metric: name, Min: math.Inf(1), Max: math.Inf(-1)}
+		var total, weights float64
+		for i, v := range s.values {
+			m.Min = min(m.Min, v)
+			m.Max = max(m.Max, v)
+			total += v * float64(s.weights[i])
+			weights += float64(s.weights[i])
+		}
+		m.Avg = total / weights
+		m.P95 = percentile(s.values, 95)
+		sum.Metrics = append(sum.Metrics, m)
+	}
+
+	for _, mount := range mounts {
+		sum.Disks = append(sum.Disks, diskTrend(mount, samples))
+	}
+	return sum
+}
+
+// ProjectionHorizon bounds disk-full projections: slow growth, often just
+// jitter, would otherwise project centuries ahead and overflow time.Duration.
+const ProjectionHorizon = 100 * 365 * 24 * time.Hour
+
+func diskTrend(mount string, samples []Sample) DiskTrend {
+	var xs, ys []float64
+	trend := DiskTrend{Mountpoint: mount}
+	var lastUnix int64
+	for _, s := range samples {
+		d, ok := s.Disks[mount]
+		if !ok {
+			continue
+		}
+		xs = append(xs, float64(s.Unix))
+		ys = append(ys, float64(d.Used))
+		trend.Used, trend.Total, lastUnix = d.Used, d.Total, s.Unix
+	}
+	if trend.Total > 0 {
+		trend.UsedPercent = float64(trend.Used) / float64(trend.Total) * 100
+	}
+
+	slope, intercept, ok := linearFit(xs, ys)
+	if !ok {
+		return trend
+	}
+	trend.GrowthPerDay = slope * 86400
+	if slope <= 0 {
+		return trend
+	}
+	free := float64(trend.Total) - (intercept + slope*float64(lastUnix))
+	full := time.Unix(lastUnix, 0)
+	if free > 0 {
+		seconds := free / slope
+		if seconds > ProjectionHorizon.Seconds() {
+			return trend
+		}
+		full = full.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	trend.FullAt = &full
+	return trend
+}
+
+// linearFit returns the least-squares line through the points. It fails with
+// fewer than two distinct x values.
+func linearFit(xs, ys []float64) (slope, intercept float64, ok bool) {
+	n := float64(len(xs))
+	if len(xs) < 2 {
+		return 0, 0, false
+	}
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= n
+	meanY /= n
+	var sxy, sxx float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		sxy += dx * (ys[i] - meanY)
+		sxx += dx * dx
+	}
+	if sxx == 0 {
+		return 0, 0, false
+	}
+	slope = sxy / sxx
+	return slope, meanY - slope*meanX, true
+}
+
+// percentile returns the nearest-rank percentile of values.
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(len(sorted)-1, rank))]
+}
+
+// ParseDuration extends time.ParseDuration with the d (day) and w (week)
+// units, e.g. "7d" or "2w".
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
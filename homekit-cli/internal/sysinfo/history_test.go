@@ -0,0 +1,101 @@
+package sysinfo
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// diskSamples returns daily samples of a filesystem growing by perDay bytes.
+func diskSamples(days int, total, used, perDay uint64) []Sample {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	samples := make([]Sample, days)
+	for i := range samples {
+		samples[i] = Sample{
+			Unix:  start + int64(i)*86400,
+			Disks: map[string]DiskSample{"/": {Used: used + uint64(i)*perDay, Total: total}},
+		}
+	}
+	return samples
+}
+
+func TestDiskTrendProjectsFull(t *testing.T) {
+	const gb = 1 << 30
+	samples := diskSamples(10, 100*gb, 50*gb, gb)
+	trend := Summarize(samples).Disks[0]
+	if trend.FullAt == nil {
+		t.Fatal("FullAt is nil")
+	}
+	last := samples[len(samples)-1].Time()
+	if days := trend.FullAt.Sub(last).Hours() / 24; days < 40 || days > 42 {
+		t.Fatalf("full in %.1f days, want 41", days)
+	}
+}
+
+func TestDiskTrendBeyondHorizon(t *testing.T) {
+	const tb, gb, mb = 1 << 40, 1 << 30, 1 << 20
+	// 900 GB free at 1 MB/day is about 2500 years away
+	trend := Summarize(diskSamples(30, tb, 100*gb, mb)).Disks[0]
+	if trend.GrowthPerDay <= 0 {
+		t.Fatalf("GrowthPerDay = %v, want positive", trend.GrowthPerDay)
+	}
+	if trend.FullAt != nil {
+		t.Fatalf("FullAt = %s, want nil beyond the horizon", trend.FullAt)
+	}
+}
+
+func TestDiskTrendShrinking(t *testing.T) {
+	samples := diskSamples(5, 1000, 500, 0)
+	for i := range samples {
+		samples[i].Disks["/"] = DiskSample{Used: 500 - uint64(i)*10, Total: 1000}
+	}
+	if trend := Summarize(samples).Disks[0]; trend.FullAt != nil {
+		t.Fatalf("FullAt = %s for shrinking usage", trend.FullAt)
+	}
+}
+
+func TestHistoryConcurrentAppendCompact(t *testing.T) {
+	h := NewHistory(t.TempDir())
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-3 * 24 * time.Hour).Unix()
+
+	const appends = 200
+	var wg sync.WaitGroup
+	errs := make(chan error, appends+appends/10)
+	for i := range appends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- h.Append(Sample{Unix: old + int64(i), CPU: 1})
+		}()
+		if i%10 == 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- h.Compact(now)
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Compact(now); err != nil {
+		t.Fatal(err)
+	}
+
+	samples, err := h.Load(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for _, s := range samples {
+		total += s.weight()
+	}
+	if total != appends {
+		t.Fatalf("history holds %d samples, want %d", total, appends)
+	}
+}
@@ -0,0 +1,121 @@
+// Package sysinfo collects host health metrics and evaluates them against
+// warning and critical thresholds.
+package sysinfo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// Provider is the source of raw metrics. The default implementation wraps
+// gopsutil; tests and other platforms can supply their own.
+type Provider interface {
+	// CPUTimes returns cumulative CPU times, per CPU or as one total.
+	CPUTimes(ctx context.Context, perCPU bool) ([]cpu.TimesStat, error)
+	VirtualMemory(ctx context.Context) (*mem.VirtualMemoryStat, error)
+	SwapMemory(ctx context.Context) (*mem.SwapMemoryStat, error)
+	LoadAvg(ctx context.Context) (*load.AvgStat, error)
+	Partitions(ctx context.Context) ([]disk.PartitionStat, error)
+	DiskUsage(ctx context.Context, path string) (*disk.UsageStat, error)
+	NetIOCounters(ctx context.Context) ([]net.IOCountersStat, error)
+	// DiskIOCounters returns cumulative read/write counters per block device.
+	DiskIOCounters(ctx context.Context) (map[string]disk.IOCountersStat, error)
+	HostInfo(ctx context.Context) (*host.InfoStat, error)
+	Temperatures(ctx context.Context) ([]host.TemperatureStat, error)
+	// Processes returns cumulative CPU seconds and memory use per process.
+	Processes(ctx context.Context) ([]ProcessSample, error)
+}
+
+// ProcessSample is a point-in-time reading of one process.
+type ProcessSample struct {
+	PID        int32
+	Name       string
+	CPUSeconds float64
+	MemPercent float64
+	RSS        uint64
+}
+
+// GopsutilProvider reads metrics from the running host.
+type GopsutilProvider struct{}
+
+func (GopsutilProvider) CPUTimes(ctx context.Context, perCPU bool) ([]cpu.TimesStat, error) {
+	return cpu.TimesWithContext(ctx, perCPU)
+}
+
+func (GopsutilProvider) VirtualMemory(ctx context.Context) (*mem.VirtualMemoryStat, error) {
+	return mem.VirtualMemoryWithContext(ctx)
+}
+
+func (GopsutilProvider) SwapMemory(ctx context.Context) (*mem.SwapMemoryStat, error) {
+	return mem.SwapMemoryWithContext(ctx)
+}
+
+func (GopsutilProvider) LoadAvg(ctx context.Context) (*load.AvgStat, error) {
+	return load.AvgWithContext(ctx)
+}
+
+// Partitions returns physical filesystems only, skipping pseudo filesystems
+// such as proc and sysfs.
+func (GopsutilProvider) Partitions(ctx context.Context) ([]disk.PartitionStat, error) {
+	return disk.PartitionsWithContext(ctx, false)
+}
+
+func (GopsutilProvider) DiskUsage(ctx context.Context, path string) (*disk.UsageStat, error) {
+	return disk.UsageWithContext(ctx, path)
+}
+
+func (GopsutilProvider) NetIOCounters(ctx context.Context) ([]net.IOCountersStat, error) {
+	return net.IOCountersWithContext(ctx, true)
+}
+
+func (GopsutilProvider) DiskIOCounters(ctx context.Context) (map[string]disk.IOCountersStat, error) {
+	return disk.IOCountersWithContext(ctx)
+}
+
+func (GopsutilProvider) HostInfo(ctx context.Context) (*host.InfoStat, error) {
+	return host.InfoWithContext(ctx)
+}
+
+// Temperatures returns the sensors gopsutil can read. Partial results are
+// returned when only some sensors fail.
+func (GopsutilProvider) Temperatures(ctx context.Context) ([]host.TemperatureStat, error) {
+	temps, err := host.SensorsTemperaturesWithContext(ctx)
+	var warn *host.Warnings
+	if errors.As(err, &warn) && len(temps) > 0 {
+		return temps, nil
+	}
+	return temps, err
+}
+
+// Processes skips processes that exit or deny access while being read.
+func (GopsutilProvider) Processes(ctx context.Context) ([]ProcessSample, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]ProcessSample, 0, len(procs))
+	for _, p := range procs {
+		times, err := p.TimesWithContext(ctx)
+		if err != nil {
+			continue
+		}
+		sample := ProcessSample{PID: p.Pid, CPUSeconds: times.User + times.System}
+		sample.Name, _ = p.NameWithContext(ctx)
+		if pct, err := p.MemoryPercentWithContext(ctx); err == nil {
+			sample.MemPercent = float64(pct)
+		}
+		if info, err := p.MemoryInfoWithContext(ctx); err == nil {
+			sample.RSS = info.RSS
+		}
+		out = append(out, sample)
+	}
+	return out, nil
+}
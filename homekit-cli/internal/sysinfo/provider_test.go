@@ -0,0 +1,184 @@
+package sysinfo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// fakeProvider serves fixed readings. CPU time and counters advance once
+// sampled is set, which the collector's Sleep does between the two samples.
+type fakeProvider struct {
+	sampled bool
+	// cpuBusy is the busy percentage of every CPU over the interval.
+	cpuBusy float64
+	cores   int
+	memory  float64
+	swap    float64
+	load5   float64
+	disks   map[string]float64
+	temps   map[string]float64
+	// tempErr fails Temperatures, an optional metric.
+	tempErr error
+}
+
+func (f *fakeProvider) CPUTimes(_ context.Context, perCPU bool) ([]cpu.TimesStat, error) {
+	n := 1
+	if perCPU {
+		n = f.cores
+	}
+	times := make([]cpu.TimesStat, n)
+	for i := range times {
+		times[i].CPU = "cpu" + string(rune('0'+i))
+		if f.sampled {
+			times[i].User = f.cpuBusy
+			times[i].Idle = 100 - f.cpuBusy
+		}
+	}
+	return times, nil
+}
+
+func (f *fakeProvider) VirtualMemory(context.Context) (*mem.VirtualMemoryStat, error) {
+	return &mem.VirtualMemoryStat{Total: 1000, Used: uint64(f.memory * 10), UsedPercent: f.memory}, nil
+}
+
+func (f *fakeProvider) SwapMemory(context.Context) (*mem.SwapMemoryStat, error) {
+	if f.swap == 0 {
+		return &mem.SwapMemoryStat{}, nil
+	}
+	return &mem.SwapMemoryStat{Total: 1000, Used: uint64(f.swap * 10), UsedPercent: f.swap}, nil
+}
+
+func (f *fakeProvider) LoadAvg(context.Context) (*load.AvgStat, error) {
+	return &load.AvgStat{Load1: f.load5, Load5: f.load5, Load15: f.load5}, nil
+}
+
+func (f *fakeProvider) Partitions(context.Context) ([]disk.PartitionStat, error) {
+	var parts []disk.PartitionStat
+	for mount := range f.disks {
+		parts = append(parts, disk.PartitionStat{Mountpoint: mount, Device: "/dev/sda1", Fstype: "ext4"})
+	}
+	return parts, nil
+}
+
+func (f *fakeProvider) DiskUsage(_ context.Context, path string) (*disk.UsageStat, error) {
+	pct, ok := f.disks[path]
+	if !ok {
+		return nil, errors.New("no such mount")
+	}
+	return &disk.UsageStat{Path: path, Total: 1000, Used: uint64(pct * 10), UsedPercent: pct, InodesTotal: 100, InodesUsed: 1, InodesUsedPercent: 1}, nil
+}
+
+func (f *fakeProvider) NetIOCounters(context.Context) ([]net.IOCountersStat, error) {
+	stat := net.IOCountersStat{Name: "eth0"}
+	if f.sampled {
+		stat.BytesRecv, stat.BytesSent = 2000, 1000
+	}
+	return []net.IOCountersStat{stat}, nil
+}
+
+func (f *fakeProvider) DiskIOCounters(context.Context) (map[string]disk.IOCountersStat, error) {
+	stat := disk.IOCountersStat{Name: "sda"}
+	if f.sampled {
+		stat.ReadBytes, stat.WriteBytes = 4000, 3000
+	}
+	return map[string]disk.IOCountersStat{"sda": stat}, nil
+}
+
+func (f *fakeProvider) HostInfo(context.Context) (*host.InfoStat, error) {
+	return &host.InfoStat{Hostname: "fake", OS: "linux", Platform: "debian", PlatformVersion: "12", Uptime: 3600}, nil
+}
+
+func (f *fakeProvider) Temperatures(context.Context) ([]host.TemperatureStat, error) {
+	if f.tempErr != nil {
+		return nil, f.tempErr
+	}
+	var temps []host.TemperatureStat
+	for sensor, c := range f.temps {
+		temps = append(temps, host.TemperatureStat{SensorKey: sensor, Temperature: c})
+	}
+	return temps, nil
+}
+
+func (f *fakeProvider) Processes(context.Context) ([]ProcessSample, error) {
+	seconds := 0.0
+	if f.sampled {
+		seconds = 0.5
+	}
+	return []ProcessSample{
+		{PID: 1, Name: "init", RSS: 10},
+		{PID: 2, Name: "busy", CPUSeconds: seconds, RSS: 5},
+	}, nil
+}
+
+// collect runs a Collector over p with a one-second interval and no waiting.
+func collect(t *testing.T, p *fakeProvider) *Report {
+	t.Helper()
+	c := Collector{
+		Provider: p,
+		TopN:     1,
+		Sleep: func(context.Context, time.Duration) error {
+			p.sampled = true
+			return nil
+		},
+		Now: func() time.Time { return time.Unix(1700000000, 0) },
+	}
+	r, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestCollect(t *testing.T) {
+	r := collect(t, &fakeProvider{
+		cpuBusy: 25,
+		cores:   2,
+		memory:  40,
+		load5:   1,
+		disks:   map[string]float64{"/": 50, "/home": 10},
+		tempErr: errors.New("no sensors"),
+	})
+	if r.CPU.Total != 25 || r.CPU.Cores != 2 || len(r.CPU.PerCPU) != 2 {
+		t.Errorf("CPU = %+v", r.CPU)
+	}
+	if r.Memory.UsedPercent != 40 || r.Swap.Total != 0 {
+		t.Errorf("Memory = %+v, Swap = %+v", r.Memory, r.Swap)
+	}
+	if len(r.Disks) != 2 || r.Disks[0].Mountpoint != "/" || r.Disks[1].Mountpoint != "/home" {
+		t.Errorf("Disks = %+v", r.Disks)
+	}
+	if recv, sent := r.NetworkRate(); recv != 2000 || sent != 1000 {
+		t.Errorf("NetworkRate = %v, %v", recv, sent)
+	}
+	if read, write := r.DiskIORate(); read != 4000 || write != 3000 {
+		t.Errorf("DiskIORate = %v, %v", read, write)
+	}
+	if len(r.TopCPU) != 1 || r.TopCPU[0].Name != "busy" || r.TopCPU[0].CPUPercent != 50 {
+		t.Errorf("TopCPU = %+v", r.TopCPU)
+	}
+	if len(r.TopMemory) != 1 || r.TopMemory[0].Name != "init" {
+		t.Errorf("TopMemory = %+v", r.TopMemory)
+	}
+	if len(r.Warnings) != 1 || !strings.HasPrefix(r.Warnings[0], "temperatures:") {
+		t.Errorf("Warnings = %q", r.Warnings)
+	}
+}
+
+func TestCollectCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := Collector{Provider: &fakeProvider{cores: 1}, Interval: time.Hour}
+	if _, err := c.Collect(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Collect = %v, want context.Canceled", err)
+	}
+}
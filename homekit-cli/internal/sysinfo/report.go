@@ -0,0 +1,471 @@
+package sysinfo
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// Report is one collection of host metrics.
+type Report struct {
+	Time         time.Time     `json:"time"`
+	Host         HostInfo      `json:"host"`
+	CPU          CPUStats      `json:"cpu"`
+	Memory       MemoryStats   `json:"memory"`
+	Swap         MemoryStats   `json:"swap"`
+	Disks        []DiskStats   `json:"disks"`
+	Network      []NetStats    `json:"network"`
+	DiskIO       []DiskIOStats `json:"disk_io,omitempty"`
+	Temperatures []TempStats   `json:"temperatures,omitempty"`
+	TopCPU       []ProcessStat `json:"top_cpu"`
+	TopMemory    []ProcessStat `json:"top_memory"`
+	// Warnings lists metrics that could not be collected.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// HostInfo identifies the host.
+type HostInfo struct {
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	Platform string `json:"platform"`
+	// Uptime is in seconds.
+	Uptime uint64 `json:"uptime_seconds"`
+}
+
+// CPUStats holds utilisation percentages and load averages.
+type CPUStats struct {
+	Cores   int       `json:"cores"`
+	Total   float64   `json:"total_percent"`
+	PerCPU  []float64 `json:"per_cpu_percent"`
+	Load1   float64   `json:"load1"`
+	Load5   float64   `json:"load5"`
+	Load15  float64   `json:"load15"`
+	Sampled string    `json:"sampled_over"`
+}
+
+// MemoryStats describes RAM or swap usage in bytes.
+type MemoryStats struct {
+	Total       uint64  `json:"total"`
+	Used        uint64  `json:"used"`
+	UsedPercent float64 `json:"used_percent"`
+}
+
+// DiskStats describes one mounted filesystem.
+type DiskStats struct {
+	Mountpoint        string  `json:"mountpoint"`
+	Device            string  `json:"device"`
+	Fstype            string  `json:"fstype"`
+	Total             uint64  `json:"total"`
+	Used              uint64  `json:"used"`
+	UsedPercent       float64 `json:"used_percent"`
+	InodesTotal       uint64  `json:"inodes_total"`
+	InodesUsed        uint64  `json:"inodes_used"`
+	InodesUsedPercent float64 `json:"inodes_used_percent"`
+}
+
+// NetStats holds cumulative counters of one network interface. The rates are
+// bytes per second over the sampling interval.
+type NetStats struct {
+	Name        string  `json:"name"`
+	BytesSent   uint64  `json:"bytes_sent"`
+	BytesRecv   uint64  `json:"bytes_recv"`
+	PacketsSent uint64  `json:"packets_sent"`
+	PacketsRecv uint64  `json:"packets_recv"`
+	Errors      uint64  `json:"errors"`
+	Drops       uint64  `json:"drops"`
+	SentRate    float64 `json:"sent_bytes_per_second"`
+	RecvRate    float64 `json:"recv_bytes_per_second"`
+}
+
+// DiskIOStats holds cumulative counters of one block device. The rates are
+// bytes per second over the sampling interval.
+type DiskIOStats struct {
+	Name       string  `json:"name"`
+	ReadBytes  uint64  `json:"read_bytes"`
+	WriteBytes uint64  `json:"write_bytes"`
+	ReadRate   float64 `json:"read_bytes_per_second"`
+	WriteRate  float64 `json:"write_bytes_per_second"`
+}
+
+// TempStats is one temperature sensor reading.
+type TempStats struct {
+	Sensor   string  `json:"sensor"`
+	Celsius  float64 `json:"celsius"`
+	High     float64 `json:"high,omitempty"`
+	Critical float64 `json:"critical,omitempty"`
+}
+
+// ProcessStat is one entry of the top process lists.
+type ProcessStat struct {
+	PID        int32   `json:"pid"`
+	Name       string  `json:"name"`
+	CPUPercent float64 `json:"cpu_percent"`
+	MemPercent float64 `json:"mem_percent"`
+	RSS        uint64  `json:"rss"`
+}
+
+// Collector gathers a Report from a Provider. CPU and per-process usage are
+// measured as the difference between two samples taken Interval apart.
+type Collector struct {
+	Provider Provider
+	Interval time.Duration
+	// TopN limits the process lists; zero disables process collection.
+	TopN int
+	// Sleep waits between samples; it defaults to a context-aware sleep and
+	// can be replaced to avoid real waiting.
+	Sleep func(ctx context.Context, d time.Duration) error
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// DefaultInterval is the CPU sampling window used when Interval is zero.
+const DefaultInterval = time.Second
+
+// Collect reads every metric. Failures of optional metrics (swap, network,
+// temperatures, processes) are recorded as warnings; CPU, memory and disk
+// failures are returned as errors.
+func (c Collector) Collect(ctx context.Context) (*Report, error) {
+	provider := c.Provider
+	if provider == nil {
+		provider = GopsutilProvider{}
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	sleep := c.Sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	now := c.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	r := &Report{Time: now()}
+	warn := func(metric string, err error) {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("%s: %v", metric, err))
+	}
+
+	// first samples
+	totalBefore, err := provider.CPUTimes(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("cpu: %w", err)
+	}
+	perBefore, err := provider.CPUTimes(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("cpu: %w", err)
+	}
+	var procsBefore []ProcessSample
+	if c.TopN > 0 {
+		if procsBefore, err = provider.Processes(ctx); err != nil {
+			warn("processes", err)
+		}
+	}
+	// Counter failures are reported once, from the second sample.
+	netBefore, _ := provider.NetIOCounters(ctx)
+	diskBefore, _ := provider.DiskIOCounters(ctx)
+
+	if err := sleep(ctx, interval); err != nil {
+		return nil, err
+	}
+
+	totalAfter, err := provider.CPUTimes(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("cpu: %w", err)
+	}
+	perAfter, err := provider.CPUTimes(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("cpu: %w", err)
+	}
+	r.CPU.Total = firstOrZero(cpuPercents(totalBefore, totalAfter))
+	r.CPU.PerCPU = cpuPercents(perBefore, perAfter)
+	r.CPU.Cores = len(perAfter)
+	if r.CPU.Cores == 0 {
+		r.CPU.Cores = runtime.NumCPU()
+	}
+	r.CPU.Sampled = interval.String()
+
+	if avg, err := provider.LoadAvg(ctx); err != nil {
+		warn("load", err)
+	} else {
+		r.CPU.Load1, r.CPU.Load5, r.CPU.Load15 = avg.Load1, avg.Load5, avg.Load15
+	}
+
+	vm, err := provider.VirtualMemory(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("memory: %w", err)
+	}
+	r.Memory = MemoryStats{Total: vm.Total, Used: vm.Used, UsedPercent: vm.UsedPercent}
+
+	if swap, err := provider.SwapMemory(ctx); err != nil {
+		warn("swap", err)
+	} else {
+		r.Swap = MemoryStats{Total: swap.Total, Used: swap.Used, UsedPercent: swap.UsedPercent}
+	}
+
+	if err := c.collectDisks(ctx, provider, r, warn); err != nil {
+		return nil, err
+	}
+
+	if counters, err := provider.NetIOCounters(ctx); err != nil {
+		warn("network", err)
+	} else {
+		prev := make(map[string]int, len(netBefore))
+		for i, n := range netBefore {
+			prev[n.Name] = i
+		}
+		for _, n := range counters {
+			stat := NetStats{
+				Name:        n.Name,
+				BytesSent:   n.BytesSent,
+				BytesRecv:   n.BytesRecv,
+				PacketsSent: n.PacketsSent,
+				PacketsRecv: n.PacketsRecv,
+				Errors:      n.Errin + n.Errout,
+				Drops:       n.Dropin + n.Dropout,
+			}
+			if i, ok := prev[n.Name]; ok {
+				stat.SentRate = rate(netBefore[i].BytesSent, n.BytesSent, interval)
+				stat.RecvRate = rate(netBefore[i].BytesRecv, n.BytesRecv, interval)
+			}
+			r.Network = append(r.Network, stat)
+		}
+		sort.Slice(r.Network, func(i, j int) bool { return r.Network[i].Name < r.Network[j].Name })
+	}
+
+	if counters, err := provider.DiskIOCounters(ctx); err != nil {
+		warn("disk io", err)
+	} else {
+		for name, d := range counters {
+			stat := DiskIOStats{Name: name, ReadBytes: d.ReadBytes, WriteBytes: d.WriteBytes}
+			if b, ok := diskBefore[name]; ok {
+				stat.ReadRate = rate(b.ReadBytes, d.ReadBytes, interval)
+				stat.WriteRate = rate(b.WriteBytes, d.WriteBytes, interval)
+			}
+			r.DiskIO = append(r.DiskIO, stat)
+		}
+		sort.Slice(r.DiskIO, func(i, j int) bool { return r.DiskIO[i].Name < r.DiskIO[j].Name })
+	}
+
+	if info, err := provider.HostInfo(ctx); err != nil {
+		warn("host", err)
+	} else {
+		r.Host = HostInfo{
+			Hostname: info.Hostname,
+			OS:       info.OS + "/" + runtime.GOARCH,
+			Platform: strings.TrimSpace(info.Platform + " " + info.PlatformVersion),
+			Uptime:   info.Uptime,
+		}
+	}
+
+	if temps, err := provider.Temperatures(ctx); err != nil && len(temps) == 0 {
+		warn("temperatures", err)
+	} else {
+		for _, t := range temps {
+			if t.Temperature <= 0 {
+				continue
+			}
+			r.Temperatures = append(r.Temperatures, TempStats{Sensor: t.SensorKey, Celsius: t.Temperature, High: t.High, Critical: t.Critical})
+		}
+		sort.Slice(r.Temperatures, func(i, j int) bool { return r.Temperatures[i].Sensor < r.Temperatures[j].Sensor })
+	}
+
+	if c.TopN > 0 && procsBefore != nil {
+		procsAfter, err := provider.Processes(ctx)
+		if err != nil {
+			warn("processes", err)
+		} else {
+			r.TopCPU, r.TopMemory = topProcesses(procsBefore, procsAfter, interval, c.TopN)
+		}
+	}
+	return r, nil
+}
+
+// NetworkRate sums the receive and send rates of every interface except
+// loopback.
+func (r *Report) NetworkRate() (recv, sent float64) {
+	for _, n := range r.Network {
+		if n.Name == "lo" || strings.HasPrefix(n.Name, "lo0") {
+			continue
+		}
+		recv += n.RecvRate
+		sent += n.SentRate
+	}
+	return recv, sent
+}
+
+// DiskIORate sums the read and write rates of whole block devices. Partitions
+// (sda1 next to sda), loop and ram devices are skipped so traffic is not
+// counted twice.
+func (r *Report) DiskIORate() (read, write float64) {
+	names := make(map[string]bool, len(r.DiskIO))
+	for _, d := range r.DiskIO {
+		names[d.Name] = true
+	}
+	for _, d := range r.DiskIO {
+		if strings.HasPrefix(d.Name, "loop") || strings.HasPrefix(d.Name, "ram") || isPartition(d.Name, names) {
+			continue
+		}
+		read += d.ReadRate
+		write += d.WriteRate
+	}
+	return read, write
+}
+
+// isPartition reports whether name is a partition of another device: the
+// device name followed by a partition number, such as sda1 for sda, or by p
+// and a number when the device name ends in a digit, such as nvme0n1p2 for
+// nvme0n1. dm-10, md10 and sdaa are whole devices.
+func isPartition(name string, devices map[string]bool) bool {
+	for dev := range devices {
+		rest, ok := strings.CutPrefix(name, dev)
+		if !ok || rest == "" {
+			continue
+		}
+		if last := dev[len(dev)-1]; last >= '0' && last <= '9' {
+			if rest, ok = strings.CutPrefix(rest, "p"); !ok {
+				continue
+			}
+		}
+		if isDigits(rest) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Collector) collectDisks(ctx context.Context, provider Provider, r *Report, warn func(string, error)) error {
+	parts, err := provider.Partitions(ctx)
+	if err != nil {
+		return fmt.Errorf("disk: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, p := range parts {
+		if seen[p.Mountpoint] {
+			continue
+		}
+		seen[p.Mountpoint] = true
+		usage, err := provider.DiskUsage(ctx, p.Mountpoint)
+		if err != nil {
+			warn("disk "+p.Mountpoint, err)
+			continue
+		}
+		if usage.Total == 0 {
+			continue
+		}
+		r.Disks = append(r.Disks, DiskStats{
+			Mountpoint:        p.Mountpoint,
+			Device:            p.Device,
+			Fstype:            p.Fstype,
+			Total:             usage.Total,
+			Used:              usage.Used,
+			UsedPercent:       usage.UsedPercent,
+			InodesTotal:       usage.InodesTotal,
+			InodesUsed:        usage.InodesUsed,
+			InodesUsedPercent: usage.InodesUsedPercent,
+		})
+	}
+	sort.Slice(r.Disks, func(i, j int) bool { return r.Disks[i].Mountpoint < r.Disks[j].Mountpoint })
+	return nil
+}
+
+// cpuPercents returns the busy percentage of each CPU between two samples.
+func cpuPercents(before, after []cpu.TimesStat) []float64 {
+	prev := make(map[string]cpu.TimesStat, len(before))
+	for _, t := range before {
+		prev[t.CPU] = t
+	}
+	out := make([]float64, 0, len(after))
+	for _, t := range after {
+		b, ok := prev[t.CPU]
+		if !ok {
+			out = append(out, 0)
+			continue
+		}
+		busy := busyTime(t) - busyTime(b)
+		total := (busyTime(t) + t.Idle + t.Iowait) - (busyTime(b) + b.Idle + b.Iowait)
+		pct := 0.0
+		if total > 0 {
+			pct = clamp(busy / total * 100)
+		}
+		out = append(out, pct)
+	}
+	return out
+}
+
+// busyTime excludes idle and iowait; guest time is already part of user time.
+func busyTime(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Nice + t.Irq + t.Softirq + t.Steal
+}
+
+func topProcesses(before, after []ProcessSample, interval time.Duration, n int) (byCPU, byMem []ProcessStat) {
+	prev := make(map[int32]ProcessSample, len(before))
+	for _, p := range before {
+		prev[p.PID] = p
+	}
+	stats := make([]ProcessStat, 0, len(after))
+	for _, p := range after {
+		s := ProcessStat{PID: p.PID, Name: p.Name, MemPercent: p.MemPercent, RSS: p.RSS}
+		if b, ok := prev[p.PID]; ok && interval > 0 {
+			s.CPUPercent = (p.CPUSeconds - b.CPUSeconds) / interval.Seconds() * 100
+			if s.CPUPercent < 0 {
+				s.CPUPercent = 0
+			}
+		}
+		stats = append(stats, s)
+	}
+
+	byCPU = append([]ProcessStat(nil), stats...)
+	sort.SliceStable(byCPU, func(i, j int) bool { return byCPU[i].CPUPercent > byCPU[j].CPUPercent })
+	byMem = append([]ProcessStat(nil), stats...)
+	sort.SliceStable(byMem, func(i, j int) bool { return byMem[i].RSS > byMem[j].RSS })
+	return byCPU[:min(n, len(byCPU))], byMem[:min(n, len(byMem))]
+}
+
+// rate converts a counter delta to a per-second rate; counter resets yield 0.
+func rate(before, after uint64, interval time.Duration) float64 {
+	if after < before || interval <= 0 {
+		return 0
+	}
+	return float64(after-before) / interval.Seconds()
+}
+
+func firstOrZero(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	return values[0]
+}
+
+func clamp(pct float64) float64 {
+	return max(0, min(100, pct))
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
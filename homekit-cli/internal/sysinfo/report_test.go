@@ -0,0 +1,41 @@
+package sysinfo
+
+import "testing"
+
+func TestIsPartition(t *testing.T) {
+	devices := map[string]bool{}
+	for _, d := range []string{"sda", "sda1", "sdaa", "nvme0n1", "nvme0n1p2", "dm-1", "dm-10", "md1", "md10", "mmcblk0", "mmcblk0p1"} {
+		devices[d] = true
+	}
+	for name, want := range map[string]bool{
+		"sda":       false,
+		"sda1":      true,
+		"sdaa":      false,
+		"nvme0n1":   false,
+		"nvme0n1p2": true,
+		"dm-1":      false,
+		"dm-10":     false,
+		"md1":       false,
+		"md10":      false,
+		"mmcblk0":   false,
+		"mmcblk0p1": true,
+	} {
+		if got := isPartition(name, devices); got != want {
+			t.Errorf("isPartition(%s) = %t, want %t", name, got, want)
+		}
+	}
+}
+
+func TestDiskIORateCountsWholeDevices(t *testing.T) {
+	r := &Report{DiskIO: []DiskIOStats{
+		{Name: "sda", ReadRate: 100, WriteRate: 10},
+		{Name: "sda1", ReadRate: 100, WriteRate: 10},
+		{Name: "dm-1", ReadRate: 1, WriteRate: 2},
+		{Name: "dm-10", ReadRate: 3, WriteRate: 4},
+		{Name: "loop0", ReadRate: 1000, WriteRate: 1000},
+	}}
+	read, write := r.DiskIORate()
+	if read != 104 || write != 16 {
+		t.Fatalf("DiskIORate = %v, %v; want 104, 16", read, write)
+	}
+}
@@ -0,0 +1,218 @@
+package sysinfo
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Status is an overall or per-metric health state. Its exit code follows the
+// Nagios plugin convention.
+type Status int
+
+const (
+	StatusOK Status = iota
+	StatusWarning
+	StatusCritical
+	StatusUnknown
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "OK"
+	case StatusWarning:
+		return "WARNING"
+	case StatusCritical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ExitCode returns 0, 1, 2 or 3 for OK, WARNING, CRITICAL and UNKNOWN.
+func (s Status) ExitCode() int {
+	return int(s)
+}
+
+// MarshalText encodes the status by name.
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Threshold holds the warning and critical levels of one metric. A level of
+// zero disables it.
+type Threshold struct {
+	Warn float64 `mapstructure:"warn" json:"warn"`
+	Crit float64 `mapstructure:"crit" json:"crit"`
+}
+
+// Evaluate returns the status of value against the threshold.
+func (t Threshold) Evaluate(value float64) Status {
+	switch {
+	case t.Crit > 0 && value >= t.Crit:
+		return StatusCritical
+	case t.Warn > 0 && value >= t.Warn:
+		return StatusWarning
+	default:
+		return StatusOK
+	}
+}
+
+// Metric names accepted in threshold configuration.
+const (
+	MetricCPU         = "cpu"
+	MetricMemory      = "memory"
+	MetricSwap        = "swap"
+	MetricDisk        = "disk"
+	MetricInodes      = "inodes"
+	MetricLoad        = "load"
+	MetricTemperature = "temperature"
+)
+
+// Thresholds maps metric names to levels. CPU, memory, swap, disk and inode
+// levels are percentages, load is the 5-minute load average per core, and
+// temperature is in degrees Celsius.
+type Thresholds map[string]Threshold
+
+// DefaultThresholds returns the built-in levels.
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		MetricCPU:         {Warn: 85, Crit: 95},
+		MetricMemory:      {Warn: 85, Crit: 95},
+		MetricSwap:        {Warn: 50, Crit: 80},
+		MetricDisk:        {Warn: 80, Crit: 90},
+		MetricInodes:      {Warn: 80, Crit: 90},
+		MetricLoad:        {Warn: 1.5, Crit: 3},
+		MetricTemperature: {Warn: 80, Crit: 90},
+	}
+}
+
+// ThresholdOverride changes some levels of a metric; a nil level keeps the
+// current one, so `disk: {warn: 70}` leaves the critical level alone.
+type ThresholdOverride struct {
+	Warn *float64 `mapstructure:"warn" json:"warn,omitempty"`
+	Crit *float64 `mapstructure:"crit" json:"crit,omitempty"`
+}
+
+// ThresholdOverrides maps metric names to partial levels, as read from the
+// health.thresholds config section.
+type ThresholdOverrides map[string]ThresholdOverride
+
+// Merge returns a copy of t with the levels set in other applied on top.
+func (t Thresholds) Merge(other ThresholdOverrides) (Thresholds, error) {
+	out := make(Thresholds, len(t))
+	for k, v := range t {
+		out[k] = v
+	}
+	for k, v := range other {
+		k = strings.ToLower(k)
+		if _, ok := DefaultThresholds()[k]; !ok {
+			return nil, fmt.Errorf("unknown health metric %q", k)
+		}
+		level := out[k]
+		if v.Warn != nil {
+			level.Warn = *v.Warn
+		}
+		if v.Crit != nil {
+			level.Crit = *v.Crit
+		}
+		out[k] = level
+	}
+	return out, nil
+}
+
+// ParseThreshold parses a `metric=warn:crit` flag value. Either level may be
+// empty to keep the current one.
+func (t Thresholds) ParseThreshold(expr string) error {
+	metric, levels, ok := strings.Cut(expr, "=")
+	if !ok {
+		return fmt.Errorf("threshold %q: expected metric=warn:crit", expr)
+	}
+	metric = strings.ToLower(strings.TrimSpace(metric))
+	if _, ok := DefaultThresholds()[metric]; !ok {
+		return fmt.Errorf("threshold %q: unknown metric %q", expr, metric)
+	}
+	warn, crit, _ := strings.Cut(levels, ":")
+	current := t[metric]
+	for _, level := range []struct {
+		raw  string
+		dest *float64
+	}{{warn, &current.Warn}, {crit, &current.Crit}} {
+		if strings.TrimSpace(level.raw) == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(level.raw), 64)
+		if err != nil {
+			return fmt.Errorf("threshold %q: %w", expr, err)
+		}
+		*level.dest = v
+	}
+	t[metric] = current
+	return nil
+}
+
+// Finding is a metric that reached a threshold.
+type Finding struct {
+	Metric    string  `json:"metric"`
+	Subject   string  `json:"subject,omitempty"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Status    Status  `json:"status"`
+}
+
+func (f Finding) String() string {
+	name := f.Metric
+	if f.Subject != "" {
+		name += " " + f.Subject
+	}
+	return fmt.Sprintf("%s %.1f >= %.1f", name, f.Value, f.Threshold)
+}
+
+// Evaluation is the result of checking a Report against Thresholds.
+type Evaluation struct {
+	Status   Status    `json:"status"`
+	Findings []Finding `json:"findings"`
+}
+
+// Evaluate checks every metric of r. The overall status is the worst finding.
+func (t Thresholds) Evaluate(r *Report) Evaluation {
+	var ev Evaluation
+	check := func(metric, subject string, value float64) {
+		level := t[metric]
+		status := level.Evaluate(value)
+		if status == StatusOK {
+			return
+		}
+		limit := level.Warn
+		if status == StatusCritical {
+			limit = level.Crit
+		}
+		ev.Findings = append(ev.Findings, Finding{Metric: metric, Subject: subject, Value: value, Threshold: limit, Status: status})
+		if status > ev.Status {
+			ev.Status = status
+		}
+	}
+
+	check(MetricCPU, "", r.CPU.Total)
+	check(MetricMemory, "", r.Memory.UsedPercent)
+	if r.Swap.Total > 0 {
+		check(MetricSwap, "", r.Swap.UsedPercent)
+	}
+	if r.CPU.Cores > 0 {
+		check(MetricLoad, "", r.CPU.Load5/float64(r.CPU.Cores))
+	}
+	for _, d := range r.Disks {
+		check(MetricDisk, d.Mountpoint, d.UsedPercent)
+		if d.InodesTotal > 0 {
+			check(MetricInodes, d.Mountpoint, d.InodesUsedPercent)
+		}
+	}
+	for _, temp := range r.Temperatures {
+		check(MetricTemperature, temp.Sensor, temp.Celsius)
+	}
+
+	sort.SliceStable(ev.Findings, func(i, j int) bool { return ev.Findings[i].Status > ev.Findings[j].Status })
+	return ev
+}
@@ -0,0 +1,159 @@
+package sysinfo
+
+import (
+	"testing"
+)
+
+func TestThresholdEvaluate(t *testing.T) {
+	level := Threshold{Warn: 80, Crit: 90}
+	for value, want := range map[float64]Status{
+		0:    StatusOK,
+		79.9: StatusOK,
+		80:   StatusWarning,
+		89.9: StatusWarning,
+		90:   StatusCritical,
+		150:  StatusCritical,
+	} {
+		if got := level.Evaluate(value); got != want {
+			t.Errorf("Evaluate(%v) = %s, want %s", value, got, want)
+		}
+	}
+	if got := (Threshold{Crit: 90}).Evaluate(85); got != StatusOK {
+		t.Errorf("disabled warning level: got %s", got)
+	}
+	if got := (Threshold{}).Evaluate(1e9); got != StatusOK {
+		t.Errorf("disabled levels: got %s", got)
+	}
+}
+
+func TestStatusExitCode(t *testing.T) {
+	for status, want := range map[Status]int{
+		StatusOK:       0,
+		StatusWarning:  1,
+		StatusCritical: 2,
+		StatusUnknown:  3,
+	} {
+		if got := status.ExitCode(); got != want {
+			t.Errorf("%s.ExitCode() = %d, want %d", status, got, want)
+		}
+	}
+}
+
+func TestThresholdsEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider fakeProvider
+		want     Status
+		findings []string
+	}{
+		{
+			name:     "healthy",
+			provider: fakeProvider{cpuBusy: 10, cores: 4, memory: 30, disks: map[string]float64{"/": 40}},
+			want:     StatusOK,
+		},
+		{
+			name:     "disk warning",
+			provider: fakeProvider{cpuBusy: 10, cores: 4, memory: 30, disks: map[string]float64{"/": 85, "/home": 10}},
+			want:     StatusWarning,
+			findings: []string{"disk /"},
+		},
+		{
+			name:     "critical first",
+			provider: fakeProvider{cpuBusy: 10, cores: 4, memory: 96, disks: map[string]float64{"/": 85}},
+			want:     StatusCritical,
+			findings: []string{"memory", "disk /"},
+		},
+		{
+			name:     "load per core",
+			provider: fakeProvider{cores: 2, load5: 4, disks: map[string]float64{"/": 1}},
+			want:     StatusWarning,
+			findings: []string{"load"},
+		},
+		{
+			name:     "swap and temperature",
+			provider: fakeProvider{cores: 1, swap: 85, disks: map[string]float64{"/": 1}, temps: map[string]float64{"cpu_thermal": 91}},
+			want:     StatusCritical,
+			findings: []string{"swap", "temperature cpu_thermal"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval := DefaultThresholds().Evaluate(collect(t, &tt.provider))
+			if eval.Status != tt.want {
+				t.Errorf("Status = %s, want %s", eval.Status, tt.want)
+			}
+			var got []string
+			for _, f := range eval.Findings {
+				name := f.Metric
+				if f.Subject != "" {
+					name += " " + f.Subject
+				}
+				got = append(got, name)
+			}
+			if len(got) != len(tt.findings) {
+				t.Fatalf("findings = %q, want %q", got, tt.findings)
+			}
+			for i := range got {
+				if got[i] != tt.findings[i] {
+					t.Fatalf("findings = %q, want %q", got, tt.findings)
+				}
+			}
+		})
+	}
+}
+
+func TestParseThreshold(t *testing.T) {
+	levels := DefaultThresholds()
+	for _, expr := range []string{"disk=70:", "CPU=:99", "load = 2:4"} {
+		if err := levels.ParseThreshold(expr); err != nil {
+			t.Fatalf("ParseThreshold(%q): %v", expr, err)
+		}
+	}
+	for metric, want := range map[string]Threshold{
+		MetricDisk: {Warn: 70, Crit: 90},
+		MetricCPU:  {Warn: 85, Crit: 99},
+		MetricLoad: {Warn: 2, Crit: 4},
+	} {
+		if levels[metric] != want {
+			t.Errorf("%s = %+v, want %+v", metric, levels[metric], want)
+		}
+	}
+	for _, expr := range []string{"disk", "gpu=1:2", "disk=x:2"} {
+		if err := levels.ParseThreshold(expr); err == nil {
+			t.Errorf("ParseThreshold(%q) succeeded", expr)
+		}
+	}
+}
+
+func TestThresholdsMerge(t *testing.T) {
+	level := func(v float64) *float64 { return &v }
+	defaults := DefaultThresholds()
+	tests := []struct {
+		name  string
+		other ThresholdOverrides
+		want  Threshold
+	}{
+		{"both levels", ThresholdOverrides{"Disk": {Warn: level(60), Crit: level(70)}}, Threshold{Warn: 60, Crit: 70}},
+		{"warn only", ThresholdOverrides{"disk": {Warn: level(70)}}, Threshold{Warn: 70, Crit: defaults[MetricDisk].Crit}},
+		{"crit only", ThresholdOverrides{"disk": {Crit: level(99)}}, Threshold{Warn: defaults[MetricDisk].Warn, Crit: 99}},
+		{"disabled warn", ThresholdOverrides{"disk": {Warn: level(0)}}, Threshold{Warn: 0, Crit: defaults[MetricDisk].Crit}},
+		{"empty", ThresholdOverrides{"disk": {}}, defaults[MetricDisk]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := defaults.Merge(tt.other)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if merged[MetricDisk] != tt.want {
+				t.Errorf("disk = %+v, want %+v", merged[MetricDisk], tt.want)
+			}
+			if merged[MetricCPU] != defaults[MetricCPU] {
+				t.Errorf("cpu = %+v, want the default", merged[MetricCPU])
+			}
+		})
+	}
+	if _, err := defaults.Merge(ThresholdOverrides{"gpu": {}}); err == nil {
+		t.Fatal("Merge accepted an unknown metric")
+	}
+}
@@ -0,0 +1,219 @@
+package templating
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/homekit/homekit-cli/internal/util/maputil"
+)
+
+// StdinData is the data file name that reads from standard input.
+const StdinData = "-"
+
+// DataSources lists template data inputs. They are applied in field order, so
+// later sources win: files (deep-merged left to right), environment, --set,
+// then --set-string.
+type DataSources struct {
+	Files     []string
+	EnvPrefix string
+	Set       []string
+	SetString []string
+	Stdin     io.Reader
+	Environ   []string
+}
+
+// LoadData builds the template data tree from all sources.
+func LoadData(src DataSources) (map[string]any, error) {
+	out := map[string]any{}
+
+	stdinUsed := false
+	for _, path := range src.Files {
+		var (
+			content []byte
+			err     error
+		)
+		if path == StdinData {
+			if stdinUsed {
+				return nil, errors.New("data from stdin (-) can only be given once")
+			}
+			stdinUsed = true
+			if src.Stdin == nil {
+				return nil, errors.New("data from stdin requested but no stdin available")
+			}
+			content, err = io.ReadAll(src.Stdin)
+		} else {
+			content, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read data file %s: %w", path, err)
+		}
+		doc, err := DecodeData(path, content)
+		if err != nil {
+			return nil, fmt.Errorf("parse data file %s: %w", path, err)
+		}
+		out = maputil.DeepMerge(out, doc)
+	}
+
+	if src.EnvPrefix != "" {
+		environ := src.Environ
+		if environ == nil {
+			environ = os.Environ()
+		}
+		if err := applyEnv(out, src.EnvPrefix, environ); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, expr := range src.Set {
+		if err := applySet(out, expr, true); err != nil {
+			return nil, fmt.Errorf("--set %s: %w", expr, err)
+		}
+	}
+	for _, expr := range src.SetString {
+		if err := applySet(out, expr, false); err != nil {
+			return nil, fmt.Errorf("--set-string %s: %w", expr, err)
+		}
+	}
+	return out, nil
+}
+
+// DecodeData parses a data document, choosing the format from the file
+// extension: .json, .toml, otherwise YAML (which also accepts JSON).
+func DecodeData(name string, content []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		if err := toml.Unmarshal(content, &doc); err != nil {
+			return nil, err
+		}
+	default:
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, err
+		}
+		if doc == nil {
+			doc = map[string]any{}
+		}
+	}
+	return doc, nil
+}
+
+// applyEnv lifts PREFIX_* variables into the data tree. The prefix is removed
+// and a double underscore separates nesting levels: APP_DB__HOST=x sets .DB.HOST.
+func applyEnv(out map[string]any, prefix string, environ []string) error {
+	for _, kv := range environ {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(key, prefix)
+		if name == "" {
+			continue
+		}
+		path := strings.Join(strings.Split(escapeDots(name), "__"), ".")
+		if err := maputil.SetPath(out, path, inferValue(value)); err != nil {
+			return fmt.Errorf("environment %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// applySet applies one --set expression: comma-separated key=value pairs where
+// `\,` escapes a comma and `{a,b}` builds a list.
+func applySet(out map[string]any, expr string, infer bool) error {
+	for _, assignment := range splitUnescaped(expr, ',', true) {
+		key, raw, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return fmt.Errorf("expected key=value, got %q", assignment)
+		}
+		var value any
+		if strings.HasPrefix(raw, "{") && strings.HasSuffix(raw, "}") {
+			items := splitUnescaped(raw[1:len(raw)-1], ',', false)
+			list := make([]any, 0, len(items))
+			for _, item := range items {
+				list = append(list, convertValue(unescape(item), infer))
+			}
+			value = list
+		} else {
+			value = convertValue(unescape(raw), infer)
+		}
+		if err := maputil.SetPath(out, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func convertValue(raw string, infer bool) any {
+	if !infer {
+		return raw
+	}
+	return inferValue(raw)
+}
+
+// inferValue maps true/false, null and integers to typed values; everything
+// else stays a string.
+func inferValue(raw string) any {
+	switch raw {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if i, err := strconv.ParseInt(raw, 10, 64); err == nil && (raw == "0" || !strings.HasPrefix(raw, "0")) {
+		return i
+	}
+	return raw
+}
+
+// splitUnescaped splits on sep, ignoring separators preceded by a backslash
+// and, when braces is set, separators inside `{...}` lists.
+func splitUnescaped(value string, sep byte, braces bool) []string {
+	var (
+		parts []string
+		start int
+		depth int
+	)
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '{':
+			if braces {
+				depth++
+			}
+		case '}':
+			if braces && depth > 0 {
+				depth--
+			}
+		case sep:
+			if depth == 0 {
+				parts = append(parts, value[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, value[start:])
+}
+
+func unescape(value string) string {
+	return strings.ReplaceAll(value, `\,`, ",")
+}
+
+func escapeDots(value string) string {
+	return strings.ReplaceAll(value, ".", `\.`)
+}
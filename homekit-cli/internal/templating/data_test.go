@@ -0,0 +1,84 @@
+package templating
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadDataSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		set       []string
+		setString []string
+		want      map[string]any
+		err       string
+	}{
+		{name: "nested list", set: []string{"a.b[0].c=x"}, want: map[string]any{"a": map[string]any{"b": []any{map[string]any{"c": "x"}}}}},
+		{name: "append in order", set: []string{"l[0]=a", "l[1]=b,l[0]=c"}, want: map[string]any{"l": []any{"c", "b"}}},
+		{name: "inferred types", set: []string{"n=8080,on=true,off=false,nil=null,zip=0123"}, want: map[string]any{"n": int64(8080), "on": true, "off": false, "nil": nil, "zip": "0123"}},
+		{name: "set-string", setString: []string{"n=8080,on=true"}, want: map[string]any{"n": "8080", "on": "true"}},
+		{name: "set-string wins", set: []string{"tag=1"}, setString: []string{"tag=1"}, want: map[string]any{"tag": "1"}},
+		{name: "brace list", set: []string{"hosts={a,b,3}"}, want: map[string]any{"hosts": []any{"a", "b", int64(3)}}},
+		{name: "escaped comma", set: []string{`msg=a\,b,x=1`}, want: map[string]any{"msg": "a,b", "x": int64(1)}},
+		{name: "escaped dot", set: []string{`labels.traefik\.enable=true`}, want: map[string]any{"labels": map[string]any{"traefik.enable": true}}},
+		{name: "index past end", set: []string{"l[1]=x"}, err: "--set l[1]=x"},
+		{name: "index past end set-string", setString: []string{"l[0]=x,l[2]=y"}, err: "--set-string l[0]=x,l[2]=y"},
+		{name: "missing value", set: []string{"a"}, err: `expected key=value, got "a"`},
+		{name: "empty key", set: []string{"a..b=1"}, err: "empty key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadData(DataSources{Set: tt.set, SetString: tt.setString})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDataPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	over := filepath.Join(dir, "over.json")
+	if err := os.WriteFile(base, []byte("db:\n  host: localhost\n  port: 5432\nname: base\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(over, []byte(`{"db": {"port": 6432}, "env": "json"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadData(DataSources{
+		Files:     []string{base, over, StdinData},
+		Stdin:     strings.NewReader("name: stdin\n"),
+		EnvPrefix: "APP_",
+		Environ:   []string{"APP_ENV=env", "APP_db__user=admin", "OTHER=x", "APP_=x"},
+		Set:       []string{"db.host=db"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"db":   map[string]any{"host": "db", "port": float64(6432), "user": "admin"},
+		"name": "stdin",
+		"env":  "json",
+		"ENV":  "env",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	if _, err := LoadData(DataSources{Files: []string{StdinData, StdinData}, Stdin: strings.NewReader("")}); err == nil {
+		t.Fatal("expected an error for stdin given twice")
+	}
+}
@@ -0,0 +1,180 @@
+package templating
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/homekit/homekit-cli/internal/util/ignoreutil"
+)
+
+const (
+	// IgnoreFile lists patterns, one per line, excluded from directory rendering.
+	IgnoreFile = ".tmplignore"
+	// DirSchemaFile optionally validates data for a whole template directory.
+	DirSchemaFile = "schema.json"
+	// templateExt is stripped from rendered file names.
+	templateExt = ".tmpl"
+)
+
+// ErrPathCollision is returned when two templates of a directory render to
+// the same output path.
+var ErrPathCollision = errors.New("rendered paths collide")
+
+// RenderedFile is one output of RenderDir.
+type RenderedFile struct {
+	// Source is the template path relative to the template root.
+	Source string
+	// Path is the rendered, slash-separated destination path.
+	Path    string
+	Content []byte
+	Mode    fs.FileMode
+}
+
+// RenderDir renders every file below src. All files are parsed into a single
+// template set, so `define` blocks are shared; files whose name starts with `_`
+// are partials and produce no output. File paths are rendered as templates too,
+// a trailing .tmpl is removed, and a path that renders empty skips the file.
+// Two files rendering to the same path are an ErrPathCollision.
+// Sidecar schemas are applied per file and never rendered.
+func (r Renderer) RenderDir(src fs.FS, data any) ([]RenderedFile, error) {
+	ignore, err := ignoreutil.Load(src, IgnoreFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	err = fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == "." {
+			return nil
+		}
+		if ignore.Match(p, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || p == IgnoreFile || p == DirSchemaFile || strings.HasSuffix(p, sidecarSchemaExt) {
+			return nil
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	if content, err := fs.ReadFile(src, DirSchemaFile); err == nil {
+		schema, err := ParseSchema(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", DirSchemaFile, err)
+		}
+		if err := schema.Validate(data); err != nil {
+			return nil, fmt.Errorf("data does not match %s:\n%w", DirSchemaFile, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	set := r.New("render-dir")
+	offsets := map[string]int{}
+	modes := map[string]fs.FileMode{}
+	for _, name := range files {
+		content, err := fs.ReadFile(src, name)
+		if err != nil {
+			return nil, err
+		}
+		fm, body, offset, err := SplitFrontMatter(content)
+		if err != nil {
+			return nil, &RenderError{File: name, Line: 1, Err: err}
+		}
+		schema := fm.Schema
+		if schema == nil {
+			if schema, err = LoadSidecarSchema(src, name); err != nil {
+				return nil, err
+			}
+		}
+		if err := schema.Validate(data); err != nil {
+			return nil, fmt.Errorf("%s: data does not match schema:\n%w", name, err)
+		}
+		offsets[name] = offset
+		if _, err := set.New(name).Parse(string(body)); err != nil {
+			return nil, fmt.Errorf("parse template: %w", locate(name, offsets, err))
+		}
+		modes[name] = 0o644
+		if info, err := fs.Stat(src, name); err == nil && info.Mode()&0o111 != 0 {
+			modes[name] = 0o755
+		}
+	}
+
+	var out []RenderedFile
+	sources := map[string]string{}
+	for _, name := range files {
+		if isPartial(name) {
+			continue
+		}
+		target, err := r.renderPath(name, data)
+		if err != nil {
+			return nil, err
+		}
+		if target == "" {
+			continue
+		}
+		if other, ok := sources[target]; ok {
+			return nil, fmt.Errorf("%w: %s and %s both render to %s", ErrPathCollision, other, name, target)
+		}
+		sources[target] = name
+		var buf bytes.Buffer
+		if err := set.ExecuteTemplate(&buf, name, data); err != nil {
+			return nil, fmt.Errorf("execute template: %w", locate(name, offsets, err))
+		}
+		out = append(out, RenderedFile{
+			Source:  name,
+			Path:    target,
+			Content: buf.Bytes(),
+			Mode:    modes[name],
+		})
+	}
+	// a file cannot also be the directory of another one
+	for _, f := range out {
+		for dir := path.Dir(f.Path); dir != "."; dir = path.Dir(dir) {
+			if other, ok := sources[dir]; ok {
+				return nil, fmt.Errorf("%w: %s renders to %s, which %s needs as a directory", ErrPathCollision, other, dir, f.Source)
+			}
+		}
+	}
+	return out, nil
+}
+
+// renderPath renders a template path and checks the result stays relative.
+func (r Renderer) renderPath(name string, data any) (string, error) {
+	rendered := name
+	if strings.Contains(name, "{{") {
+		var err error
+		rendered, err = r.RenderString(name, name, data)
+		if err != nil {
+			return "", fmt.Errorf("render path: %w", err)
+		}
+	}
+	rendered = strings.TrimSpace(rendered)
+	if rendered == "" || strings.HasSuffix(rendered, "/") {
+		return "", nil
+	}
+	rendered = strings.TrimSuffix(path.Clean(rendered), templateExt)
+	if !fs.ValidPath(rendered) || rendered == "." {
+		return "", fmt.Errorf("render path: %s rendered to invalid path %q", name, rendered)
+	}
+	return rendered, nil
+}
+
+func isPartial(name string) bool {
+	return strings.HasPrefix(path.Base(name), "_")
+}
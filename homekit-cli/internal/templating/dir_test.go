@@ -0,0 +1,69 @@
+package templating
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderDirPathCollision(t *testing.T) {
+	for name, src := range map[string]fstest.MapFS{
+		"same file": {
+			"{{ .Name }}/compose.yml": {Data: []byte("a")},
+			"web/compose.yml":         {Data: []byte("b")},
+		},
+		"tmpl suffix": {
+			"compose.yml":      {Data: []byte("a")},
+			"compose.yml.tmpl": {Data: []byte("b")},
+		},
+		"file and directory": {
+			"{{ .Name }}":     {Data: []byte("a")},
+			"web/compose.yml": {Data: []byte("b")},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Renderer{}.RenderDir(src, map[string]any{"Name": "web"})
+			if !errors.Is(err, ErrPathCollision) {
+				t.Fatalf("got %v, want ErrPathCollision", err)
+			}
+		})
+	}
+}
+
+func TestRenderDirDistinctPaths(t *testing.T) {
+	src := fstest.MapFS{
+		"{{ .Name }}/compose.yml.tmpl": {Data: []byte("image: {{ .Name }}")},
+		"README.md":                    {Data: []byte("static")},
+	}
+	files, err := Renderer{}.RenderDir(src, map[string]any{"Name": "web"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range files {
+		got[f.Path] = string(f.Content)
+	}
+	if len(got) != 2 || got["web/compose.yml"] != "image: web" || !strings.Contains(got["README.md"], "static") {
+		t.Fatalf("unexpected files %v", got)
+	}
+}
+
+func TestRenderDirSidecarSchemas(t *testing.T) {
+	src := fstest.MapFS{
+		"app.yml.tmpl":        {Data: []byte("port: {{ .Port }}")},
+		"app.yml.schema.json": {Data: []byte(`{"required": ["Port"]}`)},
+		"other.yml":           {Data: []byte("name: {{ .Name }}")},
+	}
+	files, err := Renderer{}.RenderDir(src, map[string]any{"Port": 80})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Path != "app.yml" || files[1].Path != "other.yml" {
+		t.Fatalf("unexpected files %+v", files)
+	}
+	_, err = Renderer{}.RenderDir(src, map[string]any{"Name": "web"})
+	if err == nil || !strings.Contains(err.Error(), "app.yml.tmpl: data does not match schema:\n.Port: is required") {
+		t.Fatalf("error = %v", err)
+	}
+}
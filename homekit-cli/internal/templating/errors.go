@@ -0,0 +1,68 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"text/template"
+)
+
+// RenderError locates a template failure in its source file.
+type RenderError struct {
+	File string
+	Line int
+	// Path is the template expression being evaluated, such as `.Image.Tag`.
+	Path string
+	Err  error
+}
+
+func (e *RenderError) Error() string {
+	loc := e.File
+	if e.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s: %v", loc, e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", loc, e.Err)
+}
+
+func (e *RenderError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// template: NAME:LINE:COL: executing "NAME" at <PATH>: MESSAGE
+	execErrPattern = regexp.MustCompile(`^template: (.*?):(\d+):\d+: executing ".*?" at <(.*?)>: (.*)$`)
+	// template: NAME:LINE: MESSAGE
+	parseErrPattern = regexp.MustCompile(`^template: (.*?):(\d+): (.*)$`)
+)
+
+// locate converts text/template errors into RenderErrors. The failing template
+// name is taken from the error when present (it may be a partial rather than
+// file) and its line is shifted by that template's front matter offset.
+func locate(file string, offsets map[string]int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var rerr *RenderError
+	if errors.As(err, &rerr) {
+		return err
+	}
+
+	msg := err.Error()
+	var execErr template.ExecError
+	if errors.As(err, &execErr) {
+		if m := execErrPattern.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[2])
+			return &RenderError{File: m[1], Line: line + offsets[m[1]], Path: m[3], Err: errors.New(m[4])}
+		}
+		return &RenderError{File: file, Err: err}
+	}
+	if m := parseErrPattern.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		return &RenderError{File: m[1], Line: line + offsets[m[1]], Err: errors.New(m[3])}
+	}
+	return &RenderError{File: file, Err: err}
+}
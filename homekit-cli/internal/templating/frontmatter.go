@@ -0,0 +1,62 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const frontMatterDelim = "---"
+
+// FrontMatter is optional YAML metadata at the top of a template, enclosed in
+// `---` lines. It is stripped before rendering.
+type FrontMatter struct {
+	Schema *Schema `yaml:"schema"`
+}
+
+// SplitFrontMatter separates front matter from the template body. It returns
+// the number of lines consumed so error positions can be mapped back to the
+// original file.
+func SplitFrontMatter(content []byte) (FrontMatter, []byte, int, error) {
+	var fm FrontMatter
+	first, rest, ok := cutLine(content)
+	if !ok || string(bytes.TrimRight(first, "\r")) != frontMatterDelim {
+		return fm, content, 0, nil
+	}
+
+	var meta []byte
+	lines := 1
+	for {
+		line, next, more := cutLine(rest)
+		lines++
+		if string(bytes.TrimRight(line, "\r")) == frontMatterDelim {
+			rest = next
+			break
+		}
+		if !more {
+			return fm, content, 0, fmt.Errorf("front matter: missing closing %q", frontMatterDelim)
+		}
+		meta = append(meta, line...)
+		meta = append(meta, '\n')
+		rest = next
+	}
+
+	if err := yaml.Unmarshal(meta, &fm); err != nil {
+		return fm, content, 0, fmt.Errorf("front matter: %w", err)
+	}
+	if fm.Schema != nil {
+		if err := fm.Schema.compile("."); err != nil {
+			return fm, content, 0, fmt.Errorf("front matter: %w", err)
+		}
+	}
+	return fm, rest, lines, nil
+}
+
+func cutLine(content []byte) (line, rest []byte, more bool) {
+	if len(content) == 0 {
+		return nil, nil, false
+	}
+	before, after, found := bytes.Cut(content, []byte("\n"))
+	return before, after, found
+}
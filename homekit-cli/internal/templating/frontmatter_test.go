@@ -0,0 +1,98 @@
+package templating
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name, content string
+		body          string
+		lines         int
+		schema        bool
+		err           string
+	}{
+		{name: "none", content: "image: {{ .Image }}\n", body: "image: {{ .Image }}\n"},
+		{name: "not at the top", content: "a\n---\nb\n---\n", body: "a\n---\nb\n---\n"},
+		{name: "empty", content: "---\n---\nbody\n", body: "body\n", lines: 2},
+		{name: "schema", content: "---\nschema:\n  required: [Image]\n---\nbody\n", body: "body\n", lines: 4, schema: true},
+		{name: "crlf", content: "---\r\nschema: {type: object}\r\n---\r\nbody\r\n", body: "body\r\n", lines: 3, schema: true},
+		{name: "unclosed", content: "---\nschema: {}\nbody\n", err: `front matter: missing closing "---"`},
+		{name: "bad yaml", content: "---\nschema: [\n---\n", err: "front matter: yaml"},
+		{name: "bad pattern", content: "---\nschema: {pattern: \"(\"}\n---\n", err: "front matter: schema .: invalid pattern"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body, lines, err := SplitFrontMatter([]byte(tt.content))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.body || lines != tt.lines || (fm.Schema != nil) != tt.schema {
+				t.Fatalf("got body %q, %d lines, schema %v", body, lines, fm.Schema != nil)
+			}
+		})
+	}
+}
+
+func TestRenderDocumentSchemaAndStrict(t *testing.T) {
+	const withSchema = "---\nschema:\n  required: [Image]\n---\nimage: {{ .Image }}\ntag: {{ .Tag }}\n"
+	fallback, err := ParseSchema([]byte(`{"required": ["Tag"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		content  string
+		data     map[string]any
+		fallback *Schema
+		strict   bool
+		want     string
+		err      string
+	}{
+		{name: "valid", content: withSchema, data: map[string]any{"Image": "nginx", "Tag": "1"}, want: "image: nginx\ntag: 1\n"},
+		{name: "front matter schema", content: withSchema, data: map[string]any{"Tag": "1"}, err: "compose.yaml: data does not match schema:\n.Image: is required"},
+		{name: "front matter wins over fallback", content: withSchema, fallback: fallback, data: map[string]any{"Image": "nginx"}, want: "image: nginx\ntag: <no value>\n"},
+		{name: "fallback", content: "tag: {{ .Tag }}\n", fallback: fallback, data: map[string]any{}, err: ".Tag: is required"},
+		{name: "strict missing key", content: withSchema, strict: true, data: map[string]any{"Image": "nginx"}, err: `compose.yaml:6: .Tag: map has no entry for key "Tag"`},
+		{name: "parse error line", content: withSchema + "{{ if }}\n", data: map[string]any{"Image": "nginx"}, err: "compose.yaml:7: missing value for if"},
+		{name: "bad front matter", content: "---\nschema: [\n", err: "compose.yaml:1: front matter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := Renderer{Strict: tt.strict}.RenderDocument("compose.yaml", []byte(tt.content), tt.data, tt.fallback, &out)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.want {
+				t.Fatalf("rendered %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderErrorLocation(t *testing.T) {
+	err := Renderer{Strict: true}.RenderDocument("app.tmpl", []byte("---\n---\nline\n{{ .Image.Tag }}\n"), map[string]any{"Image": map[string]any{}}, nil, &bytes.Buffer{})
+	var rerr *RenderError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("error %v is not a RenderError", err)
+	}
+	if rerr.File != "app.tmpl" || rerr.Line != 4 || rerr.Path != ".Image.Tag" {
+		t.Fatalf("RenderError %+v", rerr)
+	}
+}
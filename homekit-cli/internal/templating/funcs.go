@@ -0,0 +1,400 @@
+package templating
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FuncInfo documents a template function for `template funcs`.
+type FuncInfo struct {
+	Name  string
+	Usage string
+}
+
+type builtin struct {
+	usage string
+	fn    any
+}
+
+// builtins is the curated function library shared by every rendering path.
+// Argument order follows sprig so values can be piped as the last argument.
+var builtins = map[string]builtin{
+	// defaults and assertions
+	"default":  {"default DEFAULT VALUE", defaultValue},
+	"required": {"required MESSAGE VALUE", required},
+	"empty":    {"empty VALUE", empty},
+	"coalesce": {"coalesce VALUE...", coalesce},
+	"ternary":  {"ternary IF_TRUE IF_FALSE COND", ternary},
+	"fail":     {"fail MESSAGE", fail},
+
+	// environment
+	"env":       {"env NAME", os.Getenv},
+	"expandenv": {"expandenv STRING", os.ExpandEnv},
+
+	// serialisation
+	"toYaml":       {"toYaml VALUE", toYAML},
+	"fromYaml":     {"fromYaml STRING", fromYAML},
+	"toJson":       {"toJson VALUE", toJSON},
+	"toPrettyJson": {"toPrettyJson VALUE", toPrettyJSON},
+	"fromJson":     {"fromJson STRING", fromJSON},
+
+	// strings
+	"indent":     {"indent N STRING", indent},
+	"nindent":    {"nindent N STRING", nindent},
+	"quote":      {"quote VALUE...", quote},
+	"squote":     {"squote VALUE...", squote},
+	"trim":       {"trim STRING", strings.TrimSpace},
+	"trimPrefix": {"trimPrefix PREFIX STRING", trimPrefix},
+	"trimSuffix": {"trimSuffix SUFFIX STRING", trimSuffix},
+	"upper":      {"upper STRING", strings.ToUpper},
+	"lower":      {"lower STRING", strings.ToLower},
+	"replace":    {"replace OLD NEW STRING", replace},
+	"contains":   {"contains SUBSTR STRING", contains},
+	"hasPrefix":  {"hasPrefix PREFIX STRING", hasPrefix},
+	"hasSuffix":  {"hasSuffix SUFFIX STRING", hasSuffix},
+	"split":      {"split SEP STRING", split},
+	"join":       {"join SEP LIST", join},
+	"toString":   {"toString VALUE", toString},
+
+	// encoding and hashing
+	"b64enc":    {"b64enc STRING", b64enc},
+	"b64dec":    {"b64dec STRING", b64dec},
+	"sha1sum":   {"sha1sum STRING", sha1sum},
+	"sha256sum": {"sha256sum STRING", sha256sum},
+
+	// randomness and time
+	"randAlphaNum": {"randAlphaNum N", randAlphaNum},
+	"randNumeric":  {"randNumeric N", randNumeric},
+	"now":          {"now", time.Now},
+	"date":         {"date LAYOUT TIME", date},
+
+	// collections
+	"list":   {"list VALUE...", list},
+	"dict":   {"dict KEY VALUE...", dict},
+	"hasKey": {"hasKey MAP KEY", hasKey},
+	"keys":   {"keys MAP", keys},
+}
+
+// boundUsage documents functions that need the executing template and are
+// therefore bound per render rather than stored in builtins.
+var boundUsage = map[string]string{
+	"include": "include NAME DATA",
+	"tpl":     "tpl TEMPLATE DATA",
+}
+
+// BuiltinFuncs returns a copy of the built-in function map, excluding the
+// template-bound helpers (include, tpl).
+func BuiltinFuncs() template.FuncMap {
+	out := make(template.FuncMap, len(builtins))
+	for name, b := range builtins {
+		out[name] = b.fn
+	}
+	return out
+}
+
+// Funcs lists the built-in functions, including the template-bound helpers.
+func Funcs() []FuncInfo {
+	out := make([]FuncInfo, 0, len(builtins)+len(boundUsage))
+	for name, b := range builtins {
+		out = append(out, FuncInfo{Name: name, Usage: b.usage})
+	}
+	for name, usage := range boundUsage {
+		out = append(out, FuncInfo{Name: name, Usage: usage})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// boundFuncs returns helpers that execute named templates or template strings
+// within the namespace of t.
+func boundFuncs(t *template.Template) template.FuncMap {
+	return template.FuncMap{
+		"include": func(name string, data any) (string, error) {
+			var buf bytes.Buffer
+			if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
+		"tpl": func(text string, data any) (string, error) {
+			clone, err := t.Clone()
+			if err != nil {
+				return "", err
+			}
+			parsed, err := clone.New(t.Name() + ":tpl").Parse(text)
+			if err != nil {
+				return "", fmt.Errorf("tpl: %w", err)
+			}
+			var buf bytes.Buffer
+			if err := parsed.Execute(&buf, data); err != nil {
+				return "", fmt.Errorf("tpl: %w", err)
+			}
+			return buf.String(), nil
+		},
+	}
+}
+
+func defaultValue(def any, given ...any) any {
+	if len(given) == 0 || empty(given[0]) {
+		return def
+	}
+	return given[0]
+}
+
+func required(msg string, value any) (any, error) {
+	if value == nil {
+		return nil, errors.New(msg)
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return nil, errors.New(msg)
+	}
+	return value, nil
+}
+
+func empty(value any) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+func coalesce(values ...any) any {
+	for _, v := range values {
+		if !empty(v) {
+			return v
+		}
+	}
+	return nil
+}
+
+func ternary(ifTrue, ifFalse any, cond bool) any {
+	if cond {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+func fail(msg string) (string, error) {
+	return "", errors.New(msg)
+}
+
+func toYAML(value any) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(value); err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return "", fmt.Errorf("toYaml: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func fromYAML(value string) (map[string]any, error) {
+	out := map[string]any{}
+	if err := yaml.Unmarshal([]byte(value), &out); err != nil {
+		return nil, fmt.Errorf("fromYaml: %w", err)
+	}
+	return out, nil
+}
+
+func toJSON(value any) (string, error) {
+	out, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("toJson: %w", err)
+	}
+	return string(out), nil
+}
+
+func toPrettyJSON(value any) (string, error) {
+	out, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("toPrettyJson: %w", err)
+	}
+	return string(out), nil
+}
+
+func fromJSON(value string) (map[string]any, error) {
+	out := map[string]any{}
+	if err := json.Unmarshal([]byte(value), &out); err != nil {
+		return nil, fmt.Errorf("fromJson: %w", err)
+	}
+	return out, nil
+}
+
+func indent(spaces int, value string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(value, "\n", "\n"+pad)
+}
+
+func nindent(spaces int, value string) string {
+	return "\n" + indent(spaces, value)
+}
+
+func quote(values ...any) string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%q", toString(v)))
+	}
+	return strings.Join(out, " ")
+}
+
+func squote(values ...any) string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		out = append(out, "'"+toString(v)+"'")
+	}
+	return strings.Join(out, " ")
+}
+
+func trimPrefix(prefix, value string) string { return strings.TrimPrefix(value, prefix) }
+
+func trimSuffix(suffix, value string) string { return strings.TrimSuffix(value, suffix) }
+
+func replace(old, new, value string) string { return strings.ReplaceAll(value, old, new) }
+
+func contains(substr, value string) bool { return strings.Contains(value, substr) }
+
+func hasPrefix(prefix, value string) bool { return strings.HasPrefix(value, prefix) }
+
+func hasSuffix(suffix, value string) bool { return strings.HasSuffix(value, suffix) }
+
+func split(sep, value string) []string { return strings.Split(value, sep) }
+
+func join(sep string, values any) string {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return toString(values)
+	}
+	parts := make([]string, v.Len())
+	for i := range parts {
+		parts[i] = toString(v.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func b64enc(value string) string {
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+func b64dec(value string) (string, error) {
+	out, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: %w", err)
+	}
+	return string(out), nil
+}
+
+func sha1sum(value string) string {
+	sum := sha1.Sum([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256sum(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+const (
+	alphaNumChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	numericChars  = "0123456789"
+)
+
+func randAlphaNum(n int) (string, error) { return randFrom(alphaNumChars, n) }
+
+func randNumeric(n int) (string, error) { return randFrom(numericChars, n) }
+
+func randFrom(charset string, n int) (string, error) {
+	if n < 0 {
+		return "", fmt.Errorf("random string length must be positive, got %d", n)
+	}
+	max := big.NewInt(int64(len(charset)))
+	out := make([]byte, n)
+	for i := range out {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = charset[idx.Int64()]
+	}
+	return string(out), nil
+}
+
+func date(layout string, t time.Time) string {
+	return t.Format(layout)
+}
+
+func list(values ...any) []any {
+	return values
+}
+
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict: expected an even number of arguments")
+	}
+	out := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		out[toString(pairs[i])] = pairs[i+1]
+	}
+	return out, nil
+}
+
+func hasKey(m map[string]any, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,108 @@
+package templating
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestBuiltinFuncs(t *testing.T) {
+	data := map[string]any{
+		"name":  "web",
+		"empty": "",
+		"zero":  0,
+		"list":  []any{},
+		"ports": []any{80, 443},
+		"env":   map[string]any{"B": "2", "A": "1"},
+		"nested": map[string]any{
+			"image": map[string]any{"repo": "nginx", "tag": "1.27"},
+		},
+	}
+	tests := []struct {
+		tpl, want string
+		err       string
+	}{
+		{tpl: `{{ .name | default "app" }}`, want: "web"},
+		{tpl: `{{ .missing | default "app" }}`, want: "app"},
+		{tpl: `{{ .empty | default "app" }}`, want: "app"},
+		{tpl: `{{ .zero | default 8080 }}`, want: "8080"},
+		{tpl: `{{ .list | default "none" }}`, want: "none"},
+		{tpl: `{{ default "app" }}`, want: "app"},
+		{tpl: `{{ .nested.image.tag | default "latest" }}`, want: "1.27"},
+		{tpl: `{{ .nested.other.tag | default "latest" }}`, want: "latest"},
+		{tpl: `{{ .name | required "name is required" }}`, want: "web"},
+		{tpl: `{{ .zero | required "zero is required" }}`, want: "0"},
+		{tpl: `{{ .missing | required "missing is required" }}`, err: "missing is required"},
+		{tpl: `{{ .empty | required "empty is required" }}`, err: "empty is required"},
+		{tpl: `{{ toYaml .env }}`, want: "A: \"1\"\nB: \"2\""},
+		{tpl: `{{ toYaml .ports }}`, want: "- 80\n- 443"},
+		{tpl: `{{ toYaml .nested }}`, want: "image:\n  repo: nginx\n  tag: \"1.27\""},
+		{tpl: `{{ toYaml .empty }}`, want: `""`},
+		{tpl: "env:{{ toYaml .env | nindent 2 }}", want: "env:\n  A: \"1\"\n  B: \"2\""},
+		{tpl: `{{ toJson .env }}`, want: `{"A":"1","B":"2"}`},
+		{tpl: `{{ (fromYaml "a: [1, 2]").a | toJson }}`, want: `[1,2]`},
+		{tpl: `{{ coalesce .empty .missing .name }}`, want: "web"},
+		{tpl: `{{ ternary "on" "off" (empty .list) }}`, want: "on"},
+		{tpl: `{{ quote .name .zero }} {{ squote .name }}`, want: `"web" "0" 'web'`},
+		{tpl: `{{ join "," .ports }}`, want: "80,443"},
+		{tpl: `{{ dict "a" 1 | keys | join "," }}`, want: "a"},
+		{tpl: `{{ dict "a" }}`, err: "dict: expected an even number of arguments"},
+		{tpl: `{{ "aGk=" | b64dec }}`, want: "hi"},
+		{tpl: `{{ "hi" | sha256sum | trunc }}`, err: `function "trunc" not defined`},
+		{tpl: `{{ randAlphaNum 12 | len }}`, want: "12"},
+		{tpl: `{{ fail "stop" }}`, err: "stop"},
+		{tpl: `{{ define "p" }}[{{ . }}]{{ end }}{{ include "p" .name | upper }}`, want: "[WEB]"},
+		{tpl: `{{ tpl "{{ .name }}-dev" . }}`, want: "web-dev"},
+		{tpl: `{{ tpl "{{ .name" . }}`, err: "tpl: "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tpl, func(t *testing.T) {
+			got, err := Renderer{}.RenderString("test", tt.tpl, data)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRendererFuncsOverrideBuiltins(t *testing.T) {
+	r := Renderer{Funcs: template.FuncMap{
+		"default": func(string, ...any) string { return "custom" },
+		"shout":   func(s string) string { return strings.ToUpper(s) + "!" },
+	}}
+	got, err := r.RenderString("test", `{{ .x | default "a" }} {{ shout "hi" }}`, map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "custom HI!" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestFuncsListing(t *testing.T) {
+	infos := Funcs()
+	if len(infos) != len(builtins)+len(boundUsage) {
+		t.Fatalf("Funcs lists %d functions, want %d", len(infos), len(builtins)+len(boundUsage))
+	}
+	if !sort.SliceIsSorted(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name }) {
+		t.Fatal("Funcs is not sorted")
+	}
+	for _, info := range infos {
+		if !strings.HasPrefix(info.Usage, info.Name) {
+			t.Errorf("usage of %s is %q", info.Name, info.Usage)
+		}
+	}
+	if _, ok := BuiltinFuncs()["include"]; ok {
+		t.Fatal("BuiltinFuncs includes the template-bound include")
+	}
+}
@@ -8,43 +8,72 @@ import (
 	"text/template"
 )
 
-// Renderer renders templates using text/template with optional functions.
+// Renderer renders templates using text/template with the built-in function
+// library. Funcs extends the library and takes precedence over built-ins.
+// Strict makes missing map keys an error instead of rendering `<no value>`.
 type Renderer struct {
-	Funcs template.FuncMap
+	Funcs  template.FuncMap
+	Strict bool
 }
 
-// RenderFile renders a template file into the writer.
+// New returns an empty template with the built-in, bound and extra functions registered.
+func (r Renderer) New(name string) *template.Template {
+	tmpl := template.New(name)
+	tmpl.Funcs(BuiltinFuncs())
+	tmpl.Funcs(boundFuncs(tmpl))
+	if len(r.Funcs) > 0 {
+		tmpl.Funcs(r.Funcs)
+	}
+	if r.Strict {
+		tmpl.Option("missingkey=error")
+	}
+	return tmpl
+}
+
+// RenderFile renders a template file into the writer. A `<name>.schema.json`
+// sidecar next to the template is used when the template has no front matter
+// schema.
 func (r Renderer) RenderFile(vfs fs.FS, name string, data any, dest io.Writer) error {
-	tmpl := template.New(name).Funcs(r.Funcs)
 	content, err := fs.ReadFile(vfs, name)
 	if err != nil {
 		return err
 	}
-	parsed, err := tmpl.Parse(string(content))
+	schema, err := LoadSidecarSchema(vfs, name)
 	if err != nil {
 		return err
 	}
-	var buf bytes.Buffer
-	if err := parsed.Execute(&buf, data); err != nil {
-		return err
+	return r.RenderDocument(name, content, data, schema, dest)
+}
+
+// RenderDocument strips front matter from content, validates data against the
+// front matter schema (or fallback when the template declares none), then
+// renders. Failures are reported as *RenderError or joined *ValidationError.
+func (r Renderer) RenderDocument(name string, content []byte, data any, fallback *Schema, dest io.Writer) error {
+	fm, body, offset, err := SplitFrontMatter(content)
+	if err != nil {
+		return &RenderError{File: name, Line: 1, Err: err}
 	}
-	if _, err := io.Copy(dest, &buf); err != nil {
+	schema := fm.Schema
+	if schema == nil {
+		schema = fallback
+	}
+	if err := schema.Validate(data); err != nil {
+		return fmt.Errorf("%s: data does not match schema:\n%w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := r.execute(name, string(body), offset, data, &buf); err != nil {
 		return err
 	}
-	return nil
+	_, err = io.Copy(dest, &buf)
+	return err
 }
 
 // RenderString renders a template string and returns the result.
 func (r Renderer) RenderString(name, tpl string, data any) (string, error) {
-	tmpl := template.New(name).Funcs(r.Funcs)
-	parsed, err := tmpl.Parse(tpl)
-	if err != nil {
-		return "", fmt.Errorf("parse template: %w", err)
-	}
-
 	var buf bytes.Buffer
-	if err := parsed.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("execute template: %w", err)
+	if err := r.execute(name, tpl, 0, data, &buf); err != nil {
+		return "", err
 	}
 	return buf.String(), nil
 }
@@ -62,3 +91,14 @@ func (r Renderer) Render(reader io.Reader, data any, dest io.Writer) error {
 	_, err = io.WriteString(dest, result)
 	return err
 }
+
+func (r Renderer) execute(name, tpl string, offset int, data any, dest io.Writer) error {
+	parsed, err := r.New(name).Parse(tpl)
+	if err != nil {
+		return fmt.Errorf("parse template: %w", locate(name, map[string]int{name: offset}, err))
+	}
+	if err := parsed.Execute(dest, data); err != nil {
+		return fmt.Errorf("execute template: %w", locate(name, map[string]int{name: offset}, err))
+	}
+	return nil
+}
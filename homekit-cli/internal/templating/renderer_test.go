@@ -0,0 +1,28 @@
+package templating
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderFileSidecarSchema(t *testing.T) {
+	vfs := fstest.MapFS{
+		"compose.yaml.tmpl":        {Data: []byte("image: {{ .Image }}\n")},
+		"compose.yaml.schema.json": {Data: []byte(`{"properties": {"Image": {"type": "string", "minLength": 1}}}`)},
+		"bad.tmpl":                 {Data: []byte("x\n")},
+		"bad.schema.json":          {Data: []byte(`{"pattern": "("}`)},
+	}
+	var out bytes.Buffer
+	err := Renderer{}.RenderFile(vfs, "compose.yaml.tmpl", map[string]any{"Image": ""}, &out)
+	if err == nil || !strings.Contains(err.Error(), ".Image: must be at least 1 characters") {
+		t.Fatalf("error = %v", err)
+	}
+	if err := (Renderer{}).RenderFile(vfs, "compose.yaml.tmpl", map[string]any{"Image": "nginx"}, &out); err != nil || out.String() != "image: nginx\n" {
+		t.Fatalf("RenderFile = %q, %v", out.String(), err)
+	}
+	if err := (Renderer{}).RenderFile(vfs, "bad.tmpl", nil, &out); err == nil || !strings.Contains(err.Error(), "bad.schema.json: schema .: invalid pattern") {
+		t.Fatalf("error = %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON Schema subset used to validate template data before
+// rendering. It is decoded from schema.json sidecars or YAML front matter.
+type Schema struct {
+	Description          string             `yaml:"description"`
+	Type                 SchemaType         `yaml:"type"`
+	Properties           map[string]*Schema `yaml:"properties"`
+	Required             []string           `yaml:"required"`
+	AdditionalProperties *bool              `yaml:"additionalProperties"`
+	Items                *Schema            `yaml:"items"`
+	Enum                 []any              `yaml:"enum"`
+	MinLength            *int               `yaml:"minLength"`
+	MaxLength            *int               `yaml:"maxLength"`
+	Pattern              string             `yaml:"pattern"`
+	Minimum              *float64           `yaml:"minimum"`
+	Maximum              *float64           `yaml:"maximum"`
+	MinItems             *int               `yaml:"minItems"`
+}
+
+// SchemaType holds one or more JSON Schema type names.
+type SchemaType []string
+
+// UnmarshalYAML accepts either a single type name or a list of names.
+func (t *SchemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = SchemaType{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// ValidationError describes a single schema violation at a data path.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// ParseSchema decodes a schema from JSON or YAML.
+func ParseSchema(content []byte) (*Schema, error) {
+	var s Schema
+	if err := yaml.Unmarshal(content, &s); err != nil {
+		return nil, fmt.Errorf("parse schema: %w", err)
+	}
+	if err := s.compile("."); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+const sidecarSchemaExt = ".schema.json"
+
+// SidecarSchemaName returns the schema file paired with a template:
+// compose.yaml.tmpl pairs with compose.yaml.schema.json.
+func SidecarSchemaName(name string) string {
+	return strings.TrimSuffix(name, templateExt) + sidecarSchemaExt
+}
+
+// LoadSidecarSchema reads the sidecar schema for name from vfs, returning nil
+// when there is none.
+func LoadSidecarSchema(vfs fs.FS, name string) (*Schema, error) {
+	sidecar := SidecarSchemaName(name)
+	content, err := fs.ReadFile(vfs, sidecar)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseSchema(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sidecar, err)
+	}
+	return schema, nil
+}
+
+func (s *Schema) compile(path string) error {
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("schema %s: invalid pattern: %w", path, err)
+		}
+	}
+	for name, prop := range s.Properties {
+		if prop == nil {
+			continue
+		}
+		if err := prop.compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// Validate checks data against the schema and joins every violation found.
+// Paths use template notation, e.g. `.Services[0].Image`.
+func (s *Schema) Validate(data any) error {
+	if s == nil {
+		return nil
+	}
+	var errs []error
+	s.validate(".", normalize(data), &errs)
+	return errors.Join(errs...)
+}
+
+func (s *Schema) validate(path string, value any, errs *[]error) {
+	report := func(format string, args ...any) {
+		*errs = append(*errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 && !s.matchesType(value) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), typeName(value))
+		return
+	}
+	if len(s.Enum) > 0 && !containsValue(s.Enum, value) {
+		report("must be one of %v", s.Enum)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.Required {
+			if child, ok := v[name]; !ok || child == nil {
+				*errs = append(*errs, &ValidationError{Path: joinPath(path, name), Message: "is required"})
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			prop, ok := s.Properties[k]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					*errs = append(*errs, &ValidationError{Path: joinPath(path, k), Message: "is not allowed"})
+				}
+				continue
+			}
+			if prop != nil {
+				prop.validate(joinPath(path, k), v[k], errs)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			report("must have at least %d items", *s.MinItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			report("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && len(v) > *s.MaxLength {
+			report("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Pattern != "" && !regexp.MustCompile(s.Pattern).MatchString(v) {
+			report("must match %q", s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			report("must be >= %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			report("must be <= %v", *s.Maximum)
+		}
+	}
+}
+
+func (s *Schema) matchesType(value any) bool {
+	actual := typeName(value)
+	for _, want := range s.Type {
+		if want == actual {
+			return true
+		}
+		if want == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+func typeName(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func containsValue(options []any, value any) bool {
+	for _, opt := range options {
+		if reflect.DeepEqual(normalize(opt), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize converts decoded YAML/JSON or plain Go values into the generic
+// shapes the validator understands: map[string]any, []any, float64, string,
+// bool and nil. Struct fields are keyed by their Go field name, matching how
+// templates address them.
+func normalize(value any) any {
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = normalize(iter.Value().Interface())
+		}
+		return out
+	case reflect.Struct:
+		out := map[string]any{}
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			if field.IsExported() {
+				out[field.Name] = normalize(rv.Field(i).Interface())
+			}
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes())
+		}
+		out := make([]any, rv.Len())
+		for i := range out {
+			out[i] = normalize(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	default:
+		return rv.Interface()
+	}
+}
+
+func joinPath(base, name string) string {
+	if base == "." {
+		return "." + name
+	}
+	return base + "." + name
+}
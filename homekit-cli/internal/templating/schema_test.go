@@ -0,0 +1,128 @@
+package templating
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+  "type": "object",
+  "required": ["Name", "Image"],
+  "additionalProperties": false,
+  "properties": {
+    "Name": {"type": "string", "pattern": "^[a-z][a-z0-9-]*$", "maxLength": 12},
+    "Profile": {"enum": ["dev", "prod"]},
+    "Replicas": {"type": "integer", "minimum": 1, "maximum": 5},
+    "Ratio": {"type": "number"},
+    "Image": {
+      "type": "object",
+      "required": ["Repo"],
+      "properties": {
+        "Repo": {"type": "string", "minLength": 1},
+        "Tag": {"type": ["string", "null"]}
+      }
+    },
+    "Services": {
+      "type": "array",
+      "minItems": 1,
+      "items": {"type": "object", "required": ["Image"], "properties": {"Image": {"type": "string"}}}
+    }
+  }
+}`
+
+func TestSchemaValidate(t *testing.T) {
+	schema, err := ParseSchema([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := func() map[string]any {
+		return map[string]any{
+			"Name":     "web",
+			"Profile":  "dev",
+			"Replicas": 2,
+			"Ratio":    1,
+			"Image":    map[string]any{"Repo": "nginx", "Tag": nil},
+			"Services": []any{map[string]any{"Image": "db"}},
+		}
+	}
+	tests := []struct {
+		name   string
+		mutate func(map[string]any)
+		want   []string
+	}{
+		{name: "valid", mutate: func(map[string]any) {}},
+		{name: "wrong type", mutate: func(d map[string]any) { d["Name"] = 5 }, want: []string{".Name: expected string, got integer"}},
+		{name: "integer", mutate: func(d map[string]any) { d["Replicas"] = 1.5 }, want: []string{".Replicas: expected integer, got number"}},
+		{name: "enum", mutate: func(d map[string]any) { d["Profile"] = "staging" }, want: []string{".Profile: must be one of [dev prod]"}},
+		{name: "required", mutate: func(d map[string]any) { delete(d, "Image"); d["Name"] = nil }, want: []string{
+			".Name: is required", ".Image: is required", ".Name: expected string, got null",
+		}},
+		{name: "nested required", mutate: func(d map[string]any) { d["Image"] = map[string]any{"Tag": "1"} }, want: []string{".Image.Repo: is required"}},
+		{name: "nested type", mutate: func(d map[string]any) { d["Image"].(map[string]any)["Tag"] = 1.27 }, want: []string{".Image.Tag: expected string or null, got number"}},
+		{name: "array item", mutate: func(d map[string]any) { d["Services"] = []any{map[string]any{"Image": "a"}, map[string]any{}} }, want: []string{".Services[1].Image: is required"}},
+		{name: "min items", mutate: func(d map[string]any) { d["Services"] = []any{} }, want: []string{".Services: must have at least 1 items"}},
+		{name: "string rules", mutate: func(d map[string]any) { d["Name"] = "Web-Server-Name" }, want: []string{
+			".Name: must be at most 12 characters", `.Name: must match "^[a-z][a-z0-9-]*$"`,
+		}},
+		{name: "range", mutate: func(d map[string]any) { d["Replicas"] = 9 }, want: []string{".Replicas: must be <= 5"}},
+		{name: "additional property", mutate: func(d map[string]any) { d["Extra"] = true }, want: []string{".Extra: is not allowed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := valid()
+			tt.mutate(data)
+			err := schema.Validate(data)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("no error, want %q", tt.want)
+			}
+			if got := strings.Split(err.Error(), "\n"); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("errors %q, want %q", got, tt.want)
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("%T is not a ValidationError", err)
+			}
+		})
+	}
+}
+
+func TestSchemaValidateStructs(t *testing.T) {
+	schema, err := ParseSchema([]byte("type: object\nrequired: [Type]\nproperties:\n  Type: {type: string, minLength: 1}\n  Ports: {type: array, items: {type: integer}}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type options struct {
+		Type  string
+		Ports []uint16
+		Bytes []byte
+	}
+	if err := schema.Validate(&options{Type: "go", Ports: []uint16{80}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(options{}); err == nil || err.Error() != ".Type: must be at least 1 characters" {
+		t.Fatalf("error = %v", err)
+	}
+	var nilSchema *Schema
+	if err := nilSchema.Validate(options{}); err != nil {
+		t.Fatalf("nil schema: %v", err)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	for content, want := range map[string]string{
+		`{"properties": {"a": {"properties": {"b": {"pattern": "("}}}}}`: "schema .a.b: invalid pattern",
+		`{"items": {"pattern": "["}}`:                                    "schema .[]: invalid pattern",
+		`{"type": {"a": 1}}`:                                             "parse schema",
+	} {
+		if _, err := ParseSchema([]byte(content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseSchema(%s) = %v, want %q", content, err, want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ui
+
+import "fmt"
+
+// HumanBytes formats a byte count with binary units, e.g. "1.5 GiB".
+func HumanBytes(n int64) string {
+	const unit = 1024
+	if n < unit && n > -unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n)
+	suffixes := []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB"}
+	i := -1
+	for (value >= unit || value <= -unit) && i < len(suffixes)-1 {
+		value /= unit
+		i++
+	}
+	return fmt.Sprintf("%.1f %s", value, suffixes[i])
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a row of block characters scaled to ceiling.
+// A ceiling of zero or less scales to the largest value instead.
+func Sparkline(values []float64, ceiling float64) string {
+	if ceiling <= 0 {
+		for _, v := range values {
+			ceiling = max(ceiling, v)
+		}
+	}
+	out := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if ceiling > 0 && v > 0 {
+			level = int(v / ceiling * float64(len(sparkBlocks)-1))
+			level = max(0, min(len(sparkBlocks)-1, level))
+		}
+		out[i] = sparkBlocks[level]
+	}
+	return string(out)
+}
@@ -32,7 +32,9 @@ func (p Prompter) Confirm(question string, defaultYes bool) (bool, error) {
 	}
 	input = strings.TrimSpace(strings.ToLower(input))
 	switch input {
-	case "", "y", "yes":
+	case "":
+		return defaultYes, nil
+	case "y", "yes":
 		return true, nil
 	case "n", "no":
 		return false, nil
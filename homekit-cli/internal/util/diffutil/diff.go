@@ -0,0 +1,270 @@
+package diffutil
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+type opKind byte
+
+const (
+	opEqual  opKind = ' '
+	opDelete opKind = '-'
+	opInsert opKind = '+'
+)
+
+type op struct {
+	kind opKind
+	text string
+	// aLine and bLine are 0-based positions in the old and new inputs.
+	aLine, bLine int
+}
+
+// Unified returns a unified diff between oldText and newText, or an empty
+// string when they are equal.
+func Unified(oldName, newName, oldText, newText string, context int) string {
+	if oldText == newText {
+		return ""
+	}
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for _, h := range hunks(ops, context) {
+		writeHunk(&sb, ops[h[0]:h[1]])
+	}
+	return sb.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines computes a shortest edit script with the linear-space variant of
+// Myers' algorithm: it finds the middle snake of the edit graph and recurses
+// on both halves, so memory stays proportional to the input.
+func diffLines(a, b []string) []op {
+	d := &differ{a: a, b: b}
+	// compare line ids instead of strings
+	ids := map[string]int{}
+	d.aIDs = internLines(a, ids)
+	shared := len(ids)
+	d.bIDs = internLines(b, ids)
+	if !slices.ContainsFunc(d.bIDs, func(id int) bool { return id < shared }) {
+		// nothing in common, as when a file is rewritten: skip the search
+		d.replace(0, len(a), 0, len(b))
+		return d.ops
+	}
+	size := len(a) + len(b) + 2
+	d.vf = make([]int, 2*size)
+	d.vb = make([]int, 2*size)
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+func internLines(lines []string, ids map[string]int) []int {
+	out := make([]int, len(lines))
+	for i, l := range lines {
+		id, ok := ids[l]
+		if !ok {
+			id = len(ids)
+			ids[l] = id
+		}
+		out[i] = id
+	}
+	return out
+}
+
+type differ struct {
+	a, b       []string
+	aIDs, bIDs []int
+	// vf and vb hold the furthest reaching forward and backward paths per
+	// diagonal; they are reused across the recursion
+	vf, vb []int
+	ops    []op
+}
+
+// compare appends the edit script turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.aIDs[aLo] == d.bIDs[bLo] {
+		d.ops = append(d.ops, op{kind: opEqual, text: d.a[aLo], aLine: aLo, bLine: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.aIDs[aHi-suffix-1] == d.bIDs[bHi-suffix-1] {
+		suffix++
+	}
+	aEnd, bEnd := aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aEnd, bLo == bEnd:
+		d.replace(aLo, aEnd, bLo, bEnd)
+	default:
+		// both sides are non-empty and differ at both ends, so the edit
+		// distance is at least 2 and each half is strictly smaller
+		x, y, u, v, ok := d.middleSnake(aLo, aEnd, bLo, bEnd)
+		if !ok {
+			// cannot happen for a correct search; replacing the whole range
+			// is still a valid, if longer, script
+			d.replace(aLo, aEnd, bLo, bEnd)
+			break
+		}
+		d.compare(aLo, x, bLo, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.ops = append(d.ops, op{kind: opEqual, text: d.a[x], aLine: x, bLine: y})
+		}
+		d.compare(u, aEnd, v, bEnd)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, op{kind: opEqual, text: d.a[aEnd+i], aLine: aEnd + i, bLine: bEnd + i})
+	}
+}
+
+// replace appends deletions of a[aLo:aHi] followed by insertions of
+// b[bLo:bHi].
+func (d *differ) replace(aLo, aHi, bLo, bHi int) {
+	for x := aLo; x < aHi; x++ {
+		d.ops = append(d.ops, op{kind: opDelete, text: d.a[x], aLine: x, bLine: bLo})
+	}
+	for y := bLo; y < bHi; y++ {
+		d.ops = append(d.ops, op{kind: opInsert, text: d.b[y], aLine: aHi, bLine: y})
+	}
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the middle snake of
+// an optimal path through a[aLo:aHi] and b[bLo:bHi], searching forward from
+// the start and backward from the end until the paths overlap. It reports
+// false if they never do.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int, ok bool) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta&1 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	vf, vb := d.vf[:2*offset+1], d.vb[:2*offset+1]
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	for step := 0; step <= limit; step++ {
+		for k := -step; k <= step; k += 2 {
+			var px int
+			if k == -step || (k != step && vf[offset+k-1] < vf[offset+k+1]) {
+				px = vf[offset+k+1]
+			} else {
+				px = vf[offset+k-1] + 1
+			}
+			py := px - k
+			sx, sy := px, py
+			for px < n && py < m && d.aIDs[aLo+px] == d.bIDs[bLo+py] {
+				px++
+				py++
+			}
+			vf[offset+k] = px
+			if kb := delta - k; odd && kb >= -(step-1) && kb <= step-1 && px+vb[offset+kb] >= n {
+				return aLo + sx, bLo + sy, aLo + px, bLo + py, true
+			}
+		}
+		for kb := -step; kb <= step; kb += 2 {
+			var px int
+			if kb == -step || (kb != step && vb[offset+kb-1] < vb[offset+kb+1]) {
+				px = vb[offset+kb+1]
+			} else {
+				px = vb[offset+kb-1] + 1
+			}
+			py := px - kb
+			sx, sy := px, py
+			for px < n && py < m && d.aIDs[aHi-px-1] == d.bIDs[bHi-py-1] {
+				px++
+				py++
+			}
+			vb[offset+kb] = px
+			if k := delta - kb; !odd && k >= -step && k <= step && px+vf[offset+k] >= n {
+				return aHi - px, bHi - py, aHi - sx, bHi - sy, true
+			}
+		}
+	}
+	return 0, 0, 0, 0, false
+}
+
+// hunks groups changes with surrounding context, returning [start, end) ranges
+// into ops.
+func hunks(ops []op, context int) [][2]int {
+	var out [][2]int
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == opEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+		if n := len(out); n > 0 && start <= out[n-1][1] {
+			out[n-1][1] = end
+		} else {
+			out = append(out, [2]int{start, end})
+		}
+		i = end - 1
+	}
+	return out
+}
+
+func writeHunk(sb *strings.Builder, ops []op) {
+	aStart, bStart := ops[0].aLine, ops[0].bLine
+	var aCount, bCount int
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			aCount++
+			bCount++
+		case opDelete:
+			aCount++
+		case opInsert:
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range ops {
+		sb.WriteByte(byte(o.kind))
+		sb.WriteString(o.text)
+		if !strings.HasSuffix(o.text, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
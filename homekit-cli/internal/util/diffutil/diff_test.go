@@ -0,0 +1,144 @@
+package diffutil
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	old := "a\nb\nc\nd\ne\nf\ng\nh\n"
+	new := "a\nb\nc\nD\ne\nf\ng\nh\ni\n"
+	want := `--- old
++++ new
+@@ -1,8 +1,9 @@
+ a
+ b
+ c
+-d
++D
+ e
+ f
+ g
+ h
++i
+`
+	if got := Unified("old", "new", old, new, DefaultContext); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := Unified("old", "new", old, old, DefaultContext); got != "" {
+		t.Fatalf("equal inputs gave %q", got)
+	}
+}
+
+// TestDiffLinesMinimal checks random inputs against an LCS computed by
+// dynamic programming: the script must rebuild both sides and be shortest.
+func TestDiffLinesMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a'+rng.Intn(3))) + "\n"
+		}
+		return lines
+	}
+	for i := 0; i < 2000; i++ {
+		checkScript(t, randomLines(), randomLines())
+	}
+}
+
+// FuzzDiffLines runs the same check on arbitrary inputs, one line per
+// newline-terminated chunk.
+func FuzzDiffLines(f *testing.F) {
+	f.Add("a\nb\nc\n", "a\nc\nd\n")
+	f.Add("a\nb\na\nb\n", "b\na\nb\na")
+	f.Add("", "x\n")
+	f.Fuzz(func(t *testing.T, oldText, newText string) {
+		a, b := splitLines(oldText), splitLines(newText)
+		if len(a) > 200 || len(b) > 200 {
+			return
+		}
+		checkScript(t, a, b)
+	})
+}
+
+// checkScript fails unless diffLines(a, b) rebuilds both inputs with the
+// fewest edits.
+func checkScript(t *testing.T, a, b []string) {
+	t.Helper()
+	ops := diffLines(a, b)
+	var gotA, gotB []string
+	edits := 0
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			gotA, gotB = append(gotA, o.text), append(gotB, o.text)
+		case opDelete:
+			gotA = append(gotA, o.text)
+			edits++
+		case opInsert:
+			gotB = append(gotB, o.text)
+			edits++
+		}
+	}
+	if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+		t.Fatalf("script for %q -> %q does not rebuild the inputs: %v", a, b, ops)
+	}
+	if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+		t.Fatalf("%q -> %q: %d edits, want %d", a, b, edits, want)
+	}
+}
+
+func lcs(a, b []string) int {
+	dp := make([][]int, len(a)+1)
+	for i := range dp {
+		dp[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				dp[i][j] = dp[i+1][j+1] + 1
+			} else {
+				dp[i][j] = max(dp[i+1][j], dp[i][j+1])
+			}
+		}
+	}
+	return dp[0][0]
+}
+
+// TestUnifiedRewrite diffs two large files without a common line, the worst
+// case for memory in the quadratic formulation.
+func TestUnifiedRewrite(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&a, "old %d\n", i)
+		fmt.Fprintf(&b, "new %d\n", i)
+	}
+	out := Unified("a", "b", a.String(), b.String(), DefaultContext)
+	if got := strings.Count(out, "\n-old "); got != 20000 {
+		t.Fatalf("got %d deleted lines", got)
+	}
+}
+
+// TestUnifiedLargeEdit diffs large inputs that share only every tenth line,
+// which goes through the middle-snake search at depth.
+func TestUnifiedLargeEdit(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < 5000; i++ {
+		if i%10 == 0 {
+			fmt.Fprintf(&a, "same %d\n", i)
+			fmt.Fprintf(&b, "same %d\n", i)
+			continue
+		}
+		fmt.Fprintf(&a, "old %d\n", i)
+		fmt.Fprintf(&b, "new %d\n", i)
+	}
+	out := Unified("a", "b", a.String(), b.String(), DefaultContext)
+	if got := strings.Count(out, "\n-old "); got != 4500 {
+		t.Fatalf("got %d deleted lines", got)
+	}
+	if got := strings.Count(out, "\n same "); got != 500 {
+		t.Fatalf("got %d context lines", got)
+	}
+}
@@ -0,0 +1,117 @@
+package fileutil
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Status reports what WriteIfChanged did to a file.
+type Status string
+
+const (
+	StatusCreated   Status = "created"
+	StatusChanged   Status = "changed"
+	StatusUnchanged Status = "unchanged"
+)
+
+// WriteAtomic writes data to a temporary file in the target directory and
+// renames it into place, so readers never observe a partially written file.
+func WriteAtomic(path string, data []byte, perm fs.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	cleanup := func() { _ = os.Remove(tmpName) }
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		cleanup()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		cleanup()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		cleanup()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		cleanup()
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		cleanup()
+		return err
+	}
+	return nil
+}
+
+// Compare reports how writing data to path would change it without touching
+// the file.
+func Compare(path string, data []byte) (Status, []byte, error) {
+	current, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return StatusCreated, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if bytes.Equal(current, data) {
+		return StatusUnchanged, current, nil
+	}
+	return StatusChanged, current, nil
+}
+
+// WriteIfChanged atomically writes data unless path already holds identical
+// content. When dryRun is set, only the status is computed.
+func WriteIfChanged(path string, data []byte, perm fs.FileMode, dryRun bool) (Status, error) {
+	status, _, err := Compare(path, data)
+	if err != nil {
+		return "", err
+	}
+	if status == StatusUnchanged || dryRun {
+		return status, nil
+	}
+	return status, WriteAtomic(path, data, perm)
+}
+
+// BackupSuffix is appended to a file name by Backup.
+const BackupSuffix = ".bak"
+
+// Backup copies path to path+BackupSuffix, preserving its mode, and returns the
+// backup location.
+func Backup(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	target := path + BackupSuffix
+	if err := WriteAtomic(target, data, info.Mode().Perm()); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// ModeOr returns the permission bits of an existing file, or fallback when the
+// file does not exist.
+func ModeOr(path string, fallback fs.FileMode) fs.FileMode {
+	if info, err := os.Stat(path); err == nil {
+		return info.Mode().Perm()
+	}
+	return fallback
+}
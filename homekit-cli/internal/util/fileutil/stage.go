@@ -0,0 +1,188 @@
+package fileutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Stage collects files in a private staging directory and moves them into a
+// destination tree in one step. Everything Commit changed can be undone with
+// Rollback until Close is called.
+type Stage struct {
+	dir   string
+	files map[string]fs.FileMode
+	undo  []func() error
+}
+
+// NewStage creates a staging directory below tempRoot (os.TempDir when empty).
+func NewStage(tempRoot string) (*Stage, error) {
+	if tempRoot == "" {
+		tempRoot = os.TempDir()
+	}
+	if err := os.MkdirAll(tempRoot, 0o755); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(tempRoot, "stage-*")
+	if err != nil {
+		return nil, err
+	}
+	return &Stage{dir: dir, files: map[string]fs.FileMode{}}, nil
+}
+
+// Add writes a file into the staging area. rel is a slash-separated path
+// relative to the destination root.
+func (s *Stage) Add(rel string, data []byte, perm fs.FileMode) error {
+	return s.AddFrom(rel, bytes.NewReader(data), perm)
+}
+
+// AddFrom streams a file into the staging area.
+func (s *Stage) AddFrom(rel string, r io.Reader, perm fs.FileMode) error {
+	if !fs.ValidPath(rel) || rel == "." {
+		return fmt.Errorf("stage: invalid path %q", rel)
+	}
+	target := filepath.Join(s.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.files[rel] = perm
+	return nil
+}
+
+// Dir returns the staging directory.
+func (s *Stage) Dir() string {
+	return s.dir
+}
+
+// Files returns the staged paths in sorted order.
+func (s *Stage) Files() []string {
+	out := make([]string, 0, len(s.files))
+	for rel := range s.files {
+		out = append(out, rel)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Drop removes a file from the staging area so Commit leaves it alone.
+func (s *Stage) Drop(rel string) {
+	delete(s.files, rel)
+	_ = os.Remove(filepath.Join(s.dir, filepath.FromSlash(rel)))
+}
+
+// MkdirAll creates dir and records every directory it had to create so
+// Rollback can remove them again.
+func (s *Stage) MkdirAll(dir string, perm fs.FileMode) error {
+	var created []string
+	for p := filepath.Clean(dir); ; p = filepath.Dir(p) {
+		if _, err := os.Stat(p); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		created = append(created, p)
+		if parent := filepath.Dir(p); parent == p {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	// Outermost first, so undo (run in reverse) removes the deepest first.
+	for i := len(created) - 1; i >= 0; i-- {
+		p := created[i]
+		s.undo = append(s.undo, func() error { return os.RemoveAll(p) })
+	}
+	return nil
+}
+
+// OnRollback registers an extra undo step, run by Rollback in reverse order
+// with the file and directory changes.
+func (s *Stage) OnRollback(undo func() error) {
+	s.undo = append(s.undo, undo)
+}
+
+// Commit moves every staged file below destRoot, replacing existing files.
+// Replaced files are kept in memory and restored by Rollback. When a move
+// fails, the files already committed are rolled back before returning.
+func (s *Stage) Commit(destRoot string) error {
+	for _, rel := range s.Files() {
+		if err := s.commitFile(destRoot, rel); err != nil {
+			if rbErr := s.Rollback(); rbErr != nil {
+				return errors.Join(err, fmt.Errorf("rollback: %w", rbErr))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Stage) commitFile(destRoot, rel string) error {
+	src := filepath.Join(s.dir, filepath.FromSlash(rel))
+	dest := filepath.Join(destRoot, filepath.FromSlash(rel))
+	if err := s.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+
+	restore := func() error { return os.Remove(dest) }
+	if info, err := os.Lstat(dest); err == nil {
+		if info.IsDir() {
+			return fmt.Errorf("%s is a directory", dest)
+		}
+		original, err := os.ReadFile(dest)
+		if err != nil {
+			return err
+		}
+		perm := info.Mode().Perm()
+		restore = func() error { return WriteAtomic(dest, original, perm) }
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Rename(src, dest); err != nil {
+		// The staging area may live on another filesystem.
+		data, readErr := os.ReadFile(src)
+		if readErr != nil {
+			return err
+		}
+		if err := WriteAtomic(dest, data, s.files[rel]); err != nil {
+			return err
+		}
+	}
+	s.undo = append(s.undo, restore)
+	return nil
+}
+
+// Rollback undoes Commit and MkdirAll in reverse order.
+func (s *Stage) Rollback() error {
+	var errs []error
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		if err := s.undo[i](); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	s.undo = nil
+	return errors.Join(errs...)
+}
+
+// Close removes the staging directory. Changes can no longer be rolled back.
+func (s *Stage) Close() error {
+	s.undo = nil
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,84 @@
+package ignoreutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+type pattern struct {
+	pattern string
+	dirOnly bool
+	rooted  bool
+}
+
+// List is a parsed ignore file.
+type List []pattern
+
+// Parse reads ignore patterns, one per line: blank lines and # comments are
+// skipped, a trailing / matches directories only, and patterns containing /
+// match the full slash-separated relative path while others match the base
+// name.
+func Parse(r io.Reader) (List, error) {
+	var list List
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := pattern{}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.rooted = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if _, err := path.Match(line, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", line, err)
+		}
+		p.pattern = line
+		list = append(list, p)
+	}
+	return list, scanner.Err()
+}
+
+// Load parses the ignore file name from fsys. A missing file yields an empty list.
+func Load(fsys fs.FS, name string) (List, error) {
+	f, err := fsys.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	list, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return list, nil
+}
+
+// Match reports whether the slash-separated relative path is ignored.
+func (l List) Match(name string, isDir bool) bool {
+	for _, p := range l {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		subject := path.Base(name)
+		if p.rooted {
+			subject = name
+		}
+		if ok, _ := path.Match(p.pattern, subject); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1 +1,163 @@
 package maputil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DeepMerge merges src into dst. Nested maps are merged recursively; any other
+// value in src (including lists) replaces the value in dst. dst is modified and
+// returned, and is allocated when nil.
+func DeepMerge(dst, src map[string]any) map[string]any {
+	if dst == nil {
+		dst = make(map[string]any, len(src))
+	}
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]any)
+		dstMap, dstIsMap := dst[k].(map[string]any)
+		if srcIsMap && dstIsMap {
+			dst[k] = DeepMerge(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+	return dst
+}
+
+// SetPath assigns value at a dotted path such as `a.b[0].c`, creating
+// intermediate maps and lists as required. A literal dot in a key is written
+// as `\.`. List indexes may replace an element or append one, but not skip
+// past the end, so a single path cannot allocate an arbitrarily long list.
+func SetPath(m map[string]any, path string, value any) error {
+	segments, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	_, err = setSegments(m, segments, value, path)
+	return err
+}
+
+type segment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+func parsePath(path string) ([]segment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty key path")
+	}
+	var (
+		segments []segment
+		current  strings.Builder
+		haveKey  bool
+	)
+	flush := func() error {
+		if !haveKey && current.Len() == 0 {
+			return fmt.Errorf("key path %q: empty key", path)
+		}
+		segments = append(segments, segment{key: current.String()})
+		current.Reset()
+		haveKey = false
+		return nil
+	}
+
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case c == '\\' && i+1 < len(path):
+			i++
+			current.WriteByte(path[i])
+			haveKey = true
+		case c == '.':
+			if len(segments) > 0 && segments[len(segments)-1].isIdx && current.Len() == 0 && !haveKey {
+				continue
+			}
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case c == '[':
+			if current.Len() > 0 || haveKey {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			} else if len(segments) == 0 {
+				return nil, fmt.Errorf("key path %q: index without key", path)
+			}
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("key path %q: unterminated index", path)
+			}
+			idx, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("key path %q: invalid index %q", path, path[i+1:i+end])
+			}
+			segments = append(segments, segment{index: idx, isIdx: true})
+			i += end
+		default:
+			current.WriteByte(c)
+			haveKey = true
+		}
+	}
+	if current.Len() > 0 || haveKey {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	} else if len(segments) == 0 || !segments[len(segments)-1].isIdx {
+		return nil, fmt.Errorf("key path %q: trailing separator", path)
+	}
+	return segments, nil
+}
+
+// setSegments assigns value into container (a map or list) and returns the
+// possibly reallocated container.
+func setSegments(container any, segments []segment, value any, path string) (any, error) {
+	seg := segments[0]
+	rest := segments[1:]
+
+	if seg.isIdx {
+		list, ok := container.([]any)
+		if !ok && container != nil {
+			return nil, fmt.Errorf("key path %q: cannot index %T", path, container)
+		}
+		if seg.index > len(list) {
+			return nil, fmt.Errorf("key path %q: index %d is past the end of a list of length %d (set indexes in order)", path, seg.index, len(list))
+		}
+		if seg.index == len(list) {
+			list = append(list, nil)
+		}
+		if len(rest) == 0 {
+			list[seg.index] = value
+			return list, nil
+		}
+		child, err := setSegments(list[seg.index], rest, value, path)
+		if err != nil {
+			return nil, err
+		}
+		list[seg.index] = child
+		return list, nil
+	}
+
+	m, ok := container.(map[string]any)
+	if !ok {
+		if container != nil {
+			return nil, fmt.Errorf("key path %q: cannot set key %q on %T", path, seg.key, container)
+		}
+		m = map[string]any{}
+	}
+	if len(rest) == 0 {
+		m[seg.key] = value
+		return m, nil
+	}
+	next := m[seg.key]
+	if _, isMap := next.(map[string]any); !isMap && !rest[0].isIdx {
+		next = nil
+	}
+	child, err := setSegments(next, rest, value, path)
+	if err != nil {
+		return nil, err
+	}
+	m[seg.key] = child
+	return m, nil
+}
@@ -0,0 +1,42 @@
+package maputil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetPathLists(t *testing.T) {
+	m := map[string]any{}
+	for _, set := range []struct {
+		path  string
+		value any
+	}{
+		{"a[0]", "x"},
+		{"a[1]", "y"},
+		{"a[0]", "z"},
+		{"b[0].c", 1},
+	} {
+		if err := SetPath(m, set.path, set.value); err != nil {
+			t.Fatalf("SetPath(%s): %v", set.path, err)
+		}
+	}
+	want := map[string]any{
+		"a": []any{"z", "y"},
+		"b": []any{map[string]any{"c": 1}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("got %#v, want %#v", m, want)
+	}
+}
+
+func TestSetPathRejectsIndexPastEnd(t *testing.T) {
+	m := map[string]any{"a": []any{"x"}}
+	for _, path := range []string{"a[2]", "b[1]", "list[100000000]"} {
+		if err := SetPath(m, path, "v"); err == nil {
+			t.Errorf("SetPath(%s) succeeded, want an error", path)
+		}
+	}
+	if got := len(m["a"].([]any)); got != 1 {
+		t.Fatalf("list grew to %d elements", got)
+	}
+}
@@ -3,6 +3,7 @@ package pathformat
 import (
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func RenderFullPath(path string) string {
@@ -42,3 +43,15 @@ func MakeDirIfNotExists(path string) error {
 func Base(path string) string {
 	return filepath.Base(path)
 }
+
+// ExpandHome replaces a leading ~ with the user's home directory.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~"))
+}
@@ -2,17 +2,14 @@ package templateutil
 
 import (
 	"bytes"
-	"fmt"
-	"text/template"
 
+	"github.com/homekit/homekit-cli/internal/templating"
 	"github.com/homekit/homekit-cli/internal/util/bufutil"
 )
 
-func RenderTemplateInBytes(content []byte, data any, name string, bufPool *bufutil.Pool) ([]byte, error) {
-	tplt := template.New(name)
-	if _, err := tplt.Parse(string(content)); err != nil {
-		return nil, fmt.Errorf("parse template: %w", err)
-	}
+// RenderTemplateInBytes renders content (including any front matter schema)
+// with the renderer's function library, using a pooled buffer when bufPool is set.
+func RenderTemplateInBytes(renderer templating.Renderer, content []byte, data any, name string, bufPool *bufutil.Pool) ([]byte, error) {
 	var writer *bytes.Buffer
 	if bufPool != nil {
 		writer = bufPool.Get()
@@ -20,8 +17,8 @@ func RenderTemplateInBytes(content []byte, data any, name string, bufPool *bufut
 	} else {
 		writer = bytes.NewBuffer(nil)
 	}
-	if err := tplt.Execute(writer, data); err != nil {
+	if err := renderer.RenderDocument(name, content, data, nil, writer); err != nil {
 		return nil, err
 	}
-	return writer.Bytes(), nil
+	return bytes.Clone(writer.Bytes()), nil
 }
@@ -0,0 +1,80 @@
+package workspace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DevcontainerFile is where the dev container definition is written, relative
+// to the workspace directory.
+const DevcontainerFile = ".devcontainer/devcontainer.json"
+
+// CodeMountTarget is where code/ is mounted inside the dev container.
+const CodeMountTarget = "/root/code"
+
+// Devcontainer is the subset of the devcontainer.json format that homekit
+// generates. See https://containers.dev/implementors/json_reference/.
+type Devcontainer struct {
+	Name              string            `json:"name"`
+	Image             string            `json:"image"`
+	WorkspaceMount    string            `json:"workspaceMount"`
+	WorkspaceFolder   string            `json:"workspaceFolder"`
+	Mounts            []string          `json:"mounts,omitempty"`
+	ContainerEnv      map[string]string `json:"containerEnv,omitempty"`
+	AppPort           []string          `json:"appPort,omitempty"`
+	RunArgs           []string          `json:"runArgs,omitempty"`
+	PostCreateCommand string            `json:"postCreateCommand,omitempty"`
+}
+
+// NewDevcontainer returns a definition that mounts code/ like compose.dev.yml.
+func NewDevcontainer(name, image string) Devcontainer {
+	return Devcontainer{
+		Name:            name,
+		Image:           image,
+		WorkspaceMount:  "source=${localWorkspaceFolder}/code,target=" + CodeMountTarget + ",type=bind",
+		WorkspaceFolder: CodeMountTarget,
+	}
+}
+
+// AddVolume converts a compose short volume (source:target[:mode]) into a
+// devcontainer mount. Relative sources resolve against the workspace folder.
+func (d *Devcontainer) AddVolume(volume string) error {
+	parts := strings.Split(volume, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("volume %q: expected source:target[:mode]", volume)
+	}
+	source, target := parts[0], parts[1]
+	kind := "bind"
+	switch {
+	case strings.HasPrefix(source, "./"), source == ".":
+		source = "${localWorkspaceFolder}" + strings.TrimPrefix(source, ".")
+	case strings.HasPrefix(source, "../"):
+		source = "${localWorkspaceFolder}/" + source
+	case strings.HasPrefix(source, "~/"):
+		source = "${localEnv:HOME}" + strings.TrimPrefix(source, "~")
+	case !strings.HasPrefix(source, "/") && !strings.HasPrefix(source, "$"):
+		kind = "volume"
+	}
+	mount := fmt.Sprintf("source=%s,target=%s,type=%s", source, target, kind)
+	if len(parts) == 3 && slices.Contains(strings.Split(parts[2], ","), "ro") {
+		mount += ",readonly"
+	}
+	d.Mounts = append(d.Mounts, mount)
+	return nil
+}
+
+// Marshal returns the indented JSON document. Shell operators in
+// postCreateCommand are kept readable rather than HTML-escaped.
+func (d Devcontainer) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(d); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
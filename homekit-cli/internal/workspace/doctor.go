@@ -0,0 +1,177 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CheckStatus is the outcome of one doctor check.
+type CheckStatus string
+
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+// Check is one doctor finding. Fixable marks a missing skeleton file that can
+// be regenerated.
+type Check struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Detail  string      `json:"detail,omitempty"`
+	Fixable bool        `json:"fixable,omitempty"`
+}
+
+// Doctor validates a workspace directory against its type.
+type Doctor struct {
+	Types       *Types
+	ComposeFile string
+	// Docker reports whether the docker daemon is usable. It is a field so
+	// callers can stub the daemon.
+	Docker func(ctx context.Context) error
+}
+
+// SkeletonFiles are the files every workspace is expected to contain.
+var SkeletonFiles = []string{"README.md", "Makefile"}
+
+type composeDoc struct {
+	Services map[string]struct {
+		Image         string `yaml:"image"`
+		ContainerName string `yaml:"container_name"`
+	} `yaml:"services"`
+}
+
+// Run executes every check. The image is compared with the registered type
+// when the entry has one.
+func (d Doctor) Run(ctx context.Context, e Entry) []Check {
+	var checks []Check
+	add := func(c Check) { checks = append(checks, c) }
+
+	if !e.Exists() {
+		add(Check{Name: "directory", Status: CheckFail, Detail: e.Path + " does not exist"})
+		return checks
+	}
+	add(Check{Name: "directory", Status: CheckOK, Detail: e.Path})
+
+	if e.Type != "" && d.Types != nil {
+		if _, err := d.Types.Lookup(e.Type); err != nil {
+			add(Check{Name: "type", Status: CheckWarn, Detail: fmt.Sprintf("type %s is no longer defined", e.Type)})
+		} else {
+			add(Check{Name: "type", Status: CheckOK, Detail: e.Type})
+		}
+	}
+
+	for _, name := range SkeletonFiles {
+		if _, err := os.Stat(filepath.Join(e.Path, name)); err != nil {
+			add(Check{Name: name, Status: CheckWarn, Detail: "missing", Fixable: true})
+		} else {
+			add(Check{Name: name, Status: CheckOK})
+		}
+	}
+
+	if info, err := os.Stat(filepath.Join(e.Path, "code")); err != nil || !info.IsDir() {
+		add(Check{Name: "code/", Status: CheckFail, Detail: "missing", Fixable: true})
+	} else {
+		add(Check{Name: "code/", Status: CheckOK})
+	}
+
+	checks = append(checks, d.composeChecks(e)...)
+
+	if d.Docker != nil {
+		if err := d.Docker(ctx); err != nil {
+			add(Check{Name: "docker", Status: CheckFail, Detail: err.Error()})
+		} else {
+			add(Check{Name: "docker", Status: CheckOK})
+		}
+	}
+	return checks
+}
+
+func (d Doctor) composeChecks(e Entry) []Check {
+	name := d.ComposeFile
+	content, err := os.ReadFile(filepath.Join(e.Path, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return []Check{{Name: name, Status: CheckFail, Detail: "missing", Fixable: true}}
+	}
+	if err != nil {
+		return []Check{{Name: name, Status: CheckFail, Detail: err.Error()}}
+	}
+	var doc composeDoc
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return []Check{{Name: name, Status: CheckFail, Detail: "does not parse: " + err.Error()}}
+	}
+	if len(doc.Services) == 0 {
+		return []Check{{Name: name, Status: CheckFail, Detail: "defines no services"}}
+	}
+	checks := []Check{{Name: name, Status: CheckOK, Detail: "parses"}}
+
+	want := e.Name + "-dev"
+	svcName, found := e.Name, false
+	if svc, ok := doc.Services[e.Name]; ok && svc.ContainerName == want {
+		found = true
+	} else {
+		names := make([]string, 0, len(doc.Services))
+		for n := range doc.Services {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			if doc.Services[n].ContainerName == want {
+				svcName, found = n, true
+				break
+			}
+		}
+	}
+	if !found {
+		detail := fmt.Sprintf("no service has container_name %s", want)
+		if svc, ok := doc.Services[e.Name]; ok {
+			detail = fmt.Sprintf("service %s has container_name %q, want %s", e.Name, svc.ContainerName, want)
+		}
+		checks = append(checks, Check{Name: "container name", Status: CheckFail, Detail: detail})
+		return checks
+	}
+	checks = append(checks, Check{Name: "container name", Status: CheckOK, Detail: want})
+
+	image := doc.Services[svcName].Image
+	switch {
+	case d.Types == nil:
+	case image == "":
+		checks = append(checks, Check{Name: "image", Status: CheckFail, Detail: "service " + svcName + " has no image"})
+	default:
+		if _, ok := d.Types.Images()[image]; !ok {
+			checks = append(checks, Check{Name: "image", Status: CheckFail, Detail: image + " is not provided by any workspace type"})
+		} else if spec, err := d.Types.Lookup(e.Type); e.Type != "" && err == nil && spec.Image != image {
+			checks = append(checks, Check{Name: "image", Status: CheckWarn, Detail: fmt.Sprintf("%s differs from type %s image %s", image, e.Type, spec.Image)})
+		} else {
+			checks = append(checks, Check{Name: "image", Status: CheckOK, Detail: image})
+		}
+	}
+	return checks
+}
+
+// InferType picks the type of a workspace: the registered type, else the
+// first type whose image the compose file uses, else "default".
+func (d Doctor) InferType(e Entry) (Type, error) {
+	if e.Type != "" {
+		return d.Types.Lookup(e.Type)
+	}
+	if content, err := os.ReadFile(filepath.Join(e.Path, d.ComposeFile)); err == nil {
+		var doc composeDoc
+		if yaml.Unmarshal(content, &doc) == nil {
+			for _, spec := range d.Types.List() {
+				if spec.Image == doc.Services[e.Name].Image {
+					return spec, nil
+				}
+			}
+		}
+	}
+	return d.Types.Lookup("default")
+}
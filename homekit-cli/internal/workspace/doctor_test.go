@@ -0,0 +1,181 @@
+package workspace
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testTypes = `types:
+  default: {image: "hk:default"}
+  go: {image: "hk:go"}
+`
+
+func testDoctor(t *testing.T, docker error) Doctor {
+	t.Helper()
+	types, err := ParseTypes([]byte(testTypes))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Doctor{
+		Types:       types,
+		ComposeFile: "compose.dev.yml",
+		Docker:      func(context.Context) error { return docker },
+	}
+}
+
+// summary renders checks as "name status detail" lines.
+func summary(checks []Check) []string {
+	out := make([]string, len(checks))
+	for i, c := range checks {
+		out[i] = c.Name + " " + string(c.Status)
+		if c.Detail != "" {
+			out[i] += " " + c.Detail
+		}
+		if c.Fixable {
+			out[i] += " (fixable)"
+		}
+	}
+	return out
+}
+
+func TestDoctorRun(t *testing.T) {
+	const devService = "services:\n  demo:\n    image: hk:go\n    container_name: demo-dev\n"
+	tests := []struct {
+		name    string
+		typ     string
+		files   map[string]string
+		noCode  bool
+		docker  error
+		compose []string
+	}{
+		{
+			name:    "healthy",
+			typ:     "go",
+			files:   map[string]string{"compose.dev.yml": devService},
+			compose: []string{"compose.dev.yml ok parses", "container name ok demo-dev", "image ok hk:go"},
+		},
+		{
+			name:    "missing compose file",
+			compose: []string{"compose.dev.yml fail missing (fixable)"},
+		},
+		{
+			name:    "unparsable",
+			files:   map[string]string{"compose.dev.yml": "services: [\n"},
+			compose: []string{"compose.dev.yml fail does not parse: yaml: line 1: did not find expected node content"},
+		},
+		{
+			name:    "no services",
+			files:   map[string]string{"compose.dev.yml": "name: demo\n"},
+			compose: []string{"compose.dev.yml fail defines no services"},
+		},
+		{
+			name:  "wrong container name",
+			files: map[string]string{"compose.dev.yml": "services:\n  demo: {image: \"hk:go\", container_name: dev}\n"},
+			compose: []string{"compose.dev.yml ok parses",
+				`container name fail service demo has container_name "dev", want demo-dev`},
+		},
+		{
+			name:    "container name on another service",
+			files:   map[string]string{"compose.dev.yml": "services:\n  db: {image: \"pg:16\"}\n  dev: {image: \"hk:default\", container_name: demo-dev}\n"},
+			compose: []string{"compose.dev.yml ok parses", "container name ok demo-dev", "image ok hk:default"},
+		},
+		{
+			name:    "foreign image",
+			files:   map[string]string{"compose.dev.yml": "services:\n  demo: {image: \"node:22\", container_name: demo-dev}\n"},
+			compose: []string{"compose.dev.yml ok parses", "container name ok demo-dev", "image fail node:22 is not provided by any workspace type"},
+		},
+		{
+			name:    "image of another type",
+			typ:     "default",
+			files:   map[string]string{"compose.dev.yml": devService},
+			compose: []string{"compose.dev.yml ok parses", "container name ok demo-dev", "image warn hk:go differs from type default image hk:default"},
+		},
+		{
+			name:    "no image",
+			files:   map[string]string{"compose.dev.yml": "services:\n  demo: {build: ., container_name: demo-dev}\n"},
+			compose: []string{"compose.dev.yml ok parses", "container name ok demo-dev", "image fail service demo has no image"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "demo")
+			writeTree(t, dir, "README.md", "Makefile", "code/")
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			checks := testDoctor(t, nil).Run(context.Background(), Entry{Name: "demo", Path: dir, Type: tt.typ})
+			want := []string{"directory ok " + dir}
+			if tt.typ != "" {
+				want = append(want, "type ok "+tt.typ)
+			}
+			want = append(want, "README.md ok", "Makefile ok", "code/ ok")
+			want = append(append(want, tt.compose...), "docker ok")
+			if got := summary(checks); !reflect.DeepEqual(got, want) {
+				t.Fatalf("checks:\n%q\nwant:\n%q", got, want)
+			}
+		})
+	}
+}
+
+func TestDoctorRunSkeleton(t *testing.T) {
+	d := testDoctor(t, errors.New("docker unavailable: no socket"))
+	missing := filepath.Join(t.TempDir(), "gone")
+	if got := summary(d.Run(context.Background(), Entry{Name: "gone", Path: missing})); !reflect.DeepEqual(got, []string{"directory fail " + missing + " does not exist"}) {
+		t.Fatalf("checks of a missing directory: %q", got)
+	}
+
+	dir := t.TempDir()
+	writeTree(t, dir, "code")
+	got := summary(d.Run(context.Background(), Entry{Name: "demo", Path: dir, Type: "python"}))
+	want := []string{
+		"directory ok " + dir,
+		"type warn type python is no longer defined",
+		"README.md warn missing (fixable)",
+		"Makefile warn missing (fixable)",
+		"code/ fail missing (fixable)",
+		"compose.dev.yml fail missing (fixable)",
+		"docker fail docker unavailable: no socket",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("checks:\n%q\nwant:\n%q", got, want)
+	}
+
+	d.Docker = nil
+	for _, c := range d.Run(context.Background(), Entry{Name: "demo", Path: dir}) {
+		if c.Name == "docker" {
+			t.Fatal("docker checked without a probe")
+		}
+	}
+}
+
+func TestDoctorInferType(t *testing.T) {
+	d := testDoctor(t, nil)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "compose.dev.yml"), []byte("services:\n  demo: {image: \"hk:go\"}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		entry Entry
+		want  string
+	}{
+		{Entry{Name: "demo", Path: dir, Type: "default"}, "default"},
+		{Entry{Name: "demo", Path: dir}, "go"},
+		{Entry{Name: "other", Path: dir}, "default"},
+		{Entry{Name: "demo", Path: t.TempDir()}, "default"},
+	}
+	for _, tt := range tests {
+		spec, err := d.InferType(tt.entry)
+		if err != nil || spec.Name != tt.want {
+			t.Errorf("InferType(%+v) = %s, %v; want %s", tt.entry, spec.Name, err, tt.want)
+		}
+	}
+	if _, err := d.InferType(Entry{Name: "demo", Path: dir, Type: "python"}); err == nil {
+		t.Error("expected an error for an unknown registered type")
+	}
+}
@@ -0,0 +1,138 @@
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+)
+
+// RegistryFile is the registry file name inside the state directory.
+const RegistryFile = "workspaces.json"
+
+// ErrNotRegistered is returned when a workspace name is not in the registry.
+var ErrNotRegistered = errors.New("workspace not registered")
+
+// Entry records a workspace created by `workspace new`.
+type Entry struct {
+	Name      string    `json:"name"`
+	Path      string    `json:"path"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	// Files lists what homekit generated in Path, as slash-separated relative
+	// paths; a trailing / marks a directory tree. `workspace rm` deletes
+	// only these.
+	Files []string `json:"files,omitempty"`
+}
+
+// Exists reports whether the workspace directory is still present.
+func (e Entry) Exists() bool {
+	info, err := os.Stat(e.Path)
+	return err == nil && info.IsDir()
+}
+
+// Registry is the persisted set of known workspaces, keyed by name.
+type Registry struct {
+	path    string
+	entries map[string]Entry
+}
+
+type registryFile struct {
+	Workspaces []Entry `json:"workspaces"`
+}
+
+// LoadRegistry reads the registry stored in stateDir. A missing file yields an
+// empty registry.
+func LoadRegistry(stateDir string) (*Registry, error) {
+	r := &Registry{
+		path:    filepath.Join(stateDir, RegistryFile),
+		entries: map[string]Entry{},
+	}
+	content, err := os.ReadFile(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read workspace registry: %w", err)
+	}
+	var doc registryFile
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("parse workspace registry %s: %w", r.path, err)
+	}
+	for _, e := range doc.Workspaces {
+		r.entries[e.Name] = e
+	}
+	return r, nil
+}
+
+// Path returns the registry file location.
+func (r *Registry) Path() string {
+	return r.path
+}
+
+// Add registers a workspace. Re-registering a name is allowed only for the
+// same directory.
+func (r *Registry) Add(e Entry) error {
+	if e.Name == "" {
+		return errors.New("workspace name must be set")
+	}
+	if existing, ok := r.entries[e.Name]; ok && filepath.Clean(existing.Path) != filepath.Clean(e.Path) {
+		return fmt.Errorf("workspace %q is already registered at %s", e.Name, existing.Path)
+	}
+	r.entries[e.Name] = e
+	return nil
+}
+
+// Get looks up a workspace by name.
+func (r *Registry) Get(name string) (Entry, error) {
+	e, ok := r.entries[name]
+	if !ok {
+		return Entry{}, fmt.Errorf("%w: %s", ErrNotRegistered, name)
+	}
+	return e, nil
+}
+
+// FindByPath returns the workspace registered for dir, if any.
+func (r *Registry) FindByPath(dir string) (Entry, bool) {
+	dir = filepath.Clean(dir)
+	for _, e := range r.entries {
+		if filepath.Clean(e.Path) == dir {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Remove drops a workspace from the registry.
+func (r *Registry) Remove(name string) error {
+	if _, ok := r.entries[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotRegistered, name)
+	}
+	delete(r.entries, name)
+	return nil
+}
+
+// List returns all entries sorted by name.
+func (r *Registry) List() []Entry {
+	out := make([]Entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Save persists the registry atomically.
+func (r *Registry) Save() error {
+	content, err := json.MarshalIndent(registryFile{Workspaces: r.List()}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return fileutil.WriteAtomic(r.path, append(content, '\n'), 0o644)
+}
@@ -0,0 +1,157 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownFiles is returned when a workspace directory holds files homekit
+// did not generate, so it cannot be deleted.
+var ErrUnknownFiles = errors.New("workspace directory holds files homekit did not create")
+
+// Removal is what deleting the generated files of a workspace would remove.
+type Removal struct {
+	Dir string
+	// Paths are the generated files and trees to delete, relative to Dir.
+	Paths []string
+	// Dirs are the directories left empty by deleting Paths, deepest first.
+	Dirs []string
+	// Leftover lists files in Dir that were not generated, relative to Dir.
+	Leftover []string
+}
+
+// PlanRemoval works out how to delete the generated entries from dir (see
+// Entry.Files). Directories named without a trailing / are removed only
+// when they end up empty; every other file found in dir is Leftover, and a
+// directory without any generated entry below it is listed once as "dir/".
+func PlanRemoval(dir string, generated []string) (Removal, error) {
+	r := Removal{Dir: dir}
+	files := map[string]bool{}
+	trees := map[string]bool{}
+	for _, g := range generated {
+		if tree, ok := strings.CutSuffix(g, "/"); ok {
+			trees[path.Clean(tree)] = true
+		} else {
+			files[path.Clean(g)] = true
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		switch {
+		case trees[rel]:
+			r.Paths = append(r.Paths, rel)
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+		case d.IsDir() && !files[rel] && !generatedBelow(rel, files, trees):
+			r.Leftover = append(r.Leftover, rel+"/")
+			return fs.SkipDir
+		case d.IsDir():
+			r.Dirs = append(r.Dirs, rel)
+		case files[rel]:
+			r.Paths = append(r.Paths, rel)
+		default:
+			r.Leftover = append(r.Leftover, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return Removal{}, err
+	}
+
+	// deepest first, so nested empty directories are reported once
+	sort.SliceStable(r.Dirs, func(i, j int) bool { return strings.Count(r.Dirs[i], "/") > strings.Count(r.Dirs[j], "/") })
+	// directories homekit created or that held generated files are removed
+	// once empty; any other directory is left over unless something inside
+	// it already is
+	kept := r.Dirs[:0]
+	for _, d := range r.Dirs {
+		if !files[d] && !r.holdsGenerated(d) {
+			if !r.holdsLeftover(d) {
+				r.Leftover = append(r.Leftover, d+"/")
+			}
+			continue
+		}
+		kept = append(kept, d)
+	}
+	r.Dirs = kept
+	sort.Strings(r.Leftover)
+	return r, nil
+}
+
+func generatedBelow(dir string, sets ...map[string]bool) bool {
+	for _, set := range sets {
+		for p := range set {
+			if strings.HasPrefix(p, dir+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r Removal) holdsGenerated(dir string) bool {
+	for _, p := range r.Paths {
+		if strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Removal) holdsLeftover(dir string) bool {
+	for _, p := range r.Leftover {
+		if strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply deletes the generated paths and the emptied directories, then Dir
+// itself. It refuses to delete anything when there are leftover files.
+func (r Removal) Apply() error {
+	if len(r.Leftover) > 0 {
+		return r.LeftoverError()
+	}
+	for _, p := range r.Paths {
+		if err := os.RemoveAll(filepath.Join(r.Dir, filepath.FromSlash(p))); err != nil {
+			return err
+		}
+	}
+	for _, d := range r.Dirs {
+		if err := os.Remove(filepath.Join(r.Dir, filepath.FromSlash(d))); err != nil {
+			return err
+		}
+	}
+	return os.Remove(r.Dir)
+}
+
+// LeftoverError describes the leftover files, listing at most ten.
+func (r Removal) LeftoverError() error {
+	const shown = 10
+	list := r.Leftover
+	more := ""
+	if len(list) > shown {
+		more = fmt.Sprintf(" and %d more", len(list)-shown)
+		list = list[:shown]
+	}
+	return fmt.Errorf("%w: %s: %s%s", ErrUnknownFiles, r.Dir, strings.Join(list, ", "), more)
+}
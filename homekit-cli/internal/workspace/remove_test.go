@@ -0,0 +1,93 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeTree(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		if f[len(f)-1] == '/' {
+			if err := os.MkdirAll(p, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRemovalDeletesOnlyGenerated(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ws")
+	writeTree(t, dir, "compose.dev.yml", "README.md", "code/", ".devcontainer/devcontainer.json")
+	r, err := PlanRemoval(dir, []string{"compose.dev.yml", "README.md", "Makefile", "code", ".devcontainer/devcontainer.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Leftover) != 0 {
+		t.Fatalf("unexpected leftover %v", r.Leftover)
+	}
+	if err := r.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("directory still exists: %v", err)
+	}
+}
+
+func TestRemovalRefusesForeignFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "compose.dev.yml", "main.go", "code/app.go", ".devcontainer/devcontainer.json", ".devcontainer/extra.json", "empty/nested/")
+	r, err := PlanRemoval(dir, []string{"compose.dev.yml", "code", ".devcontainer/devcontainer.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".devcontainer/extra.json", "code/app.go", "empty/", "main.go"}
+	if !reflect.DeepEqual(r.Leftover, want) {
+		t.Fatalf("leftover %v, want %v", r.Leftover, want)
+	}
+	if err := r.Apply(); !errors.Is(err, ErrUnknownFiles) {
+		t.Fatalf("Apply: %v, want ErrUnknownFiles", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "compose.dev.yml")); err != nil {
+		t.Fatalf("generated file deleted despite leftovers: %v", err)
+	}
+}
+
+func TestRemovalKeepsClone(t *testing.T) {
+	dir := t.TempDir()
+	writeTree(t, dir, "compose.dev.yml", "code/.git/HEAD", "code/.git/refs/heads/main", "code/main.go")
+	r, err := PlanRemoval(dir, []string{"compose.dev.yml", "code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"code/.git/", "code/main.go"}
+	if !reflect.DeepEqual(r.Leftover, want) {
+		t.Fatalf("leftover %v, want %v", r.Leftover, want)
+	}
+}
+
+func TestRemovalDeletesTree(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ws")
+	writeTree(t, dir, "compose.dev.yml", "cache/a/b", "cache/c")
+	r, err := PlanRemoval(dir, []string{"compose.dev.yml", "cache/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("directory still exists: %v", err)
+	}
+}
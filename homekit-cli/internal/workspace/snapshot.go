@@ -0,0 +1,471 @@
+package workspace
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/homekit/homekit-cli/internal/util/fileutil"
+	"github.com/homekit/homekit-cli/internal/util/ignoreutil"
+)
+
+const (
+	// SnapshotIgnoreFile lists paths excluded from snapshots, relative to the
+	// workspace directory, using the .tmplignore pattern syntax.
+	SnapshotIgnoreFile = ".homekitignore"
+	// SnapshotsDir holds snapshots inside the state directory, one
+	// sub-directory per workspace.
+	SnapshotsDir = "snapshots"
+
+	snapshotExt   = ".tar.gz"
+	manifestExt   = ".manifest.json"
+	snapshotIDFmt = "20060102T150405Z"
+)
+
+// ErrSnapshotNotFound is returned when a snapshot ID does not exist.
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// ErrSnapshotCorrupt is returned when an archive does not match its manifest.
+var ErrSnapshotCorrupt = errors.New("snapshot does not match its manifest")
+
+// Snapshot is one stored archive of a workspace directory.
+type Snapshot struct {
+	ID        string    `json:"id"`
+	Workspace string    `json:"workspace"`
+	Archive   string    `json:"archive"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Manifest records the checksum of the archive and of every file in it.
+type Manifest struct {
+	Workspace     string         `json:"workspace"`
+	Source        string         `json:"source"`
+	CreatedAt     time.Time      `json:"created_at"`
+	ArchiveSHA256 string         `json:"archive_sha256"`
+	Files         []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one archive entry. Directories and symlinks carry
+// no checksum.
+type ManifestFile struct {
+	Path   string      `json:"path"`
+	Mode   fs.FileMode `json:"mode"`
+	Size   int64       `json:"size,omitempty"`
+	SHA256 string      `json:"sha256,omitempty"`
+	Link   string      `json:"link,omitempty"`
+}
+
+// SnapshotStore keeps snapshots below <stateDir>/snapshots.
+type SnapshotStore struct {
+	root string
+}
+
+// NewSnapshotStore returns the store inside stateDir.
+func NewSnapshotStore(stateDir string) *SnapshotStore {
+	return &SnapshotStore{root: filepath.Join(stateDir, SnapshotsDir)}
+}
+
+func (s *SnapshotStore) dir(workspace string) string {
+	return filepath.Join(s.root, workspace)
+}
+
+func (s *SnapshotStore) manifestPath(workspace, id string) string {
+	return filepath.Join(s.dir(workspace), id+manifestExt)
+}
+
+// Create archives srcDir, honouring its SnapshotIgnoreFile, and stores the
+// archive with its manifest.
+func (s *SnapshotStore) Create(workspace, srcDir string, now time.Time) (Snapshot, error) {
+	ignore, err := ignoreutil.Load(os.DirFS(srcDir), SnapshotIgnoreFile)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	dir := s.dir(workspace)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Snapshot{}, err
+	}
+
+	id := now.UTC().Format(snapshotIDFmt)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, id+snapshotExt)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		id = fmt.Sprintf("%s-%d", now.UTC().Format(snapshotIDFmt), i)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	manifest := Manifest{Workspace: workspace, Source: srcDir, CreatedAt: now.UTC()}
+	archiveSum := sha256.New()
+	if err := writeArchive(io.MultiWriter(tmp, archiveSum), srcDir, ignore, &manifest); err != nil {
+		tmp.Close()
+		return Snapshot{}, fmt.Errorf("archive %s: %w", srcDir, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return Snapshot{}, err
+	}
+	manifest.ArchiveSHA256 = hex.EncodeToString(archiveSum.Sum(nil))
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if err := fileutil.WriteAtomic(s.manifestPath(workspace, id), append(content, '\n'), 0o644); err != nil {
+		return Snapshot{}, err
+	}
+	archive := filepath.Join(dir, id+snapshotExt)
+	if err := os.Rename(tmp.Name(), archive); err != nil {
+		_ = os.Remove(s.manifestPath(workspace, id))
+		return Snapshot{}, err
+	}
+	return s.Get(workspace, id)
+}
+
+func writeArchive(w io.Writer, srcDir string, ignore ignoreutil.List, manifest *Manifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	err := filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ignore.Match(rel, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entry := ManifestFile{Path: rel, Mode: info.Mode()}
+		link := ""
+		switch {
+		case info.Mode()&fs.ModeSymlink != 0:
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+			entry.Link = link
+		case info.IsDir(), info.Mode().IsRegular():
+		default:
+			// sockets, devices and pipes cannot be restored meaningfully
+			return nil
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uname, hdr.Gname = "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			sum := sha256.New()
+			n, err := io.Copy(io.MultiWriter(tw, sum), f)
+			f.Close()
+			if err != nil {
+				return err
+			}
+			entry.Size = n
+			entry.SHA256 = hex.EncodeToString(sum.Sum(nil))
+		}
+		manifest.Files = append(manifest.Files, entry)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// List returns the snapshots of a workspace, oldest first.
+func (s *SnapshotStore) List(workspace string) ([]Snapshot, error) {
+	entries, err := os.ReadDir(s.dir(workspace))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var out []Snapshot
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), snapshotExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		snap, err := s.Get(workspace, id)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, snap)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// Get returns one snapshot. The ID "latest" selects the newest one.
+func (s *SnapshotStore) Get(workspace, id string) (Snapshot, error) {
+	if id == "latest" {
+		all, err := s.List(workspace)
+		if err != nil {
+			return Snapshot{}, err
+		}
+		if len(all) == 0 {
+			return Snapshot{}, fmt.Errorf("%w: workspace %s has no snapshots", ErrSnapshotNotFound, workspace)
+		}
+		return all[len(all)-1], nil
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return Snapshot{}, fmt.Errorf("%w: invalid id %q", ErrSnapshotNotFound, id)
+	}
+	archive := filepath.Join(s.dir(workspace), id+snapshotExt)
+	info, err := os.Stat(archive)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Snapshot{}, fmt.Errorf("%w: %s/%s", ErrSnapshotNotFound, workspace, id)
+	}
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snap := Snapshot{ID: id, Workspace: workspace, Archive: archive, Size: info.Size(), CreatedAt: info.ModTime().UTC()}
+	if t, err := time.Parse(snapshotIDFmt, strings.SplitN(id, "-", 2)[0]); err == nil {
+		snap.CreatedAt = t
+	}
+	return snap, nil
+}
+
+// Prune deletes all but the newest keep snapshots and returns the removed ones.
+func (s *SnapshotStore) Prune(workspace string, keep int) ([]Snapshot, error) {
+	if keep < 0 {
+		return nil, errors.New("retention count must not be negative")
+	}
+	all, err := s.List(workspace)
+	if err != nil || len(all) <= keep {
+		return nil, err
+	}
+	removed := all[:len(all)-keep]
+	for _, snap := range removed {
+		if err := s.Remove(snap); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// Remove deletes a snapshot archive and its manifest.
+func (s *SnapshotStore) Remove(snap Snapshot) error {
+	if err := os.Remove(snap.Archive); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	err := os.Remove(s.manifestPath(snap.Workspace, snap.ID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Verify checks the archive checksum and every entry against the manifest.
+func (s *SnapshotStore) Verify(snap Snapshot) (*Manifest, error) {
+	content, err := os.ReadFile(s.manifestPath(snap.Workspace, snap.ID))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+
+	f, err := os.Open(snap.Archive)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	archiveSum := sha256.New()
+	if _, err := io.Copy(archiveSum, f); err != nil {
+		return nil, err
+	}
+	if got := hex.EncodeToString(archiveSum.Sum(nil)); got != manifest.ArchiveSHA256 {
+		return nil, fmt.Errorf("%w: archive checksum %s, manifest %s", ErrSnapshotCorrupt, got, manifest.ArchiveSHA256)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	expected := make(map[string]ManifestFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		expected[file.Path] = file
+	}
+	seen := map[string]bool{}
+	err = readArchive(f, func(hdr *tar.Header, name string, r io.Reader) error {
+		want, ok := expected[name]
+		if !ok {
+			return fmt.Errorf("%w: unexpected entry %s", ErrSnapshotCorrupt, name)
+		}
+		seen[name] = true
+		if hdr.Typeflag == tar.TypeReg {
+			sum := sha256.New()
+			n, err := io.Copy(sum, r)
+			if err != nil {
+				return err
+			}
+			if n != want.Size || hex.EncodeToString(sum.Sum(nil)) != want.SHA256 {
+				return fmt.Errorf("%w: checksum mismatch for %s", ErrSnapshotCorrupt, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for name := range expected {
+		if !seen[name] {
+			return nil, fmt.Errorf("%w: %s missing from archive", ErrSnapshotCorrupt, name)
+		}
+	}
+	return &manifest, nil
+}
+
+// Restore extracts a snapshot over destDir; callers check it with Verify
+// first. Regular files go through a fileutil.Stage under tempDir so a failed
+// restore is rolled back. Files in destDir that are not in the snapshot are
+// left alone.
+func (s *SnapshotStore) Restore(snap Snapshot, destDir, tempDir string) error {
+	f, err := os.Open(snap.Archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	stage, err := fileutil.NewStage(tempDir)
+	if err != nil {
+		return err
+	}
+	defer stage.Close()
+
+	type link struct{ name, target string }
+	var (
+		links []link
+		dirs  []string
+	)
+	err = readArchive(f, func(hdr *tar.Header, name string, r io.Reader) error {
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			dirs = append(dirs, name)
+		case tar.TypeReg:
+			return stage.AddFrom(name, r, fs.FileMode(hdr.Mode).Perm())
+		case tar.TypeSymlink:
+			links = append(links, link{name, hdr.Linkname})
+		}
+		return nil
+	})
+	if err == nil {
+		err = checkNoSymlinkParents(destDir, append(stage.Files(), dirs...))
+	}
+	for _, dir := range dirs {
+		if err != nil {
+			break
+		}
+		err = stage.MkdirAll(filepath.Join(destDir, filepath.FromSlash(dir)), 0o755)
+	}
+	if err == nil {
+		err = stage.Commit(destDir)
+	}
+	for _, l := range links {
+		if err != nil {
+			break
+		}
+		target := filepath.Join(destDir, filepath.FromSlash(l.name))
+		if current, readErr := os.Readlink(target); readErr == nil && current == l.target {
+			continue
+		}
+		if _, statErr := os.Lstat(target); statErr == nil {
+			err = fmt.Errorf("restore symlink %s: path exists", l.name)
+			break
+		}
+		if err = os.Symlink(l.target, target); err == nil {
+			stage.OnRollback(func() error { return os.Remove(target) })
+		}
+	}
+	if err != nil {
+		if rbErr := stage.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("rollback: %w", rbErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// readArchive walks a gzip tar stream, rejecting entry names that could
+// escape the extraction root.
+func readArchive(r io.Reader, fn func(hdr *tar.Header, name string, r io.Reader) error) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+		}
+		name := strings.TrimSuffix(hdr.Name, "/")
+		if !fs.ValidPath(name) || name == "." || path.Clean(name) != name {
+			return fmt.Errorf("%w: unsafe entry %q", ErrSnapshotCorrupt, hdr.Name)
+		}
+		if err := fn(hdr, name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// checkNoSymlinkParents refuses to write through symlinked directories inside
+// destDir, which could redirect restored files outside the workspace.
+func checkNoSymlinkParents(destDir string, paths []string) error {
+	for _, rel := range paths {
+		dir := path.Dir(rel)
+		for dir != "." {
+			info, err := os.Lstat(filepath.Join(destDir, filepath.FromSlash(dir)))
+			if err == nil && info.Mode()&fs.ModeSymlink != 0 {
+				return fmt.Errorf("restore %s: parent %s is a symlink", rel, dir)
+			}
+			dir = path.Dir(dir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+package workspace
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var snapshotTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func readFile(t *testing.T, p string) string {
+	t.Helper()
+	content, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	state, src := t.TempDir(), t.TempDir()
+	writeTree(t, src, "compose.dev.yml", "code/main.go", "cache/blob", "empty/")
+	if err := os.WriteFile(filepath.Join(src, SnapshotIgnoreFile), []byte("cache/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("code/main.go", filepath.Join(src, "main.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewSnapshotStore(state)
+	snap, err := store.Create("demo", src, snapshotTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.ID != "20240501T120000Z" || !snap.CreatedAt.Equal(snapshotTime) {
+		t.Fatalf("unexpected snapshot %+v", snap)
+	}
+	manifest, err := store.Verify(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range manifest.Files {
+		paths = append(paths, f.Path)
+	}
+	want := []string{SnapshotIgnoreFile, "code", "code/main.go", "compose.dev.yml", "empty", "main.go"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("manifest files %v, want %v", paths, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(src, "code/main.go"), []byte("changed"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(src, "compose.dev.yml")); err != nil {
+		t.Fatal(err)
+	}
+	writeTree(t, src, "extra.txt")
+	if err := store.Restore(snap, src, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"code/main.go", "compose.dev.yml", "extra.txt"} {
+		if got := readFile(t, filepath.Join(src, f)); got != "x" {
+			t.Fatalf("%s = %q after restore", f, got)
+		}
+	}
+	if link, err := os.Readlink(filepath.Join(src, "main.go")); err != nil || link != "code/main.go" {
+		t.Fatalf("symlink = %q, %v", link, err)
+	}
+
+	again, err := store.Create("demo", src, snapshotTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := store.Get("demo", "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != "20240501T120000Z-1" || latest.ID != again.ID {
+		t.Fatalf("second snapshot %s, latest %s", again.ID, latest.ID)
+	}
+}
+
+func TestSnapshotVerifyRejectsTampering(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, archive, manifest string)
+	}{
+		{"archive", func(t *testing.T, archive, _ string) {
+			f, err := os.OpenFile(archive, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := f.Write([]byte{0}); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"file checksum", func(t *testing.T, _, manifest string) {
+			editManifest(t, manifest, func(m *Manifest) { m.Files[1].SHA256 = hex.EncodeToString(make([]byte, 32)) })
+		}},
+		{"missing entry", func(t *testing.T, _, manifest string) {
+			editManifest(t, manifest, func(m *Manifest) { m.Files = m.Files[1:] })
+		}},
+		{"extra entry", func(t *testing.T, _, manifest string) {
+			editManifest(t, manifest, func(m *Manifest) { m.Files = append(m.Files, ManifestFile{Path: "gone"}) })
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			writeTree(t, src, "a.txt", "b.txt")
+			store := NewSnapshotStore(t.TempDir())
+			snap, err := store.Create("demo", src, snapshotTime)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.tamper(t, snap.Archive, store.manifestPath("demo", snap.ID))
+			if _, err := store.Verify(snap); !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Fatalf("Verify error = %v, want ErrSnapshotCorrupt", err)
+			}
+		})
+	}
+}
+
+func editManifest(t *testing.T, p string, edit func(*Manifest)) {
+	t.Helper()
+	var m Manifest
+	if err := json.Unmarshal([]byte(readFile(t, p)), &m); err != nil {
+		t.Fatal(err)
+	}
+	edit(&m)
+	content, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeSnapshot stores a hand-built archive with a matching manifest, so
+// entries Create would never produce can reach Verify and Restore.
+func writeSnapshot(t *testing.T, store *SnapshotStore, names ...string) Snapshot {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	manifest := Manifest{Workspace: "demo"}
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: 1}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256([]byte("x"))
+		manifest.Files = append(manifest.Files, ManifestFile{Path: name, Mode: 0o644, Size: 1, SHA256: hex.EncodeToString(sum[:])})
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	manifest.ArchiveSHA256 = hex.EncodeToString(sum[:])
+
+	id := snapshotTime.Format(snapshotIDFmt)
+	if err := os.MkdirAll(store.dir("demo"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(store.dir("demo"), id+snapshotExt), buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(store.manifestPath("demo", id), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := store.Get("demo", id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snap
+}
+
+func TestSnapshotRejectsUnsafeEntries(t *testing.T) {
+	for _, name := range []string{"../escape.txt", "code/../../escape.txt", "/tmp/escape.txt", "./a.txt"} {
+		t.Run(name, func(t *testing.T) {
+			root := t.TempDir()
+			dest := filepath.Join(root, "ws", "dest")
+			if err := os.MkdirAll(dest, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			store := NewSnapshotStore(t.TempDir())
+			snap := writeSnapshot(t, store, name)
+			if _, err := store.Verify(snap); !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Fatalf("Verify error = %v, want ErrSnapshotCorrupt", err)
+			}
+			if err := store.Restore(snap, dest, t.TempDir()); !errors.Is(err, ErrSnapshotCorrupt) {
+				t.Fatalf("Restore error = %v, want ErrSnapshotCorrupt", err)
+			}
+			for _, p := range []string{filepath.Join(root, "ws", "escape.txt"), filepath.Join(root, "escape.txt")} {
+				if _, err := os.Lstat(p); !errors.Is(err, os.ErrNotExist) {
+					t.Fatalf("%s written outside the destination", p)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotRestoreRefusesSymlinkedParent(t *testing.T) {
+	dest, outside := t.TempDir(), t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dest, "code")); err != nil {
+		t.Fatal(err)
+	}
+	store := NewSnapshotStore(t.TempDir())
+	snap := writeSnapshot(t, store, "code/main.go")
+	if _, err := store.Verify(snap); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Restore(snap, dest, t.TempDir()); err == nil {
+		t.Fatal("expected an error for a symlinked parent")
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "main.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("restore wrote through the symlink")
+	}
+}
+
+func TestSnapshotPrune(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, "a.txt")
+	store := NewSnapshotStore(t.TempDir())
+	var ids []string
+	for i := range 3 {
+		snap, err := store.Create("demo", src, snapshotTime.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, snap.ID)
+	}
+
+	if removed, err := store.Prune("demo", 5); err != nil || len(removed) != 0 {
+		t.Fatalf("Prune(5) = %v, %v", removed, err)
+	}
+	if _, err := store.Prune("demo", -1); err == nil {
+		t.Fatal("expected an error for a negative count")
+	}
+	removed, err := store.Prune("demo", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || removed[0].ID != ids[0] || removed[1].ID != ids[1] {
+		t.Fatalf("removed %v, want %v", removed, ids[:2])
+	}
+	left, err := store.List("demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0].ID != ids[2] {
+		t.Fatalf("left %v, want %s", left, ids[2])
+	}
+	if _, err := os.Stat(store.manifestPath("demo", ids[0])); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("pruned manifest still exists")
+	}
+	if _, err := store.Get("demo", ids[0]); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("Get pruned = %v", err)
+	}
+}
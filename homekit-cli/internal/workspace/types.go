@@ -0,0 +1,120 @@
+package workspace
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TypesFile is the workspace type registry in the workspaces asset namespace.
+const TypesFile = "types.yaml"
+
+// Type describes a workspace flavour: the dev container image and the extras
+// rendered into the generated compose file.
+type Type struct {
+	Name        string            `yaml:"-"`
+	Description string            `yaml:"description"`
+	Image       string            `yaml:"image"`
+	Env         map[string]string `yaml:"env"`
+	Ports       []string          `yaml:"ports"`
+	Volumes     []string          `yaml:"volumes"`
+	Services    map[string]any    `yaml:"services"`
+	Hooks       Hooks             `yaml:"hooks"`
+	Files       []TemplateFile    `yaml:"files"`
+}
+
+// Hooks lists shell commands run at workspace lifecycle points.
+type Hooks struct {
+	PostCreate []string `yaml:"post_create"`
+}
+
+// TemplateFile maps a template in the workspaces namespace to a path inside
+// the workspace.
+type TemplateFile struct {
+	Src  string `yaml:"src"`
+	Dest string `yaml:"dest"`
+}
+
+// Types is the parsed workspace type registry.
+type Types struct {
+	byName map[string]Type
+}
+
+type typesFile struct {
+	Types map[string]Type `yaml:"types"`
+}
+
+// LoadTypes reads TypesFile from the root of fsys, normally the layered
+// workspaces asset namespace so an override file replaces the embedded one.
+func LoadTypes(fsys fs.FS) (*Types, error) {
+	content, err := fs.ReadFile(fsys, TypesFile)
+	if err != nil {
+		return nil, fmt.Errorf("read workspace types: %w", err)
+	}
+	return ParseTypes(content)
+}
+
+// ParseTypes decodes and validates a workspace type registry.
+func ParseTypes(content []byte) (*Types, error) {
+	var doc typesFile
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", TypesFile, err)
+	}
+	if len(doc.Types) == 0 {
+		return nil, fmt.Errorf("%s defines no workspace types", TypesFile)
+	}
+	t := &Types{byName: make(map[string]Type, len(doc.Types))}
+	for name, spec := range doc.Types {
+		if spec.Image == "" {
+			return nil, fmt.Errorf("%s: type %q has no image", TypesFile, name)
+		}
+		for _, f := range spec.Files {
+			if f.Src == "" || f.Dest == "" {
+				return nil, fmt.Errorf("%s: type %q has a file without src or dest", TypesFile, name)
+			}
+		}
+		spec.Name = name
+		t.byName[name] = spec
+	}
+	return t, nil
+}
+
+// Lookup returns the named type or an error listing valid names.
+func (t *Types) Lookup(name string) (Type, error) {
+	spec, ok := t.byName[name]
+	if !ok {
+		return Type{}, fmt.Errorf("invalid workspace type %q. Please choose from %s", name, strings.Join(t.Names(), ", "))
+	}
+	return spec, nil
+}
+
+// Names returns the sorted type names.
+func (t *Types) Names() []string {
+	names := make([]string, 0, len(t.byName))
+	for name := range t.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// List returns all types sorted by name.
+func (t *Types) List() []Type {
+	out := make([]Type, 0, len(t.byName))
+	for _, name := range t.Names() {
+		out = append(out, t.byName[name])
+	}
+	return out
+}
+
+// Images returns the set of images referenced by any type.
+func (t *Types) Images() map[string]struct{} {
+	out := map[string]struct{}{}
+	for _, spec := range t.byName {
+		out[spec.Image] = struct{}{}
+	}
+	return out
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"os"
 
 	"github.com/homekit/homekit-cli/cmd/homekit"
+	"github.com/homekit/homekit-cli/internal/core"
 )
 
 func main() {
 	if err := homekit.Execute(); err != nil {
+		var exitErr *core.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil {
+				log.Printf("homekit-cli: %v", exitErr.Err)
+			}
+			os.Exit(exitErr.Code)
+		}
 		log.Fatalf("homekit-cli: %v", err)
 	}
 }